@@ -20,11 +20,17 @@ type AnalyticsQueryOptions struct {
 	Priority             bool
 	PositionalParameters []interface{}
 	NamedParameters      map[string]interface{}
+	// Parameters is a struct, or a pointer to a struct, whose fields are bound as named parameters. Fields are named
+	// by their n1ql tag, falling back to their json tag, and are serialized using Serializer. Every named parameter
+	// referenced by the statement must have a value and every value must be referenced. Parameters cannot be used
+	// along with PositionalParameters or NamedParameters.
+	Parameters interface{}
 
 	// Experimental: This API is subject to change at any time.
 	Deferred bool
 
-	// JSONSerializer is used to deserialize each row in the result. This should be a JSON deserializer as results are JSON.
+	// JSONSerializer is used to deserialize each row in the result, and to serialize Parameters. This should be a JSON
+	// serializer as results are JSON.
 	// NOTE: if not set then query will always default to DefaultJSONSerializer.
 	Serializer JSONSerializer
 }
@@ -55,6 +61,31 @@ func (opts *AnalyticsQueryOptions) toMap(statement string) (map[string]interface
 		return nil, errors.New("Positional and named parameters must be used exclusively")
 	}
 
+	if opts.Parameters != nil {
+		if opts.PositionalParameters != nil || opts.NamedParameters != nil {
+			return nil, errors.New("Parameters cannot be used with positional or named parameters")
+		}
+
+		serializer := opts.Serializer
+		if serializer == nil {
+			serializer = &DefaultJSONSerializer{}
+		}
+
+		params, err := structParameters(opts.Parameters, serializer)
+		if err != nil {
+			return nil, err
+		}
+
+		err = checkNamedParameters(statement, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, value := range params {
+			execOpts["$"+key] = value
+		}
+	}
+
 	if opts.PositionalParameters != nil {
 		execOpts["args"] = opts.PositionalParameters
 	}
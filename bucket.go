@@ -3,6 +3,8 @@ package gocb
 // Bucket represents a single bucket within a cluster.
 type Bucket struct {
 	sb stateBlock
+
+	cluster *Cluster
 }
 
 // BucketOptions are the options available when connecting to a Bucket.
@@ -26,6 +28,15 @@ func newBucket(sb *stateBlock, bucketName string, opts BucketOptions) *Bucket {
 			DuraTimeout:      sb.DuraTimeout,
 			DuraPollTimeout:  sb.DuraPollTimeout,
 
+			DuraPollBehavior:          sb.DuraPollBehavior,
+			AmbiguousDurabilityErrors: sb.AmbiguousDurabilityErrors,
+			DurabilityFallback:        sb.DurabilityFallback,
+
+			ReplicaFallbackHandler: sb.ReplicaFallbackHandler,
+
+			KvStats:    sb.KvStats,
+			NearCaches: sb.NearCaches,
+
 			Transcoder: sb.Transcoder,
 			Serializer: sb.Serializer,
 		},
@@ -0,0 +1,323 @@
+package gocb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
+)
+
+// NodeLocation describes a kv node within the vbucket map of a bucket.
+type NodeLocation struct {
+	// Index is the position of the node within the vbucket map, -1 if no node is currently assigned.
+	Index int
+	// Address is the address of the kv service on the node, empty if it is unknown.
+	Address string
+	// ServerGroup is the server group which the node belongs to, this is only populated when requested.
+	ServerGroup string
+}
+
+// VbucketMap is a snapshot of the vbucket map of a bucket, as seen by the SDK.
+type VbucketMap struct {
+	NumReplicas int
+	Nodes       []NodeLocation
+	// Vbuckets contains, for each vbucket, the index of the active node followed by the index of each replica
+	// node. Indexes are -1 where no node is currently assigned.
+	Vbuckets [][]int
+}
+
+// ActiveVbuckets returns the vbuckets which are active on the node at index.
+func (m *VbucketMap) ActiveVbuckets(index int) []uint16 {
+	var vbuckets []uint16
+	for vbID, servers := range m.Vbuckets {
+		if len(servers) > 0 && servers[0] == index {
+			vbuckets = append(vbuckets, uint16(vbID))
+		}
+	}
+
+	return vbuckets
+}
+
+// ReplicaVbuckets returns the vbuckets for which the node at index holds a replica.
+func (m *VbucketMap) ReplicaVbuckets(index int) []uint16 {
+	var vbuckets []uint16
+	for vbID, servers := range m.Vbuckets {
+		for _, server := range servers[1:] {
+			if server == index {
+				vbuckets = append(vbuckets, uint16(vbID))
+				break
+			}
+		}
+	}
+
+	return vbuckets
+}
+
+// VbucketMapOptions are the options available to the VbucketMap operation.
+type VbucketMapOptions struct {
+	Timeout time.Duration
+	Context context.Context
+	// ServerGroups fetches the server group of each node from the management service. This requires permission
+	// to read the cluster configuration.
+	ServerGroups bool
+}
+
+// VbucketMap returns a snapshot of the vbucket map of the bucket from the current cluster configuration.
+func (b *Bucket) VbucketMap(opts *VbucketMapOptions) (*VbucketMap, error) {
+	if opts == nil {
+		opts = &VbucketMapOptions{}
+	}
+
+	ctx, cancel := contextFromMaybeTimeout(opts.Context, opts.Timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	cli := b.sb.getCachedClient()
+	provider, err := cli.getKvProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := routingNodes(ctx, cli, provider, opts.ServerGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	vbMap := &VbucketMap{
+		NumReplicas: provider.NumReplicas(),
+		Nodes:       nodes,
+		Vbuckets:    make([][]int, provider.NumVbuckets()),
+	}
+	for vbID := range vbMap.Vbuckets {
+		servers := make([]int, vbMap.NumReplicas+1)
+		for replicaIdx := range servers {
+			servers[replicaIdx] = provider.VbucketToServer(uint16(vbID), uint32(replicaIdx))
+		}
+		vbMap.Vbuckets[vbID] = servers
+	}
+
+	return vbMap, nil
+}
+
+// kvServerListInterval is how long the kv server list of a bucket is cached for before it is fetched again.
+const kvServerListInterval = time.Minute
+
+// kvServerListMinInterval is how long to wait between fetches of the kv server list when the number of servers
+// known to the agent does not match it.
+const kvServerListMinInterval = time.Second
+
+// clientKvServerAddresses returns the addresses of the kv nodes, indexed by their position within the vbucket
+// map. The addresses are read from the server list of the bucket configuration, so will be empty until it has been
+// fetched.
+func clientKvServerAddresses(cli client) []string {
+	return cli.kvServerAddresses()
+}
+
+type jsonBucketNodeExt struct {
+	Hostname string         `json:"hostname"`
+	Services map[string]int `json:"services"`
+}
+
+type jsonBucketServerConfig struct {
+	NodesExt         []jsonBucketNodeExt `json:"nodesExt"`
+	VBucketServerMap struct {
+		ServerList []string `json:"serverList"`
+	} `json:"vBucketServerMap"`
+}
+
+// fetchKvServerList fetches the configuration of bucket and returns its kv server list, in the order referred
+// to by the vbucket map. As with the route config built by the agent, the extended node list is preferred so that
+// the TLS ports are used when secure is set.
+func fetchKvServerList(ctx context.Context, provider httpProvider, bucket string, secure bool) ([]string, error) {
+	req := &gocbcore.HttpRequest{
+		Service: gocbcore.ServiceType(MgmtService),
+		Path:    fmt.Sprintf("/pools/default/b/%s", url.PathEscape(bucket)),
+		Method:  "GET",
+		Context: ctx,
+	}
+
+	resp, err := provider.DoHttpRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		err = resp.Body.Close()
+		if err != nil {
+			logDebugf("Failed to close socket (%s)", err)
+		}
+		return nil, errors.Errorf("failed to fetch bucket configuration (%d): %s", resp.StatusCode, data)
+	}
+
+	var config jsonBucketServerConfig
+	jsonDec := json.NewDecoder(resp.Body)
+	err = jsonDec.Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resp.Body.Close()
+	if err != nil {
+		logDebugf("Failed to close socket (%s)", err)
+	}
+
+	if len(config.NodesExt) == 0 {
+		return config.VBucketServerMap.ServerList, nil
+	}
+
+	portName := "kv"
+	if secure {
+		portName = "kvSSL"
+	}
+
+	var servers []string
+	for _, node := range config.NodesExt {
+		port, ok := node.Services[portName]
+		if !ok {
+			continue
+		}
+
+		// The node which served the configuration may omit its own hostname.
+		hostname := node.Hostname
+		if hostname == "" {
+			if endpoint, err := url.Parse(resp.Endpoint); err == nil {
+				hostname = endpoint.Hostname()
+			}
+		}
+
+		servers = append(servers, net.JoinHostPort(hostname, strconv.Itoa(port)))
+	}
+
+	return servers, nil
+}
+
+// routingNodes returns the location of each kv node within the vbucket map.
+func routingNodes(ctx context.Context, cli client, provider kvProvider, serverGroups bool) ([]NodeLocation, error) {
+	addresses := clientKvServerAddresses(cli)
+
+	var groups *jsonServerGroups
+	if serverGroups {
+		httpProvider, err := cli.getHTTPProvider()
+		if err != nil {
+			return nil, err
+		}
+
+		groups, err = fetchServerGroups(ctx, httpProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nodes := make([]NodeLocation, provider.NumServers())
+	for i := range nodes {
+		nodes[i].Index = i
+		if i < len(addresses) {
+			nodes[i].Address = addresses[i]
+		}
+		if groups != nil {
+			nodes[i].ServerGroup = groups.groupFor(nodes[i].Address)
+		}
+	}
+
+	return nodes, nil
+}
+
+// nodeLocation returns the location of the node at index, or a location with an index of -1 if there is none.
+func nodeLocation(nodes []NodeLocation, index int) NodeLocation {
+	if index < 0 || index >= len(nodes) {
+		return NodeLocation{Index: -1}
+	}
+
+	return nodes[index]
+}
+
+type jsonServerGroupNode struct {
+	Hostname string         `json:"hostname"`
+	Ports    map[string]int `json:"ports"`
+}
+
+type jsonServerGroup struct {
+	Name  string                `json:"name"`
+	Nodes []jsonServerGroupNode `json:"nodes"`
+}
+
+type jsonServerGroups struct {
+	Groups []jsonServerGroup `json:"groups"`
+}
+
+// groupFor returns the name of the server group containing the node with the kv service at address.
+func (groups *jsonServerGroups) groupFor(address string) string {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return ""
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	for _, group := range groups.Groups {
+		for _, node := range group.Nodes {
+			nodeHost, _, err := net.SplitHostPort(node.Hostname)
+			if err != nil || nodeHost != host {
+				continue
+			}
+
+			// Several nodes can share a host, in which case the kv ports tell them apart.
+			if len(node.Ports) == 0 || node.Ports["direct"] == port || node.Ports["sslDirect"] == port {
+				return group.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+func fetchServerGroups(ctx context.Context, provider httpProvider) (*jsonServerGroups, error) {
+	req := &gocbcore.HttpRequest{
+		Service: gocbcore.ServiceType(MgmtService),
+		Path:    "/pools/default/serverGroups",
+		Method:  "GET",
+		Context: ctx,
+	}
+
+	resp, err := provider.DoHttpRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		err = resp.Body.Close()
+		if err != nil {
+			logDebugf("Failed to close socket (%s)", err)
+		}
+		return nil, errors.Errorf("failed to fetch server groups (%d): %s", resp.StatusCode, data)
+	}
+
+	var groups jsonServerGroups
+	jsonDec := json.NewDecoder(resp.Body)
+	err = jsonDec.Decode(&groups)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resp.Body.Close()
+	if err != nil {
+		logDebugf("Failed to close socket (%s)", err)
+	}
+
+	return &groups, nil
+}
@@ -0,0 +1,92 @@
+package gocb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func TestVbucketMap(t *testing.T) {
+	provider := &mockKvProvider{
+		numReplicas: 1,
+		numServers:  2,
+		numVbuckets: 4,
+	}
+	col := testRoutingCollection(t, provider)
+	b := &Bucket{sb: col.sb}
+
+	vbMap, err := b.VbucketMap(&VbucketMapOptions{ServerGroups: true})
+	if err != nil {
+		t.Fatalf("Expected VbucketMap to not error %v", err)
+	}
+
+	if vbMap.NumReplicas != 1 || len(vbMap.Vbuckets) != 4 || len(vbMap.Nodes) != 2 {
+		t.Fatalf("Vbucket map was not as expected: %+v", vbMap)
+	}
+
+	if vbMap.Nodes[1].Address != "10.0.0.2:11210" || vbMap.Nodes[1].ServerGroup != "Group 2" {
+		t.Fatalf("Expected second node to be 10.0.0.2:11210 in Group 2 but was %v", vbMap.Nodes[1])
+	}
+
+	if len(vbMap.ActiveVbuckets(0)) != 4 || len(vbMap.ReplicaVbuckets(1)) != 4 || len(vbMap.ActiveVbuckets(1)) != 0 {
+		t.Fatalf("Expected all vbuckets to be active on the first node and replicated to the second")
+	}
+}
+
+func TestServerGroupForSharedHost(t *testing.T) {
+	groups := &jsonServerGroups{
+		Groups: []jsonServerGroup{
+			{Name: "Group 1", Nodes: []jsonServerGroupNode{{Hostname: "127.0.0.1:9000", Ports: map[string]int{"direct": 12000}}}},
+			{Name: "Group 2", Nodes: []jsonServerGroupNode{{Hostname: "127.0.0.1:9001", Ports: map[string]int{"direct": 12002}}}},
+		},
+	}
+
+	if group := groups.groupFor("127.0.0.1:12002"); group != "Group 2" {
+		t.Fatalf("Expected Group 2 but was %s", group)
+	}
+
+	if group := groups.groupFor("127.0.0.2:12002"); group != "" {
+		t.Fatalf("Expected no group but was %s", group)
+	}
+}
+
+func TestFetchKvServerList(t *testing.T) {
+	body := `{"vBucketServerMap":{"serverList":["10.0.0.1:11210","10.0.0.2:11210"]},"nodesExt":[` +
+		`{"services":{"kv":11210,"kvSSL":11207,"mgmt":8091},"thisNode":true},` +
+		`{"hostname":"10.0.0.2","services":{"kv":11210,"kvSSL":11207,"mgmt":8091}},` +
+		`{"hostname":"10.0.0.3","services":{"n1ql":8093,"mgmt":8091}}]}`
+	provider := &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			if req.Path != "/pools/default/b/travel-sample" {
+				t.Fatalf("Expected request to bucket configuration but was %s", req.Path)
+			}
+
+			return &gocbcore.HttpResponse{
+				Endpoint:   "http://10.0.0.1:8091",
+				StatusCode: 200,
+				Body:       &testReadCloser{bytes.NewBufferString(body), nil},
+			}, nil
+		},
+	}
+
+	servers, err := fetchKvServerList(context.Background(), provider, "travel-sample", true)
+	if err != nil {
+		t.Fatalf("Expected server list to be fetched but was %v", err)
+	}
+
+	if len(servers) != 2 || servers[0] != "10.0.0.1:11207" || servers[1] != "10.0.0.2:11207" {
+		t.Fatalf("Expected the TLS kv ports of the kv nodes but was %v", servers)
+	}
+
+	body = `{"vBucketServerMap":{"serverList":["10.0.0.1:11210","10.0.0.2:11210"]}}`
+	servers, err = fetchKvServerList(context.Background(), provider, "travel-sample", false)
+	if err != nil {
+		t.Fatalf("Expected server list to be fetched but was %v", err)
+	}
+
+	if len(servers) != 2 || servers[1] != "10.0.0.2:11210" {
+		t.Fatalf("Expected the vbucket map server list but was %v", servers)
+	}
+}
@@ -2,10 +2,13 @@ package gocb
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/google/uuid"
 
 	"github.com/pkg/errors"
 )
@@ -17,6 +20,9 @@ type client interface {
 	getKvProvider() (kvProvider, error)
 	getHTTPProvider() (httpProvider, error)
 	getDiagnosticsProvider() (diagnosticsProvider, error)
+	getDcpProvider() (dcpProvider, error)
+	supportsEnhancedDurability() bool
+	kvServerAddresses() []string
 	close() error
 }
 
@@ -26,6 +32,20 @@ type stdClient struct {
 	lock         sync.Mutex
 	agent        *gocbcore.Agent
 	bootstrapErr error
+
+	// dcpAgent is only created once a change stream is required.
+	dcpAgent *gocbcore.Agent
+
+	// durabilityUnsupported, durabilityCheckedAt and durabilityDetecting are accessed atomically so that durable
+	// writes never wait for support to be detected.
+	durabilityUnsupported uint32
+	durabilityCheckedAt   int64
+	durabilityDetecting   uint32
+
+	kvServersLock       sync.Mutex
+	kvServers           []string
+	kvServersCheckedAt  time.Time
+	kvServersRefreshing bool
 }
 
 func newClient(cluster *Cluster, sb *clientStateBlock) *stdClient {
@@ -45,6 +65,29 @@ func (c *stdClient) connect() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	config, err := c.agentConfig()
+	if err != nil {
+		c.bootstrapErr = err
+		return c.bootstrapErr
+	}
+
+	agent, err := gocbcore.CreateAgent(config)
+	if err != nil {
+		c.bootstrapErr = maybeEnhanceKVErr(err, "", false)
+		return c.bootstrapErr
+	}
+
+	c.agent = agent
+	c.refreshEnhancedDurability()
+
+	c.kvServersLock.Lock()
+	c.refreshKvServerListLocked()
+	c.kvServersLock.Unlock()
+
+	return nil
+}
+
+func (c *stdClient) agentConfig() (*gocbcore.AgentConfig, error) {
 	auth := c.cluster.auth
 
 	config := &gocbcore.AgentConfig{
@@ -61,27 +104,23 @@ func (c *stdClient) connect() error {
 
 	err := config.FromConnStr(c.cluster.connSpec().String())
 	if err != nil {
-		c.bootstrapErr = err
-		return c.bootstrapErr
+		return nil, err
 	}
 
 	useCertificates := config.TlsConfig != nil && len(config.TlsConfig.Certificates) > 0
 	if useCertificates {
 		if auth == nil {
-			c.bootstrapErr = configurationError{message: "invalid mixed authentication configuration, client certificate and CertAuthenticator must be used together"}
-			return c.bootstrapErr
+			return nil, configurationError{message: "invalid mixed authentication configuration, client certificate and CertAuthenticator must be used together"}
 		}
 		_, ok := auth.(CertAuthenticator)
 		if !ok {
-			c.bootstrapErr = configurationError{message: "invalid mixed authentication configuration, client certificate and CertAuthenticator must be used together"}
-			return c.bootstrapErr
+			return nil, configurationError{message: "invalid mixed authentication configuration, client certificate and CertAuthenticator must be used together"}
 		}
 	}
 
 	_, ok := auth.(CertAuthenticator)
 	if ok && !useCertificates {
-		c.bootstrapErr = configurationError{message: "invalid mixed authentication configuration, client certificate and CertAuthenticator must be used together"}
-		return c.bootstrapErr
+		return nil, configurationError{message: "invalid mixed authentication configuration, client certificate and CertAuthenticator must be used together"}
 	}
 
 	config.BucketName = c.state.BucketName
@@ -91,14 +130,7 @@ func (c *stdClient) connect() error {
 		bucketName: c.state.BucketName,
 	}
 
-	agent, err := gocbcore.CreateAgent(config)
-	if err != nil {
-		c.bootstrapErr = maybeEnhanceKVErr(err, "", false)
-		return c.bootstrapErr
-	}
-
-	c.agent = agent
-	return nil
+	return config, nil
 }
 
 func (c *stdClient) getKvProvider() (kvProvider, error) {
@@ -131,6 +163,141 @@ func (c *stdClient) getDiagnosticsProvider() (diagnosticsProvider, error) {
 	return c.agent, nil
 }
 
+// getDcpProvider returns an agent for opening DCP streams against the bucket, creating it on first use.
+func (c *stdClient) getDcpProvider() (dcpProvider, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.bootstrapErr != nil {
+		return nil, c.bootstrapErr
+	}
+
+	if c.agent == nil {
+		return nil, errors.New("Cluster not yet connected")
+	}
+
+	if c.dcpAgent != nil {
+		return c.dcpAgent, nil
+	}
+
+	config, err := c.agentConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Streams are only used to observe which keys change, so document bodies are not needed.
+	streamName := fmt.Sprintf("%s-%s", Identifier(), uuid.New().String())
+	agent, err := gocbcore.CreateDcpAgent(config, streamName, gocbcore.DcpOpenFlagProducer|gocbcore.DcpOpenFlagNoValue)
+	if err != nil {
+		return nil, maybeEnhanceKVErr(err, "", false)
+	}
+
+	c.dcpAgent = agent
+	return c.dcpAgent, nil
+}
+
+// supportsEnhancedDurability returns whether the cluster supports synchronous durability. Support is detected from
+// the cluster compatibility version in the background once the client connects, and detected again once
+// durabilitySupportInterval has passed so that support is picked up when an upgrade of the cluster completes. Until
+// it has first been detected the cluster is assumed to support it.
+func (c *stdClient) supportsEnhancedDurability() bool {
+	checkedAt := atomic.LoadInt64(&c.durabilityCheckedAt)
+	if checkedAt != 0 && time.Since(time.Unix(0, checkedAt)) >= durabilitySupportInterval {
+		c.refreshEnhancedDurability()
+	}
+
+	return atomic.LoadUint32(&c.durabilityUnsupported) == 0
+}
+
+// refreshEnhancedDurability detects support for synchronous durability in the background, unless it is already
+// being detected.
+func (c *stdClient) refreshEnhancedDurability() {
+	if !atomic.CompareAndSwapUint32(&c.durabilityDetecting, 0, 1) {
+		return
+	}
+
+	go func() {
+		var unsupported uint32
+		if !c.detectEnhancedDurability() {
+			unsupported = 1
+		}
+
+		atomic.StoreUint32(&c.durabilityUnsupported, unsupported)
+		atomic.StoreInt64(&c.durabilityCheckedAt, time.Now().UnixNano())
+		atomic.StoreUint32(&c.durabilityDetecting, 0)
+	}()
+}
+
+func (c *stdClient) detectEnhancedDurability() bool {
+	provider, err := c.getHTTPProvider()
+	if err != nil {
+		logDebugf("Failed to detect synchronous durability support (%s)", err)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cluster.sb.KvTimeout)
+	defer cancel()
+
+	supported, err := clusterSupportsEnhancedDurability(ctx, provider)
+	if err != nil {
+		// When support is unknown the agent rejects synchronous durability itself if the node lacks it.
+		logDebugf("Failed to detect synchronous durability support (%s)", err)
+		return true
+	}
+
+	return supported
+}
+
+// kvServerAddresses returns the kv server list of the bucket, indexed by position within the vbucket map. The list
+// is fetched in the background once the client connects, and fetched again once kvServerListInterval has passed or
+// the number of servers known to the agent changes. It is empty until it has first been fetched.
+func (c *stdClient) kvServerAddresses() []string {
+	c.kvServersLock.Lock()
+	defer c.kvServersLock.Unlock()
+
+	if elapsed := time.Since(c.kvServersCheckedAt); !c.kvServersRefreshing && (elapsed >= kvServerListInterval ||
+		elapsed >= kvServerListMinInterval && c.agent != nil && c.agent.NumServers() != len(c.kvServers)) {
+		c.refreshKvServerListLocked()
+	}
+
+	return c.kvServers
+}
+
+// refreshKvServerListLocked fetches the kv server list in the background, kvServersLock must be held.
+func (c *stdClient) refreshKvServerListLocked() {
+	c.kvServersRefreshing = true
+	go func() {
+		servers := c.fetchKvServerList()
+
+		c.kvServersLock.Lock()
+		if servers != nil {
+			c.kvServers = servers
+		}
+		c.kvServersCheckedAt = time.Now()
+		c.kvServersRefreshing = false
+		c.kvServersLock.Unlock()
+	}()
+}
+
+func (c *stdClient) fetchKvServerList() []string {
+	provider, err := c.getHTTPProvider()
+	if err != nil {
+		logDebugf("Failed to fetch kv server list (%s)", err)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cluster.sb.KvTimeout)
+	defer cancel()
+
+	servers, err := fetchKvServerList(ctx, provider, c.state.BucketName, c.agent.IsSecure())
+	if err != nil {
+		logDebugf("Failed to fetch kv server list (%s)", err)
+		return nil
+	}
+
+	return servers
+}
+
 func (c *stdClient) openCollection(ctx context.Context, scopeName string, collectionName string) {
 	if scopeName == "_default" && collectionName == "_default" {
 		return
@@ -184,5 +351,16 @@ func (c *stdClient) close() error {
 	if c.agent == nil {
 		return errors.New("Cluster not yet connected")
 	}
+
+	c.lock.Lock()
+	dcpAgent := c.dcpAgent
+	c.lock.Unlock()
+	if dcpAgent != nil {
+		err := dcpAgent.Close()
+		if err != nil {
+			logDebugf("Failed to close DCP agent (%s)", err)
+		}
+	}
+
 	return c.agent.Close()
 }
@@ -20,7 +20,7 @@ type Cluster struct {
 	connections     map[string]client
 
 	clusterLock sync.RWMutex
-	queryCache  map[string]*n1qlCache
+	queryCache  *preparedCache
 
 	sb stateBlock
 
@@ -43,6 +43,29 @@ type ClusterOptions struct {
 	// Serializer is used for deserialization of data used in query, analytics, view and search operations. This
 	// will default to DefaultJSONSerializer. NOTE: This is entirely independent of Transcoder.
 	Serializer JSONSerializer
+	// DurabilityPollBehavior controls the interval between polls when performing observe based durability,
+	// polling stops early once CanRetry returns false. If nil then a fixed interval of 100ms is used.
+	DurabilityPollBehavior RetryBehavior
+	// AmbiguousDurabilityErrors causes observe based durability failures to be returned as ambiguous errors,
+	// see IsDurabilityAmbiguousError.
+	AmbiguousDurabilityErrors bool
+	// DurabilityFallback causes operations specifying a DurabilityLevel to use the observe based equivalent when
+	// the server does not support synchronous durability. Majority maps to ReplicateTo a majority of nodes
+	// (excluding the active), MajorityAndPersistActive additionally requires PersistTo 1 and PersistToMajority
+	// requires PersistTo a majority of nodes. Mutation tokens must be enabled on the bucket for this to apply.
+	// Support is detected from the cluster compatibility version before operations are dispatched, and is
+	// detected again periodically so that synchronous durability is used once the cluster has been upgraded.
+	DurabilityFallback bool
+	// ReplicaFallbackHandler is called whenever a Get falls back to reading from a replica, see
+	// GetOptions.ReplicaFallback. It can be used to record fallbacks in tracing or metrics systems and must not
+	// block.
+	ReplicaFallbackHandler func(ReplicaFallbackEvent)
+	// PreparedStatementCacheSize is the maximum number of prepared statements cached for queries executed with
+	// QueryOptions.Prepared, the least recently used statement is evicted once it is reached. Defaults to 5000.
+	PreparedStatementCacheSize int
+	// PreparedStatementCacheTTL is the maximum amount of time that a prepared statement is cached for, statements
+	// are prepared again once it has passed. Defaults to no expiry.
+	PreparedStatementCacheTTL time.Duration
 }
 
 // ClusterCloseOptions is the set of options available when disconnecting from a Cluster.
@@ -130,11 +153,20 @@ func Connect(connStr string, opts ClusterOptions) (*Cluster, error) {
 			KvTimeout:              kvTimeout,
 			DuraTimeout:            40000 * time.Millisecond,
 			DuraPollTimeout:        100 * time.Millisecond,
+			DuraPollBehavior:       opts.DurabilityPollBehavior,
 			Transcoder:             opts.Transcoder,
 			Serializer:             opts.Serializer,
+
+			AmbiguousDurabilityErrors: opts.AmbiguousDurabilityErrors,
+			DurabilityFallback:        opts.DurabilityFallback,
+
+			ReplicaFallbackHandler: opts.ReplicaFallbackHandler,
+
+			KvStats:    newKvStatsRegistry(),
+			NearCaches: newNearCacheRegistry(),
 		},
 
-		queryCache: make(map[string]*n1qlCache),
+		queryCache: newPreparedCache(opts.PreparedStatementCacheSize, opts.PreparedStatementCacheTTL),
 	}
 
 	err = cluster.parseExtraConnStrOptions(connSpec)
@@ -195,6 +227,7 @@ func (c *Cluster) Bucket(bucketName string, opts *BucketOptions) *Bucket {
 		opts = &BucketOptions{}
 	}
 	b := newBucket(&c.sb, bucketName, *opts)
+	b.cluster = c
 	cli := c.getClient(&b.sb.clientStateBlock)
 	b.cacheClient(cli)
 	err := cli.connect()
@@ -68,12 +68,14 @@ type AnalyticsResultsMetadata struct {
 // AnalyticsResults allows access to the results of an Analytics query.
 type AnalyticsResults struct {
 	metadata   AnalyticsResultsMetadata
+	statement  string
 	err        error
 	httpStatus int
 
 	handle       *AnalyticsDeferredResultHandle
 	streamResult *streamingResult
 	cancel       context.CancelFunc
+	canceller    *requestCanceller
 	httpProvider httpProvider
 	ctx          context.Context
 
@@ -120,7 +122,11 @@ func (r *AnalyticsResults) Close() error {
 		return r.err
 	}
 
+	completed := r.streamResult.allRowsRead
 	err := r.streamResult.Close()
+	if r.canceller != nil {
+		r.canceller.finish(completed)
+	}
 	ctxErr := r.ctx.Err()
 	if r.cancel != nil {
 		r.cancel()
@@ -247,16 +253,17 @@ func (r *AnalyticsResults) readAttribute(decoder *json.Decoder, t json.Token) (b
 			ProcessedObjects: metrics.ProcessedObjects,
 		}
 	case "errors":
-		var respErrs []analyticsQueryError
+		var respErrs []AnalyticsErrorEntry
 		err := decoder.Decode(&respErrs)
 		if err != nil {
 			return false, err
 		}
 		if len(respErrs) > 0 {
 			// this isn't an error that we want to bail on so store it and keep going
-			respErr := respErrs[0]
+			respErr := newAnalyticsQueryError(respErrs)
+			respErr.statement = r.statement
 			respErr.endpoint = r.metadata.sourceAddr
-			respErr.httpStatus = 200
+			respErr.httpStatus = r.httpStatus
 			respErr.contextID = r.metadata.clientContextID
 			r.err = respErr
 		}
@@ -322,17 +329,26 @@ func (c *Cluster) AnalyticsQuery(statement string, opts *AnalyticsQueryOptions)
 		return nil, err
 	}
 
-	return c.analyticsQuery(ctx, statement, opts, provider)
+	return c.analyticsQuery(ctx, statement, "", opts, provider)
 }
 
-func (c *Cluster) analyticsQuery(ctx context.Context, statement string, opts *AnalyticsQueryOptions,
+// analyticsQuery executes statement, queryContext is used to resolve unqualified datasets and may be empty.
+func (c *Cluster) analyticsQuery(ctx context.Context, statement, queryContext string, opts *AnalyticsQueryOptions,
 	provider httpProvider) (*AnalyticsResults, error) {
 
+	if opts.Serializer == nil {
+		opts.Serializer = c.sb.Serializer
+	}
+
 	queryOpts, err := opts.toMap(statement)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not parse query options")
 	}
 
+	if queryContext != "" {
+		queryOpts["query_context"] = queryContext
+	}
+
 	timeout := c.sb.AnalyticsTimeout
 	tmostr, castok := queryOpts["timeout"].(string)
 	if castok {
@@ -364,10 +380,6 @@ func (c *Cluster) analyticsQuery(ctx context.Context, statement string, opts *An
 		queryOpts["timeout"] = newTimeout.String()
 	}
 
-	if opts.Serializer == nil {
-		opts.Serializer = c.sb.Serializer
-	}
-
 	var retries uint
 	var res *AnalyticsResults
 	for {
@@ -432,6 +444,10 @@ func (c *Cluster) executeAnalyticsQuery(ctx context.Context, opts map[string]int
 		if ctx.Err() == context.DeadlineExceeded {
 			return nil, timeoutError{}
 		}
+		if ctx.Err() == context.Canceled {
+			// The request may have reached the server, which would carry on executing it.
+			go c.cancelAnalyticsRequest(provider, req.Endpoint, generatedClientContextID(opts))
+		}
 		return nil, errors.Wrap(err, "could not complete analytics http request")
 	}
 
@@ -451,6 +467,7 @@ func (c *Cluster) executeAnalyticsQuery(ctx context.Context, opts map[string]int
 		httpProvider: provider,
 		serializer:   serializer,
 	}
+	queryResults.statement, _ = opts["statement"].(string)
 
 	streamResult, err := newStreamingResults(resp.Body, queryResults.readAttribute)
 	if err != nil {
@@ -463,6 +480,9 @@ func (c *Cluster) executeAnalyticsQuery(ctx context.Context, opts map[string]int
 		if bodyErr != nil {
 			logDebugf("Failed to close socket (%s)", bodyErr.Error())
 		}
+		if ctx.Err() == context.Canceled {
+			go c.cancelAnalyticsRequest(provider, resp.Endpoint, generatedClientContextID(opts))
+		}
 		return nil, err
 	}
 
@@ -471,6 +491,11 @@ func (c *Cluster) executeAnalyticsQuery(ctx context.Context, opts map[string]int
 	if streamResult.HasRows() {
 		queryResults.cancel = cancel
 		queryResults.ctx = ctx
+
+		clientContextID := generatedClientContextID(opts)
+		queryResults.canceller = newRequestCanceller(ctx, func() {
+			c.cancelAnalyticsRequest(provider, resp.Endpoint, clientContextID)
+		})
 	} else {
 		bodyErr := streamResult.Close()
 		if bodyErr != nil {
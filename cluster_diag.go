@@ -85,6 +85,8 @@ type DiagnosticsResult struct {
 	ConfigRev int64
 	SDK       string
 	Services  []DiagnosticEntry
+	// KvStats are the statistics of the collections which have hot key sampling enabled.
+	KvStats *KvStatsResult
 }
 
 type jsonDiagnosticEntry struct {
@@ -94,12 +96,66 @@ type jsonDiagnosticEntry struct {
 	LastActivityUs uint64 `json:"last_activity_us"`
 }
 
+type jsonKvKeyStats struct {
+	Key        string `json:"key"`
+	Operations uint64 `json:"operations"`
+	Bytes      uint64 `json:"bytes"`
+	LatencyUs  uint64 `json:"latency_us"`
+	Error      uint64 `json:"error"`
+}
+
+type jsonKvStatsWindow struct {
+	Start           string           `json:"start"`
+	End             string           `json:"end"`
+	Operations      uint64           `json:"operations"`
+	Bytes           uint64           `json:"bytes"`
+	TopByOperations []jsonKvKeyStats `json:"top_by_operations"`
+	TopByBytes      []jsonKvKeyStats `json:"top_by_bytes"`
+	TopByLatency    []jsonKvKeyStats `json:"top_by_latency"`
+}
+
+type jsonCollectionKvStats struct {
+	Bucket     string             `json:"bucket"`
+	Scope      string             `json:"scope"`
+	Collection string             `json:"collection"`
+	Current    jsonKvStatsWindow  `json:"current"`
+	Previous   *jsonKvStatsWindow `json:"previous,omitempty"`
+}
+
 type jsonDiagnosticReport struct {
 	Version   int                              `json:"version"`
 	ID        string                           `json:"id"`
 	ConfigRev int64                            `json:"config_rev"`
 	SDK       string                           `json:"sdk"`
 	Services  map[string][]jsonDiagnosticEntry `json:"services"`
+	KvStats   []jsonCollectionKvStats          `json:"kv_stats,omitempty"`
+}
+
+func jsonKvKeyStatsList(stats []KvKeyStats) []jsonKvKeyStats {
+	jsonStats := make([]jsonKvKeyStats, len(stats))
+	for i, stat := range stats {
+		jsonStats[i] = jsonKvKeyStats{
+			Key:        stat.Key,
+			Operations: stat.Operations,
+			Bytes:      stat.Bytes,
+			LatencyUs:  uint64(stat.Latency / time.Microsecond),
+			Error:      stat.Error,
+		}
+	}
+
+	return jsonStats
+}
+
+func (window *KvStatsWindow) toJSON() *jsonKvStatsWindow {
+	return &jsonKvStatsWindow{
+		Start:           window.Start.Format(time.RFC3339Nano),
+		End:             window.End.Format(time.RFC3339Nano),
+		Operations:      window.Operations,
+		Bytes:           window.Bytes,
+		TopByOperations: jsonKvKeyStatsList(window.TopByOperations),
+		TopByBytes:      jsonKvKeyStatsList(window.TopByBytes),
+		TopByLatency:    jsonKvKeyStatsList(window.TopByLatency),
+	}
 }
 
 // MarshalJSON generates a JSON representation of this diagnostics report.
@@ -131,6 +187,21 @@ func (report *DiagnosticsResult) MarshalJSON() ([]byte, error) {
 		})
 	}
 
+	if report.KvStats != nil {
+		for _, stats := range report.KvStats.Collections {
+			jsonStats := jsonCollectionKvStats{
+				Bucket:     stats.BucketName,
+				Scope:      stats.ScopeName,
+				Collection: stats.CollectionName,
+				Current:    *stats.Current.toJSON(),
+			}
+			if stats.Previous != nil {
+				jsonStats.Previous = stats.Previous.toJSON()
+			}
+			jsonReport.KvStats = append(jsonReport.KvStats, jsonStats)
+		}
+	}
+
 	return json.Marshal(&jsonReport)
 }
 
@@ -170,6 +241,7 @@ func (c *Cluster) Diagnostics(opts *DiagnosticsOptions) (*DiagnosticsResult, err
 		ID:        opts.ReportID,
 		ConfigRev: agentReport.ConfigRev,
 		SDK:       Identifier(),
+		KvStats:   c.KvStats(),
 	}
 
 	for _, conn := range agentReport.MemdConns {
@@ -189,3 +261,11 @@ func (c *Cluster) Diagnostics(opts *DiagnosticsOptions) (*DiagnosticsResult, err
 
 	return report, nil
 }
+
+// KvStats returns the statistics of the collections which have hot key sampling enabled, see
+// CollectionOptions.HotKeys. Keys are redacted according to the log redaction level.
+//
+// Volatile: This API is subject to change at any time.
+func (c *Cluster) KvStats() *KvStatsResult {
+	return c.sb.KvStats.stats()
+}
@@ -0,0 +1,310 @@
+package gocb
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultPreparedStatementCacheSize = 5000
+
+// PreparedStatementCacheStats are the statistics recorded by the prepared statement cache of a cluster.
+type PreparedStatementCacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	// Invalidations is the number of entries removed because the server reported that they were no longer valid.
+	Invalidations uint64
+	Entries       int
+}
+
+// HitRatio returns the fraction of prepared queries which were served from the cache.
+func (s PreparedStatementCacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
+
+// preparedCache is a LRU cache of prepared statements, keyed by statement along with the query context which it was
+// prepared in.
+type preparedCache struct {
+	lock    sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List
+	now     func() time.Time
+
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	expirations   uint64
+	invalidations uint64
+}
+
+type preparedCacheEntry struct {
+	key       string
+	statement string
+	prepared  *n1qlCache
+	expires   time.Time
+}
+
+func newPreparedCache(size int, ttl time.Duration) *preparedCache {
+	if size <= 0 {
+		size = defaultPreparedStatementCacheSize
+	}
+
+	return &preparedCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+func preparedCacheKey(queryContext, statement string) string {
+	// The same statement can refer to different keyspaces depending on the context it is run in.
+	if queryContext == "" {
+		return statement
+	}
+
+	return queryContext + " " + statement
+}
+
+func (pc *preparedCache) get(key string) *n1qlCache {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	elem, ok := pc.entries[key]
+	if !ok {
+		pc.misses++
+		return nil
+	}
+
+	entry := elem.Value.(*preparedCacheEntry)
+	if !entry.expires.IsZero() && !pc.now().Before(entry.expires) {
+		pc.removeElement(elem)
+		pc.expirations++
+		pc.misses++
+		return nil
+	}
+
+	pc.lru.MoveToFront(elem)
+	pc.hits++
+	return entry.prepared
+}
+
+func (pc *preparedCache) put(key, statement string, prepared *n1qlCache) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	var expires time.Time
+	if pc.ttl > 0 {
+		expires = pc.now().Add(pc.ttl)
+	}
+
+	if elem, ok := pc.entries[key]; ok {
+		entry := elem.Value.(*preparedCacheEntry)
+		entry.prepared = prepared
+		entry.expires = expires
+		pc.lru.MoveToFront(elem)
+		return
+	}
+
+	pc.entries[key] = pc.lru.PushFront(&preparedCacheEntry{
+		key:       key,
+		statement: statement,
+		prepared:  prepared,
+		expires:   expires,
+	})
+
+	for pc.lru.Len() > pc.size {
+		pc.removeElement(pc.lru.Back())
+		pc.evictions++
+	}
+}
+
+// invalidate removes an entry which the server has reported is no longer valid.
+func (pc *preparedCache) invalidate(key string) {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	if elem, ok := pc.entries[key]; ok {
+		pc.removeElement(elem)
+		pc.invalidations++
+	}
+}
+
+// removeStatement removes statement from the cache for every query context it was prepared in.
+func (pc *preparedCache) removeStatement(statement string) int {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	var removed int
+	for elem := pc.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*preparedCacheEntry).statement == statement {
+			pc.removeElement(elem)
+			removed++
+		}
+		elem = next
+	}
+
+	return removed
+}
+
+func (pc *preparedCache) clear() {
+	pc.lock.Lock()
+	pc.entries = make(map[string]*list.Element)
+	pc.lru.Init()
+	pc.lock.Unlock()
+}
+
+func (pc *preparedCache) removeElement(elem *list.Element) {
+	pc.lru.Remove(elem)
+	delete(pc.entries, elem.Value.(*preparedCacheEntry).key)
+}
+
+func (pc *preparedCache) snapshot() PreparedStatementCacheStats {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	return PreparedStatementCacheStats{
+		Hits:          pc.hits,
+		Misses:        pc.misses,
+		Evictions:     pc.evictions,
+		Expirations:   pc.expirations,
+		Invalidations: pc.invalidations,
+		Entries:       pc.lru.Len(),
+	}
+}
+
+// PreparedStatementCache provides access to the statements which have been prepared by queries executed with
+// QueryOptions.Prepared.
+type PreparedStatementCache struct {
+	cluster *Cluster
+}
+
+// PreparedStatements returns the prepared statement cache of the cluster.
+func (c *Cluster) PreparedStatements() *PreparedStatementCache {
+	return &PreparedStatementCache{cluster: c}
+}
+
+// Stats returns the statistics of the cache.
+func (psc *PreparedStatementCache) Stats() PreparedStatementCacheStats {
+	return psc.cluster.queryCache.snapshot()
+}
+
+// Clear removes every statement from the cache, so that each statement is prepared again the next time it is
+// executed.
+func (psc *PreparedStatementCache) Clear() {
+	psc.cluster.queryCache.clear()
+}
+
+// Remove removes statement from the cache, including where it was prepared within a scope. It returns whether the
+// statement was cached.
+func (psc *PreparedStatementCache) Remove(statement string) bool {
+	return psc.cluster.queryCache.removeStatement(statement) > 0
+}
+
+// NamedStatement is a statement to prepare, the name is used to identify the statement in errors.
+type NamedStatement struct {
+	Name      string
+	Statement string
+	// Scope prepares the statement for execution with Scope.Query, resolving unqualified keyspaces within the
+	// scope. When nil the statement is prepared for execution with Cluster.Query.
+	Scope *Scope
+}
+
+// WarmPreparedStatementsOptions is the set of options available to PreparedStatementCache Warm.
+type WarmPreparedStatementsOptions struct {
+	// Timeout applies to preparing each statement, defaults to the query timeout of the cluster.
+	Timeout time.Duration
+	Context context.Context
+}
+
+// Warm prepares statements and adds them to the cache, so that the first queries which execute them with
+// QueryOptions.Prepared do not have to. Every statement is attempted, the returned error lists those which could
+// not be prepared.
+func (psc *PreparedStatementCache) Warm(statements []NamedStatement, opts *WarmPreparedStatementsOptions) error {
+	if opts == nil {
+		opts = &WarmPreparedStatementsOptions{}
+	}
+
+	c := psc.cluster
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = c.sb.QueryTimeout
+	}
+
+	var failed []string
+	for _, stmt := range statements {
+		err := c.warmPreparedStatement(opts.Context, stmt, timeout)
+		if err != nil {
+			logDebugf("Failed to prepare statement %s (%s)", stmt.Name, err)
+			failed = append(failed, fmt.Sprintf("%s: %s", stmt.Name, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("failed to prepare %d of %d statements: %s", len(failed), len(statements),
+			strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// warmPreparedStatement prepares stmt within the same query context, and caches it under the same key, as query
+// would when executing it.
+func (c *Cluster) warmPreparedStatement(ctx context.Context, stmt NamedStatement, timeout time.Duration) error {
+	var provider httpProvider
+	var queryContext string
+	var err error
+	if stmt.Scope != nil {
+		provider, err = stmt.Scope.sb.getCachedClient().getHTTPProvider()
+		queryContext = stmt.Scope.queryContext()
+	} else {
+		provider, err = c.getHTTPProvider()
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := contextFromMaybeTimeout(ctx, timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	queryOpts, err := (&QueryOptions{Timeout: timeout}).toMap(stmt.Statement)
+	if err != nil {
+		return err
+	}
+
+	if queryContext != "" {
+		queryOpts["query_context"] = queryContext
+	}
+
+	c.checkEnhancedPreparedStatements(provider)
+
+	prepared, err := c.prepareN1qlQuery(ctx, queryOpts, provider)
+	if err != nil {
+		return err
+	}
+
+	if c.supportsEnhancedPreparedStatements() {
+		prepared = &n1qlCache{enhanced: true, name: prepared.name}
+	}
+
+	c.queryCache.put(preparedCacheKey(queryContext, stmt.Statement), stmt.Statement, prepared)
+	return nil
+}
@@ -0,0 +1,256 @@
+package gocb
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func testNewPreparedCache(entries map[string]*n1qlCache) *preparedCache {
+	cache := newPreparedCache(0, 0)
+	for key, prepared := range entries {
+		cache.put(key, key, prepared)
+	}
+
+	return cache
+}
+
+// testEntries returns the entries of the cache without affecting the statistics or recency of any entry.
+func (pc *preparedCache) testEntries() map[string]*n1qlCache {
+	pc.lock.Lock()
+	defer pc.lock.Unlock()
+
+	entries := make(map[string]*n1qlCache, len(pc.entries))
+	for key, elem := range pc.entries {
+		entries[key] = elem.Value.(*preparedCacheEntry).prepared
+	}
+
+	return entries
+}
+
+func TestPreparedCacheBounds(t *testing.T) {
+	cache := newPreparedCache(2, time.Minute)
+	now := time.Unix(1571057032, 0)
+	cache.now = func() time.Time {
+		return now
+	}
+
+	cache.put("a", "a", &n1qlCache{name: "a"})
+	cache.put("b", "b", &n1qlCache{name: "b"})
+	if cache.get("a") == nil {
+		t.Fatalf("Expected a to be cached")
+	}
+
+	cache.put("c", "c", &n1qlCache{name: "c"})
+	if cache.get("b") != nil {
+		t.Fatalf("Expected the least recently used entry to be evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if cache.get("a") != nil {
+		t.Fatalf("Expected the entry to have expired")
+	}
+
+	stats := cache.snapshot()
+	expected := PreparedStatementCacheStats{Hits: 1, Misses: 2, Evictions: 1, Expirations: 1, Entries: 1}
+	if stats != expected {
+		t.Fatalf("Expected stats to be %+v but was %+v", expected, stats)
+	}
+
+	if stats.HitRatio() != float64(1)/3 {
+		t.Fatalf("Expected hit ratio to be a third but was %f", stats.HitRatio())
+	}
+}
+
+func TestPreparedStatementsRemoveAndClear(t *testing.T) {
+	cluster := testGetClusterForHTTP(&mockHTTPProvider{}, time.Second, 0, 0)
+	statement := "SELECT * FROM airline"
+
+	cluster.queryCache.put(preparedCacheKey("", statement), statement, &n1qlCache{name: "one"})
+	cluster.queryCache.put(preparedCacheKey("default:`b`.`s`", statement), statement, &n1qlCache{name: "two"})
+	cluster.queryCache.put("SELECT 1", "SELECT 1", &n1qlCache{name: "three"})
+
+	if !cluster.PreparedStatements().Remove(statement) {
+		t.Fatalf("Expected the statement to be removed")
+	}
+
+	if cluster.PreparedStatements().Remove(statement) {
+		t.Fatalf("Expected the statement to already have been removed")
+	}
+
+	if entries := cluster.queryCache.testEntries(); len(entries) != 1 || entries["SELECT 1"] == nil {
+		t.Fatalf("Expected the statement to be removed from every context but was %v", entries)
+	}
+
+	cluster.PreparedStatements().Clear()
+	if stats := cluster.PreparedStatements().Stats(); stats.Entries != 0 {
+		t.Fatalf("Expected the cache to be empty but was %+v", stats)
+	}
+}
+
+func TestPreparedStatementsInvalidation(t *testing.T) {
+	statement := "SELECT * FROM airline"
+	prepareBytes, err := loadRawTestDataset("query_enhanced_statement")
+	if err != nil {
+		t.Fatalf("Could not read test dataset: %v", err)
+	}
+
+	var requests []map[string]interface{}
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var body map[string]interface{}
+		err := json.Unmarshal(req.Body, &body)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+		requests = append(requests, body)
+
+		respBody := prepareBytes
+		if _, ok := body["prepared"]; ok && len(requests) == 1 {
+			respBody = []byte(`{"errors":[{"code":4080,"msg":"the prepared statement has changed"}],"status":"errors"}`)
+		}
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(respBody), nil},
+		}, nil
+	}
+
+	cluster := testGetClusterForHTTP(&mockHTTPProvider{doFn: doHTTP}, time.Second, 0, 0)
+	cluster.supportsEnhancedStatements = 1
+	cluster.queryCache.put(statement, statement, &n1qlCache{enhanced: true, name: "stale"})
+
+	_, err = cluster.Query(statement, &QueryOptions{Prepared: true})
+	if !IsPreparedStatementFailure(err) {
+		t.Fatalf("Expected a prepared statement failure but was %v", err)
+	}
+
+	stats := cluster.PreparedStatements().Stats()
+	if stats.Invalidations != 1 || stats.Entries != 0 {
+		t.Fatalf("Expected the stale statement to be invalidated but was %+v", stats)
+	}
+
+	_, err = cluster.Query(statement, &QueryOptions{Prepared: true})
+	if err != nil {
+		t.Fatalf("Expected query execution to not error %v", err)
+	}
+
+	if len(requests) != 2 || !strings.HasPrefix(requests[1]["statement"].(string), "PREPARE ") {
+		t.Fatalf("Expected the statement to be prepared again but requests were %v", requests)
+	}
+
+	if entries := cluster.queryCache.testEntries(); entries[statement] == nil || entries[statement].name == "stale" {
+		t.Fatalf("Expected the statement to be cached again but was %v", entries)
+	}
+}
+
+func TestPreparedStatementsWarm(t *testing.T) {
+	prepareBytes, err := loadRawTestDataset("query_enhanced_statement")
+	if err != nil {
+		t.Fatalf("Could not read test dataset: %v", err)
+	}
+
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var body map[string]interface{}
+		err := json.Unmarshal(req.Body, &body)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+
+		respBody := prepareBytes
+		if body["statement"] == "PREPARE SELECT broken" {
+			respBody = []byte(`{"errors":[{"code":3000,"msg":"syntax error"}],"status":"errors"}`)
+		}
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(respBody), nil},
+		}, nil
+	}
+
+	provider := &mockHTTPProvider{
+		doFn: doHTTP,
+		supportFn: func(capability gocbcore.ClusterCapability) bool {
+			return false
+		},
+	}
+
+	cluster := testGetClusterForHTTP(provider, time.Second, 0, 0)
+	err = cluster.PreparedStatements().Warm([]NamedStatement{
+		{Name: "airlines", Statement: "SELECT * FROM airline"},
+		{Name: "broken", Statement: "SELECT broken"},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "broken: [3000] syntax error") ||
+		strings.Contains(err.Error(), "airlines") {
+		t.Fatalf("Expected only the broken statement to fail but was %v", err)
+	}
+
+	cached := cluster.queryCache.testEntries()["SELECT * FROM airline"]
+	if cached == nil || cached.name != "[127.0.0.1:8091]32f2405d-5715-5915-b2b2-d2c557da4996" || cached.encodedPlan == "" {
+		t.Fatalf("Expected the statement to be cached but was %+v", cached)
+	}
+}
+
+func TestPreparedStatementsWarmScope(t *testing.T) {
+	prepareBytes, err := loadRawTestDataset("query_enhanced_statement")
+	if err != nil {
+		t.Fatalf("Could not read test dataset: %v", err)
+	}
+
+	var requests []map[string]interface{}
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var body map[string]interface{}
+		err := json.Unmarshal(req.Body, &body)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+		requests = append(requests, body)
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(prepareBytes), nil},
+		}, nil
+	}
+
+	provider := &mockHTTPProvider{
+		doFn: doHTTP,
+		supportFn: func(capability gocbcore.ClusterCapability) bool {
+			return false
+		},
+	}
+
+	cluster := testGetClusterForHTTP(provider, time.Second, 0, 0)
+	scope := testGetScopeForHTTP(cluster, "inventory")
+	statement := "SELECT * FROM airline"
+
+	err = cluster.PreparedStatements().Warm([]NamedStatement{
+		{Name: "airlines", Statement: statement, Scope: scope},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected warm to succeed but was %v", err)
+	}
+
+	if len(requests) != 1 || requests[0]["query_context"] != scope.queryContext() {
+		t.Fatalf("Expected the statement to be prepared within the scope but requests were %v", requests)
+	}
+
+	entries := cluster.queryCache.testEntries()
+	if len(entries) != 1 || entries[preparedCacheKey(scope.queryContext(), statement)] == nil {
+		t.Fatalf("Expected the statement to be cached for the scope but was %v", entries)
+	}
+
+	_, err = scope.Query(statement, &QueryOptions{Prepared: true})
+	if err != nil {
+		t.Fatalf("Expected query execution to not error %v", err)
+	}
+
+	if len(requests) != 2 || requests[1]["prepared"] == nil || requests[1]["statement"] != nil {
+		t.Fatalf("Expected the scope query to execute the warmed statement but requests were %v", requests)
+	}
+}
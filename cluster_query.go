@@ -62,6 +62,7 @@ type QueryResultsMetadata struct {
 	metrics         QueryResultMetrics
 	signature       interface{}
 	warnings        []QueryWarning
+	profile         *QueryProfile
 	sourceAddr      string
 }
 
@@ -69,11 +70,13 @@ type QueryResultsMetadata struct {
 type QueryResults struct {
 	metadata     QueryResultsMetadata
 	preparedName string
+	statement    string
 	err          error
 	httpStatus   int
 
 	streamResult       *streamingResult
 	cancel             context.CancelFunc
+	canceller          *requestCanceller
 	ctx                context.Context
 	enhancedStatements bool
 
@@ -120,7 +123,11 @@ func (r *QueryResults) Close() error {
 		return r.err
 	}
 
+	completed := r.streamResult.allRowsRead
 	err := r.streamResult.Close()
+	if r.canceller != nil {
+		r.canceller.finish(completed)
+	}
 	ctxErr := r.ctx.Err()
 	if r.cancel != nil {
 		r.cancel()
@@ -200,6 +207,11 @@ func (r *QueryResultsMetadata) Signature() interface{} {
 	return r.signature
 }
 
+// Profile returns the profiling information for the query, it is nil unless QueryOptions.Profile was set.
+func (r *QueryResultsMetadata) Profile() *QueryProfile {
+	return r.profile
+}
+
 func (r *QueryResults) readAttribute(decoder *json.Decoder, t json.Token) (bool, error) {
 	switch t {
 	case "requestID":
@@ -244,14 +256,15 @@ func (r *QueryResults) readAttribute(decoder *json.Decoder, t json.Token) (bool,
 			WarningCount:  metrics.WarningCount,
 		}
 	case "errors":
-		var respErrs []queryError
+		var respErrs []QueryErrorEntry
 		err := decoder.Decode(&respErrs)
 		if err != nil {
 			return false, err
 		}
 		if len(respErrs) > 0 {
 			// this isn't an error that we want to bail on so store it and keep going
-			respErr := respErrs[0]
+			respErr := newQueryError(respErrs)
+			respErr.statement = r.statement
 			respErr.enhancedStmtSupported = r.enhancedStatements
 			respErr.endpoint = r.metadata.sourceAddr
 			respErr.httpStatus = r.httpStatus
@@ -279,6 +292,13 @@ func (r *QueryResults) readAttribute(decoder *json.Decoder, t json.Token) (bool,
 		if err != nil {
 			return false, err
 		}
+	case "profile":
+		var profile n1qlResponseProfile
+		err := decoder.Decode(&profile)
+		if err != nil {
+			return false, err
+		}
+		r.metadata.profile = newQueryProfile(profile)
 	default:
 		var ignore interface{}
 		err := decoder.Decode(&ignore)
@@ -319,17 +339,26 @@ func (c *Cluster) Query(statement string, opts *QueryOptions) (*QueryResults, er
 		return nil, err
 	}
 
-	return c.query(ctx, statement, opts, provider)
+	return c.query(ctx, statement, "", opts, provider)
 }
 
-func (c *Cluster) query(ctx context.Context, statement string, opts *QueryOptions,
+// query executes statement, queryContext is used to resolve unqualified keyspaces and may be empty.
+func (c *Cluster) query(ctx context.Context, statement, queryContext string, opts *QueryOptions,
 	provider httpProvider) (*QueryResults, error) {
 
+	if opts.Serializer == nil {
+		opts.Serializer = c.sb.Serializer
+	}
+
 	queryOpts, err := opts.toMap(statement)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not parse query options")
 	}
 
+	if queryContext != "" {
+		queryOpts["query_context"] = queryContext
+	}
+
 	// Work out which timeout to use, the cluster level default or query specific one
 	timeout := c.sb.QueryTimeout
 	tmostr, castok := queryOpts["timeout"].(string)
@@ -362,10 +391,6 @@ func (c *Cluster) query(ctx context.Context, statement string, opts *QueryOption
 		queryOpts["timeout"] = newTimeout.String()
 	}
 
-	if opts.Serializer == nil {
-		opts.Serializer = c.sb.Serializer
-	}
-
 	var res *QueryResults
 	if opts.Prepared {
 		res, err = c.doPreparedN1qlQuery(ctx, queryOpts, provider, cancel, opts.Serializer)
@@ -386,24 +411,16 @@ func (c *Cluster) query(ctx context.Context, statement string, opts *QueryOption
 
 func (c *Cluster) doPreparedN1qlQuery(ctx context.Context, queryOpts map[string]interface{},
 	provider httpProvider, cancel context.CancelFunc, serializer JSONSerializer) (*QueryResults, error) {
-	if capabilitySupporter, ok := provider.(clusterCapabilityProvider); ok {
-		if !c.supportsEnhancedPreparedStatements() &&
-			capabilitySupporter.SupportsClusterCapability(gocbcore.ClusterCapabilityEnhancedPreparedStatements) {
-			c.setSupportsEnhancedPreparedStatements(true)
-			c.clusterLock.Lock()
-			c.queryCache = make(map[string]*n1qlCache)
-			c.clusterLock.Unlock()
-		}
-	}
+	c.checkEnhancedPreparedStatements(provider)
 
 	stmtStr, isStr := queryOpts["statement"].(string)
 	if !isStr {
 		return nil, configurationError{message: "query statement could not be parsed"}
 	}
 
-	c.clusterLock.RLock()
-	cachedStmt := c.queryCache[stmtStr]
-	c.clusterLock.RUnlock()
+	queryContext, _ := queryOpts["query_context"].(string)
+	cacheKey := preparedCacheKey(queryContext, stmtStr)
+	cachedStmt := c.queryCache.get(cacheKey)
 
 	if cachedStmt != nil {
 		// Attempt to execute our cached query plan
@@ -415,14 +432,23 @@ func (c *Cluster) doPreparedN1qlQuery(ctx context.Context, queryOpts map[string]
 
 		results, err := c.doRetryableQuery(ctx, queryOpts, provider, cancel, serializer)
 		if err == nil {
+			results.statement = stmtStr
 			return results, nil
 		}
 
-		// If we get error 4050, 4070 or 5000, we should attempt
+		if IsPreparedStatementFailure(err) || IsRetryableError(err) {
+			c.queryCache.invalidate(cacheKey)
+		}
+
+		// If the error indicates that the prepared statement is no longer valid then we should attempt
 		//   to re-prepare the statement immediately before failing.
 		if !IsRetryableError(err) {
-			return nil, err
+			return nil, withQueryStatement(err, stmtStr)
 		}
+
+		queryOpts["statement"] = stmtStr
+		delete(queryOpts, "prepared")
+		delete(queryOpts, "encoded_plan")
 	}
 
 	// Prepare the query
@@ -432,9 +458,7 @@ func (c *Cluster) doPreparedN1qlQuery(ctx context.Context, queryOpts map[string]
 			return nil, err
 		}
 
-		c.clusterLock.Lock()
-		c.queryCache[stmtStr] = &n1qlCache{enhanced: true, name: results.preparedName}
-		c.clusterLock.Unlock()
+		c.queryCache.put(cacheKey, stmtStr, &n1qlCache{enhanced: true, name: results.preparedName})
 
 		return results, nil
 	}
@@ -446,16 +470,32 @@ func (c *Cluster) doPreparedN1qlQuery(ctx context.Context, queryOpts map[string]
 	}
 
 	// Save new cached statement
-	c.clusterLock.Lock()
-	c.queryCache[stmtStr] = cachedStmt
-	c.clusterLock.Unlock()
+	c.queryCache.put(cacheKey, stmtStr, cachedStmt)
 
 	// Update with new prepared data
 	delete(queryOpts, "statement")
 	queryOpts["prepared"] = cachedStmt.name
 	queryOpts["encoded_plan"] = cachedStmt.encodedPlan
 
-	return c.doRetryableQuery(ctx, queryOpts, provider, cancel, serializer)
+	results, err := c.doRetryableQuery(ctx, queryOpts, provider, cancel, serializer)
+	if err != nil {
+		return nil, withQueryStatement(err, stmtStr)
+	}
+
+	results.statement = stmtStr
+	return results, nil
+}
+
+// checkEnhancedPreparedStatements enables enhanced prepared statements once the cluster supports them, statements
+// prepared before then are discarded as they were prepared the old way.
+func (c *Cluster) checkEnhancedPreparedStatements(provider httpProvider) {
+	if capabilitySupporter, ok := provider.(clusterCapabilityProvider); ok {
+		if !c.supportsEnhancedPreparedStatements() &&
+			capabilitySupporter.SupportsClusterCapability(gocbcore.ClusterCapabilityEnhancedPreparedStatements) {
+			c.setSupportsEnhancedPreparedStatements(true)
+			c.queryCache.clear()
+		}
+	}
 }
 
 func (c *Cluster) prepareEnhancedN1qlQuery(ctx context.Context, opts map[string]interface{},
@@ -517,6 +557,12 @@ func (c *Cluster) doRetryableQuery(ctx context.Context, queryOpts map[string]int
 			break
 		}
 
+		// Retrying a prepared statement which is no longer valid would fail in the same way, the caller prepares
+		// the statement again instead.
+		if _, ok := queryOpts["prepared"]; ok && IsPreparedStatementFailure(err) {
+			break
+		}
+
 		if enhancedStatements {
 			qErr, ok := err.(QueryError)
 			if ok {
@@ -566,6 +612,10 @@ func (c *Cluster) executeN1qlQuery(ctx context.Context, opts map[string]interfac
 		if ctx.Err() == context.DeadlineExceeded {
 			return nil, timeoutError{}
 		}
+		if ctx.Err() == context.Canceled {
+			// The request may have reached the server, which would carry on executing it.
+			go c.cancelN1qlRequest(provider, req.Endpoint, "", generatedClientContextID(opts))
+		}
 		return nil, errors.Wrap(err, "could not complete query http request")
 	}
 
@@ -585,6 +635,7 @@ func (c *Cluster) executeN1qlQuery(ctx context.Context, opts map[string]interfac
 		serializer:         serializer,
 		enhancedStatements: c.supportsEnhancedPreparedStatements(),
 	}
+	queryResults.statement, _ = opts["statement"].(string)
 
 	streamResult, err := newStreamingResults(resp.Body, queryResults.readAttribute)
 	if err != nil {
@@ -597,6 +648,9 @@ func (c *Cluster) executeN1qlQuery(ctx context.Context, opts map[string]interfac
 		if bodyErr != nil {
 			logDebugf("Failed to close socket (%s)", bodyErr.Error())
 		}
+		if ctx.Err() == context.Canceled {
+			go c.cancelN1qlRequest(provider, resp.Endpoint, queryResults.metadata.requestID, generatedClientContextID(opts))
+		}
 		return nil, err
 	}
 
@@ -605,6 +659,12 @@ func (c *Cluster) executeN1qlQuery(ctx context.Context, opts map[string]interfac
 	if streamResult.HasRows() {
 		queryResults.cancel = cancel
 		queryResults.ctx = ctx
+
+		requestID := queryResults.metadata.requestID
+		clientContextID := generatedClientContextID(opts)
+		queryResults.canceller = newRequestCanceller(ctx, func() {
+			c.cancelN1qlRequest(provider, resp.Endpoint, requestID, clientContextID)
+		})
 	} else {
 		bodyErr := streamResult.Close()
 		if bodyErr != nil {
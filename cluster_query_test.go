@@ -303,7 +303,7 @@ func testPreparedQuery(t *testing.T) {
 		t.Fatalf("Result should have had non empty SourceEndpoint")
 	}
 
-	if globalCluster.queryCache[query] == nil {
+	if globalCluster.queryCache.testEntries()[query] == nil {
 		t.Fatalf("Query should have been in query cache after prepared statement execution")
 	}
 
@@ -340,7 +340,7 @@ func testPreparedQuery(t *testing.T) {
 		t.Fatalf("Result should have had non empty SourceEndpoint")
 	}
 
-	if globalCluster.queryCache[query] == nil {
+	if globalCluster.queryCache.testEntries()[query] == nil {
 		t.Fatalf("Query should have been in query cache after prepared statement execution")
 	}
 }
@@ -684,6 +684,83 @@ func TestQueryError(t *testing.T) {
 	}
 }
 
+func TestQueryErrorEntries(t *testing.T) {
+	statement := "UPDATE default SET a = 1 WHERE b = 2"
+	timeout := 60 * time.Second
+
+	var requests int
+	respond := func(body string) {
+		doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			requests++
+
+			return &gocbcore.HttpResponse{
+				Endpoint:   "http://localhost:8093",
+				StatusCode: 409,
+				Body:       &testReadCloser{bytes.NewBufferString(body), nil},
+			}, nil
+		}
+
+		cluster := testGetClusterForHTTP(&mockHTTPProvider{doFn: doHTTP}, timeout, 0, 0)
+		cluster.sb.N1qlRetryBehavior = StandardDelayRetryBehavior(3, 1, time.Millisecond, LinearDelayFunction)
+
+		_, err := cluster.Query(statement, &QueryOptions{ClientContextID: "ctx"})
+		if err == nil {
+			t.Fatalf("Expected query to error")
+		}
+
+		queryErr, ok := err.(QueryError)
+		if !ok {
+			t.Fatalf("Expected error to be QueryError but was %s", reflect.TypeOf(err).String())
+		}
+
+		if queryErr.Statement() != statement || queryErr.ContextID() != "ctx" || queryErr.HTTPStatus() != 409 ||
+			queryErr.Endpoint() != "localhost:8093" {
+			t.Fatalf("Expected error to contain the server context but was %s %s %d %s", queryErr.Statement(),
+				queryErr.ContextID(), queryErr.HTTPStatus(), queryErr.Endpoint())
+		}
+	}
+
+	respond(`{"clientContextID":"ctx","errors":[{"code":12009,"msg":"DML Error, possible causes include CAS mismatch",` +
+		`"reason":{"caller":"couchbase:2098","code":12033}},{"code":5010,"msg":"other"}],"status":"errors"}`)
+	if requests != 1 {
+		t.Fatalf("Expected CAS mismatch to not be retried but was sent %d times", requests)
+	}
+
+	requests = 0
+	respond(`{"clientContextID":"ctx","errors":[{"code":1234,"msg":"try again","retry":true}],"status":"errors"}`)
+	if requests != 3 {
+		t.Fatalf("Expected errors which the server marked as retryable to be retried but was sent %d times", requests)
+	}
+}
+
+func TestQueryErrorMultipleEntries(t *testing.T) {
+	body := `{"clientContextID":"ctx","errors":[{"code":12009,"msg":"DML Error, possible causes include CAS mismatch",` +
+		`"reason":{"code":12033}},{"code":5010,"msg":"other"}],"status":"errors"}`
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBufferString(body), nil},
+		}, nil
+	}
+
+	cluster := testGetClusterForHTTP(&mockHTTPProvider{doFn: doHTTP}, 60*time.Second, 0, 0)
+	_, err := cluster.Query("UPDATE default SET a = 1", nil)
+	if !IsDMLCasMismatch(err) {
+		t.Fatalf("Expected error to be a CAS mismatch but was %v", err)
+	}
+
+	entries := err.(QueryError).Errors()
+	if len(entries) != 2 || entries[1].Code != 5010 || entries[0].Reason["code"] != float64(12033) {
+		t.Fatalf("Expected every error entry to be exposed but was %+v", entries)
+	}
+
+	expected := "[12009] DML Error, possible causes include CAS mismatch, [5010] other"
+	if err.Error() != expected {
+		t.Fatalf("Expected error Error() to be %s but was %s", expected, err.Error())
+	}
+}
+
 func TestQueryServiceNotFound(t *testing.T) {
 	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
 		return nil, gocbcore.ErrNoN1qlService
@@ -1110,7 +1187,7 @@ func TestBasicEnhancedPreparedQuery(t *testing.T) {
 	cluster := testGetClusterForHTTP(provider, timeout, 0, 0)
 	cluster.sb.N1qlRetryBehavior = StandardDelayRetryBehavior(3, 1, 100*time.Millisecond, LinearDelayFunction)
 
-	cluster.queryCache = map[string]*n1qlCache{
+	cluster.queryCache = testNewPreparedCache(map[string]*n1qlCache{
 		"fake": {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
@@ -1119,7 +1196,7 @@ func TestBasicEnhancedPreparedQuery(t *testing.T) {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
 		},
-	}
+	})
 
 	_, err = cluster.Query(statement, &QueryOptions{Prepared: true})
 	if err != nil {
@@ -1130,11 +1207,11 @@ func TestBasicEnhancedPreparedQuery(t *testing.T) {
 		t.Fatalf("Expected query to be run 1 time but was run %d times", retries)
 	}
 
-	if len(cluster.queryCache) != 1 {
-		t.Fatalf("Query cache should have contained 1 item but was %v", cluster.queryCache)
+	if len(cluster.queryCache.testEntries()) != 1 {
+		t.Fatalf("Query cache should have contained 1 item but was %v", cluster.queryCache.testEntries())
 	}
 
-	cache, ok := cluster.queryCache["select `beer-sample`.* from `beer-sample` WHERE `type` = ? ORDER BY brewery_id, name"]
+	cache, ok := cluster.queryCache.testEntries()["select `beer-sample`.* from `beer-sample` WHERE `type` = ? ORDER BY brewery_id, name"]
 	if !ok {
 		t.Fatal("Expected query cache to contain query")
 	}
@@ -1181,7 +1258,7 @@ func TestBasicEnhancedPreparedQueryAlreadySupported(t *testing.T) {
 	cluster.sb.N1qlRetryBehavior = StandardDelayRetryBehavior(3, 1, 100*time.Millisecond, LinearDelayFunction)
 	cluster.supportsEnhancedStatements = 1
 
-	cluster.queryCache = map[string]*n1qlCache{
+	cluster.queryCache = testNewPreparedCache(map[string]*n1qlCache{
 		"fake": {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
@@ -1190,7 +1267,7 @@ func TestBasicEnhancedPreparedQueryAlreadySupported(t *testing.T) {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
 		},
-	}
+	})
 
 	_, err = cluster.Query(statement, &QueryOptions{Prepared: true})
 	if err != nil {
@@ -1201,8 +1278,8 @@ func TestBasicEnhancedPreparedQueryAlreadySupported(t *testing.T) {
 		t.Fatalf("Expected query to be run 1 time but was run %d times", retries)
 	}
 
-	if len(cluster.queryCache) != 3 {
-		t.Fatalf("Query cache should have contained 3 items but was %v", cluster.queryCache)
+	if len(cluster.queryCache.testEntries()) != 3 {
+		t.Fatalf("Query cache should have contained 3 items but was %v", cluster.queryCache.testEntries())
 	}
 }
 
@@ -1239,7 +1316,7 @@ func TestBasicEnhancedPreparedQueryAlreadyCached(t *testing.T) {
 	cluster.sb.N1qlRetryBehavior = StandardDelayRetryBehavior(3, 1, 100*time.Millisecond, LinearDelayFunction)
 	cluster.supportsEnhancedStatements = 1
 
-	cluster.queryCache = map[string]*n1qlCache{
+	cluster.queryCache = testNewPreparedCache(map[string]*n1qlCache{
 		"fake": {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
@@ -1251,7 +1328,7 @@ func TestBasicEnhancedPreparedQueryAlreadyCached(t *testing.T) {
 		"select `beer-sample`.* from `beer-sample` WHERE `type` = ? ORDER BY brewery_id, name": {
 			name: "[127.0.0.1:8091]32f2405d-5715-5915-b2b2-d2c557da4996",
 		},
-	}
+	})
 
 	_, err = cluster.Query(statement, &QueryOptions{Prepared: true})
 	if err != nil {
@@ -1262,8 +1339,8 @@ func TestBasicEnhancedPreparedQueryAlreadyCached(t *testing.T) {
 		t.Fatalf("Expected query to be run 1 times but ws run %d times", retries)
 	}
 
-	if len(cluster.queryCache) != 3 {
-		t.Fatalf("Query cache should have contained 3 items but was %v", cluster.queryCache)
+	if len(cluster.queryCache.testEntries()) != 3 {
+		t.Fatalf("Query cache should have contained 3 items but was %v", cluster.queryCache.testEntries())
 	}
 }
 
@@ -1305,7 +1382,7 @@ func TestBasicRetriesEnhancedPreparedNoRetry(t *testing.T) {
 	cluster := testGetClusterForHTTP(provider, timeout, 0, 0)
 	cluster.sb.N1qlRetryBehavior = StandardDelayRetryBehavior(3, 1, 100*time.Millisecond, LinearDelayFunction)
 
-	cluster.queryCache = map[string]*n1qlCache{
+	cluster.queryCache = testNewPreparedCache(map[string]*n1qlCache{
 		"fake": {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
@@ -1314,7 +1391,7 @@ func TestBasicRetriesEnhancedPreparedNoRetry(t *testing.T) {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
 		},
-	}
+	})
 
 	_, err = cluster.Query(statement, &QueryOptions{Prepared: true})
 	if err == nil {
@@ -1325,8 +1402,8 @@ func TestBasicRetriesEnhancedPreparedNoRetry(t *testing.T) {
 		t.Fatalf("Expected query to be retried 1 time but was retried %d times", retries)
 	}
 
-	if len(cluster.queryCache) != 0 {
-		t.Fatalf("Query cache should have been empty but was %v", cluster.queryCache)
+	if len(cluster.queryCache.testEntries()) != 0 {
+		t.Fatalf("Query cache should have been empty but was %v", cluster.queryCache.testEntries())
 	}
 }
 
@@ -1368,7 +1445,7 @@ func TestBasicRetriesEnhancedPreparedRetry(t *testing.T) {
 	cluster := testGetClusterForHTTP(provider, timeout, 0, 0)
 	cluster.sb.N1qlRetryBehavior = StandardDelayRetryBehavior(3, 1, 100*time.Millisecond, LinearDelayFunction)
 
-	cluster.queryCache = map[string]*n1qlCache{
+	cluster.queryCache = testNewPreparedCache(map[string]*n1qlCache{
 		"fake": {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
@@ -1377,7 +1454,7 @@ func TestBasicRetriesEnhancedPreparedRetry(t *testing.T) {
 			name:        "mefake",
 			encodedPlan: "somethingencoded",
 		},
-	}
+	})
 
 	_, err = cluster.Query(statement, &QueryOptions{Prepared: true})
 	if err == nil {
@@ -1388,8 +1465,8 @@ func TestBasicRetriesEnhancedPreparedRetry(t *testing.T) {
 		t.Fatalf("Expected query to be retried 3 time but ws retried %d times", retries)
 	}
 
-	if len(cluster.queryCache) != 0 {
-		t.Fatalf("Query cache should have been empty but was %v", cluster.queryCache)
+	if len(cluster.queryCache.testEntries()) != 0 {
+		t.Fatalf("Query cache should have been empty but was %v", cluster.queryCache.testEntries())
 	}
 }
 
@@ -1405,6 +1482,7 @@ func testGetClusterForHTTP(provider *mockHTTPProvider, n1qlTimeout, analyticsTim
 	clients["mock-false"] = cli
 	c := &Cluster{
 		connections: clients,
+		queryCache:  newPreparedCache(0, 0),
 	}
 	c.sb.QueryTimeout = n1qlTimeout
 	c.sb.AnalyticsTimeout = analyticsTimeout
@@ -0,0 +1,204 @@
+package gocb
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v8"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// requestCanceller cancels a request on the server if it is abandoned before its response has been read in full,
+// either because the context of the request is canceled or because its results are closed early. Closing the HTTP
+// stream alone does not stop the server from executing the request.
+type requestCanceller struct {
+	once   sync.Once
+	done   chan struct{}
+	cancel func()
+}
+
+func newRequestCanceller(ctx context.Context, cancel func()) *requestCanceller {
+	rc := &requestCanceller{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			// The server is sent a timeout no later than the deadline of the context, so it stops the request itself
+			// when the deadline passes.
+			if ctx.Err() == context.Canceled {
+				rc.abandon()
+			}
+		case <-rc.done:
+		}
+	}()
+
+	return rc
+}
+
+func (rc *requestCanceller) abandon() {
+	rc.once.Do(func() {
+		go rc.cancel()
+	})
+}
+
+// finish stops watching the request, completed is whether its response was read in full.
+func (rc *requestCanceller) finish(completed bool) {
+	if completed {
+		rc.once.Do(func() {})
+	} else {
+		rc.abandon()
+	}
+	close(rc.done)
+}
+
+// generatedClientContextID returns the client context id of a request when it was generated by the SDK. Ids set by
+// the caller are not guaranteed to be unique, so they are never used to cancel requests.
+func generatedClientContextID(opts map[string]interface{}) string {
+	id, ok := opts["client_context_id"].(uuid.UUID)
+	if !ok {
+		return ""
+	}
+
+	return id.String()
+}
+
+// cancelN1qlRequest cancels a request which may still be executing on the query service. The request is identified
+// by its request id when that has been received, otherwise by its client context id if that was generated by the
+// SDK. Requests which cannot be identified are left to time out on the server.
+func (c *Cluster) cancelN1qlRequest(provider httpProvider, endpoint, requestID, clientContextID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.sb.QueryTimeout)
+	defer cancel()
+
+	var err error
+	if requestID != "" {
+		err = deleteN1qlActiveRequest(ctx, provider, endpoint, requestID)
+	} else if clientContextID != "" {
+		err = c.deleteN1qlActiveRequests(ctx, provider, "clientContextID", clientContextID)
+	}
+	if err != nil {
+		logDebugf("Failed to cancel query request %s/%s (%s)", requestID, clientContextID, err)
+	}
+}
+
+// deleteN1qlActiveRequest cancels requestID on the query node at endpoint, active requests are held by the node
+// which is executing them.
+func deleteN1qlActiveRequest(ctx context.Context, provider httpProvider, endpoint, requestID string) error {
+	req := &gocbcore.HttpRequest{
+		Service:  gocbcore.N1qlService,
+		Path:     "/admin/active_requests/" + url.PathEscape(requestID),
+		Method:   "DELETE",
+		Context:  ctx,
+		Endpoint: endpoint,
+	}
+
+	resp, err := provider.DoHttpRequest(req)
+	if err != nil {
+		return errors.Wrap(err, "could not complete query cancellation http request")
+	}
+
+	bodyErr := resp.Body.Close()
+	if bodyErr != nil {
+		logDebugf("Failed to close response body, %s", bodyErr.Error())
+	}
+
+	// The request may have completed before it could be canceled.
+	if resp.StatusCode != 200 && resp.StatusCode != 404 {
+		return errors.Errorf("query service returned status %d when canceling request", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// deleteN1qlActiveRequests cancels the requests on every query node whose field matches value.
+func (c *Cluster) deleteN1qlActiveRequests(ctx context.Context, provider httpProvider, field, value string) error {
+	results, err := c.query(ctx, "DELETE FROM system:active_requests WHERE "+field+" = $1", "", &QueryOptions{
+		PositionalParameters: []interface{}{value},
+	}, provider)
+	if err != nil {
+		return err
+	}
+
+	for results.NextBytes() != nil {
+	}
+
+	return results.Close()
+}
+
+// cancelAnalyticsRequest cancels a request which may still be executing on the analytics service. Analytics requests
+// can only be identified by their client context id, so only requests whose id was generated by the SDK are canceled.
+func (c *Cluster) cancelAnalyticsRequest(provider httpProvider, endpoint, clientContextID string) {
+	if clientContextID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.sb.AnalyticsTimeout)
+	defer cancel()
+
+	req := &gocbcore.HttpRequest{
+		Service:     gocbcore.CbasService,
+		Path:        "/analytics/admin/active_requests",
+		Method:      "DELETE",
+		Context:     ctx,
+		Endpoint:    endpoint,
+		Body:        []byte(url.Values{"client_context_id": {clientContextID}}.Encode()),
+		ContentType: "application/x-www-form-urlencoded",
+	}
+
+	resp, err := provider.DoHttpRequest(req)
+	if err != nil {
+		logDebugf("Failed to cancel analytics request %s (%s)", clientContextID, err)
+		return
+	}
+
+	bodyErr := resp.Body.Close()
+	if bodyErr != nil {
+		logDebugf("Failed to close response body, %s", bodyErr.Error())
+	}
+
+	if resp.StatusCode != 200 && resp.StatusCode != 404 {
+		logDebugf("Failed to cancel analytics request %s, analytics service returned status %d", clientContextID,
+			resp.StatusCode)
+	}
+}
+
+// CancelQueryOptions is the set of options available to Cluster.CancelQuery.
+type CancelQueryOptions struct {
+	Timeout time.Duration
+	Context context.Context
+}
+
+// CancelQuery cancels the N1QL request with requestID on whichever query node is executing it. Request ids are
+// available from QueryResultsMetadata.RequestID and from the system:active_requests keyspace. It is not an error
+// for the request to have already completed.
+func (c *Cluster) CancelQuery(requestID string, opts *CancelQueryOptions) error {
+	if requestID == "" {
+		return invalidArgumentsError{message: "request id cannot be empty"}
+	}
+
+	if opts == nil {
+		opts = &CancelQueryOptions{}
+	}
+
+	provider, err := c.getHTTPProvider()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := contextFromMaybeTimeout(opts.Context, opts.Timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	err = c.deleteN1qlActiveRequests(ctx, provider, "requestId", requestID)
+	if err != nil {
+		return errors.Wrapf(err, "could not cancel query request %s", requestID)
+	}
+
+	return nil
+}
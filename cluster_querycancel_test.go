@@ -0,0 +1,237 @@
+package gocb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+const testStreamingQueryResponse = `{"requestID":"c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1","clientContextID":"cancel-test",` +
+	`"results":[{"id":1},{"id":2},{"id":3}],"status":"success"}`
+
+func testGetCancellingHTTPProvider(respBody string) (*mockHTTPProvider, chan *gocbcore.HttpRequest) {
+	cancelled := make(chan *gocbcore.HttpRequest, 1)
+	provider := &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			return &gocbcore.HttpResponse{
+				Endpoint:   "http://localhost:8093",
+				StatusCode: 200,
+				Body:       &testReadCloser{bytes.NewBufferString(respBody), nil},
+			}, nil
+		},
+		cancelFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			cancelled <- req
+			return &gocbcore.HttpResponse{
+				Endpoint:   req.Endpoint,
+				StatusCode: 200,
+				Body:       &testReadCloser{bytes.NewBuffer(nil), nil},
+			}, nil
+		},
+	}
+
+	return provider, cancelled
+}
+
+func testWaitForCancellation(t *testing.T, cancelled chan *gocbcore.HttpRequest) *gocbcore.HttpRequest {
+	select {
+	case req := <-cancelled:
+		return req
+	case <-time.After(time.Second):
+		t.Fatalf("Expected request to be cancelled on the server")
+		return nil
+	}
+}
+
+func TestQueryCancelledOnEarlyClose(t *testing.T) {
+	provider, cancelled := testGetCancellingHTTPProvider(testStreamingQueryResponse)
+	cluster := testGetClusterForHTTP(provider, time.Second, 0, 0)
+
+	results, err := cluster.Query("SELECT * FROM test", nil)
+	if err != nil {
+		t.Fatalf("Expected query to succeed but was %v", err)
+	}
+
+	var row interface{}
+	if !results.Next(&row) {
+		t.Fatalf("Expected a row but was %v", results.Close())
+	}
+
+	err = results.Close()
+	if err != nil {
+		t.Fatalf("Expected close to succeed but was %v", err)
+	}
+
+	req := testWaitForCancellation(t, cancelled)
+	if req.Service != gocbcore.N1qlService || req.Endpoint != "http://localhost:8093" ||
+		req.Path != "/admin/active_requests/c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1" {
+		t.Fatalf("Expected request to be cancelled on the node executing it but was %+v", req)
+	}
+}
+
+func TestQueryCancelledOnContextCancel(t *testing.T) {
+	provider, cancelled := testGetCancellingHTTPProvider(testStreamingQueryResponse)
+	cluster := testGetClusterForHTTP(provider, time.Second, 0, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := cluster.Query("SELECT * FROM test", &QueryOptions{Context: ctx})
+	if err != nil {
+		t.Fatalf("Expected query to succeed but was %v", err)
+	}
+
+	cancel()
+
+	req := testWaitForCancellation(t, cancelled)
+	if req.Path != "/admin/active_requests/c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1" {
+		t.Fatalf("Expected request to be cancelled but was %+v", req)
+	}
+
+	results.Close()
+}
+
+func TestQueryNotCancelledWhenComplete(t *testing.T) {
+	provider, cancelled := testGetCancellingHTTPProvider(testStreamingQueryResponse)
+	cluster := testGetClusterForHTTP(provider, time.Second, 0, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := cluster.Query("SELECT * FROM test", &QueryOptions{Context: ctx})
+	if err != nil {
+		t.Fatalf("Expected query to succeed but was %v", err)
+	}
+
+	var row interface{}
+	for results.Next(&row) {
+	}
+
+	err = results.Close()
+	if err != nil {
+		t.Fatalf("Expected close to succeed but was %v", err)
+	}
+
+	cancel()
+
+	select {
+	case req := <-cancelled:
+		t.Fatalf("Expected completed request not to be cancelled but was %+v", req)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func testCloseAnalyticsQueryEarly(t *testing.T, opts *AnalyticsQueryOptions) (string, chan *gocbcore.HttpRequest) {
+	provider, cancelled := testGetCancellingHTTPProvider(testStreamingQueryResponse)
+	doFn := provider.doFn
+	var executed map[string]interface{}
+	provider.doFn = func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		err := json.Unmarshal(req.Body, &executed)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+		return doFn(req)
+	}
+	cluster := testGetClusterForHTTP(provider, 0, time.Second, 0)
+
+	results, err := cluster.AnalyticsQuery("SELECT * FROM test", opts)
+	if err != nil {
+		t.Fatalf("Expected query to succeed but was %v", err)
+	}
+
+	var row interface{}
+	if !results.Next(&row) {
+		t.Fatalf("Expected a row but was %v", results.Close())
+	}
+
+	err = results.Close()
+	if err != nil {
+		t.Fatalf("Expected close to succeed but was %v", err)
+	}
+
+	clientContextID, _ := executed["client_context_id"].(string)
+	return clientContextID, cancelled
+}
+
+func TestAnalyticsQueryCancelledOnEarlyClose(t *testing.T) {
+	clientContextID, cancelled := testCloseAnalyticsQueryEarly(t, nil)
+	if clientContextID == "" {
+		t.Fatalf("Expected request to be sent with a generated client context id")
+	}
+
+	req := testWaitForCancellation(t, cancelled)
+	if req.Service != gocbcore.CbasService || req.Path != "/analytics/admin/active_requests" {
+		t.Fatalf("Expected analytics request to be cancelled but was %+v", req)
+	}
+
+	form, err := url.ParseQuery(string(req.Body))
+	if err != nil || form.Get("client_context_id") != clientContextID {
+		t.Fatalf("Expected request to be identified by its client context id %s but was %s", clientContextID, req.Body)
+	}
+}
+
+func TestAnalyticsQueryNotCancelledByCallerClientContextID(t *testing.T) {
+	_, cancelled := testCloseAnalyticsQueryEarly(t, &AnalyticsQueryOptions{ClientContextID: "cancel-test"})
+
+	select {
+	case req := <-cancelled:
+		t.Fatalf("Expected request with a caller set client context id not to be cancelled but was %+v", req)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCancelQuery(t *testing.T) {
+	var executed map[string]interface{}
+	provider := &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			err := json.Unmarshal(req.Body, &executed)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal request body %v", err)
+			}
+
+			return &gocbcore.HttpResponse{
+				Endpoint:   "http://localhost:8093",
+				StatusCode: 200,
+				Body:       &testReadCloser{bytes.NewBufferString(`{"results":[],"status":"success"}`), nil},
+			}, nil
+		},
+	}
+	cluster := testGetClusterForHTTP(provider, time.Second, 0, 0)
+
+	err := cluster.CancelQuery("c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1", nil)
+	if err != nil {
+		t.Fatalf("Expected cancel to succeed but was %v", err)
+	}
+
+	if executed["statement"] != "DELETE FROM system:active_requests WHERE requestId = $1" {
+		t.Fatalf("Expected active request to be deleted but statement was %v", executed["statement"])
+	}
+
+	args, _ := executed["args"].([]interface{})
+	if len(args) != 1 || args[0] != "c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1" {
+		t.Fatalf("Expected request id to be bound but args were %v", executed["args"])
+	}
+
+	err = cluster.CancelQuery("", nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected empty request id to be invalid but was %v", err)
+	}
+}
+
+func TestGeneratedClientContextID(t *testing.T) {
+	opts, err := (&QueryOptions{}).toMap("SELECT 1")
+	if err != nil {
+		t.Fatalf("Expected options to be valid but was %v", err)
+	}
+	if generatedClientContextID(opts) == "" {
+		t.Fatalf("Expected generated client context id to be usable for cancellation")
+	}
+
+	opts, err = (&QueryOptions{ClientContextID: "caller-id"}).toMap("SELECT 1")
+	if err != nil {
+		t.Fatalf("Expected options to be valid but was %v", err)
+	}
+	if id := generatedClientContextID(opts); id != "" {
+		t.Fatalf("Expected caller set client context id not to be used for cancellation but was %s", id)
+	}
+}
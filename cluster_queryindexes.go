@@ -4,6 +4,8 @@ import (
 	"context"
 	"strings"
 	"time"
+
+	"github.com/couchbase/gocb/v2/n1ql"
 )
 
 // QueryIndexManager provides methods for performing Couchbase N1ql index management.
@@ -39,16 +41,16 @@ func (qm *QueryIndexManager) createIndex(bucketName, indexName string, fields []
 		qs += "CREATE INDEX"
 	}
 	if indexName != "" {
-		qs += " `" + indexName + "`"
+		qs += " " + n1ql.EscapeIdentifier(indexName)
 	}
-	qs += " ON `" + bucketName + "`"
+	qs += " ON " + n1ql.EscapeIdentifier(bucketName)
 	if len(fields) > 0 {
 		qs += " ("
 		for i := 0; i < len(fields); i++ {
 			if i > 0 {
 				qs += ", "
 			}
-			qs += "`" + fields[i] + "`"
+			qs += n1ql.EscapeIdentifier(fields[i])
 		}
 		qs += ")"
 	}
@@ -151,9 +153,9 @@ func (qm *QueryIndexManager) dropIndex(bucketName, indexName string, opts dropQu
 	var qs string
 
 	if indexName == "" {
-		qs += "DROP PRIMARY INDEX ON `" + bucketName + "`"
+		qs += "DROP PRIMARY INDEX ON " + n1ql.EscapeIdentifier(bucketName)
 	} else {
-		qs += "DROP INDEX `" + bucketName + "`.`" + indexName + "`"
+		qs += "DROP INDEX " + n1ql.EscapeKeyspace(bucketName, indexName)
 	}
 
 	rows, err := qm.executeQuery(qs, &QueryOptions{
@@ -311,12 +313,12 @@ func (qm *QueryIndexManager) BuildDeferredIndexes(bucketName string, opts *Build
 	}
 
 	var qs string
-	qs += "BUILD INDEX ON `" + bucketName + "`("
+	qs += "BUILD INDEX ON " + n1ql.EscapeIdentifier(bucketName) + "("
 	for i := 0; i < len(deferredList); i++ {
 		if i > 0 {
 			qs += ", "
 		}
-		qs += "`" + deferredList[i] + "`"
+		qs += n1ql.EscapeIdentifier(deferredList[i])
 	}
 	qs += ")"
 
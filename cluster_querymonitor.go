@@ -0,0 +1,534 @@
+package gocb
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/couchbase/gocbcore/v8"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// QueryMonitor provides access to the requests which the query service is executing and has recently completed,
+// along with the vitals of the service.
+// Volatile: This API is subject to change at any time.
+type QueryMonitor struct {
+	cluster *Cluster
+}
+
+// QueryMonitor returns a monitor for the query service of the cluster.
+func (c *Cluster) QueryMonitor() *QueryMonitor {
+	return &QueryMonitor{cluster: c}
+}
+
+// QueryRequest is a request which is executing on, or has been completed by, the query service.
+type QueryRequest struct {
+	RequestID       string
+	ClientContextID string
+	Statement       string
+	PreparedName    string
+	// State is the state of the request, such as running, completed, cancelled or timeout.
+	State string
+	// Users are the users which the request was executed as.
+	Users      string
+	Node       string
+	RemoteAddr string
+	UserAgent  string
+
+	RequestTime time.Time
+	ElapsedTime time.Duration
+	ServiceTime time.Duration
+	ResultCount uint64
+	ResultSize  uint64
+	ErrorCount  uint64
+
+	// PhaseTimes, PhaseCounts and PhaseOperators are only set for requests executed with profiling enabled.
+	PhaseTimes     map[string]time.Duration
+	PhaseCounts    map[string]uint64
+	PhaseOperators map[string]uint64
+}
+
+type jsonQueryRequest struct {
+	RequestID       string            `json:"requestId"`
+	ClientContextID string            `json:"clientContextID"`
+	Statement       string            `json:"statement"`
+	PreparedName    string            `json:"preparedName"`
+	State           string            `json:"state"`
+	Users           string            `json:"users"`
+	Node            string            `json:"node"`
+	RemoteAddr      string            `json:"remoteAddr"`
+	UserAgent       string            `json:"userAgent"`
+	RequestTime     string            `json:"requestTime"`
+	ElapsedTime     string            `json:"elapsedTime"`
+	ServiceTime     string            `json:"serviceTime"`
+	ResultCount     uint64            `json:"resultCount"`
+	ResultSize      uint64            `json:"resultSize"`
+	ErrorCount      uint64            `json:"errorCount"`
+	PhaseTimes      map[string]string `json:"phaseTimes"`
+	PhaseCounts     map[string]uint64 `json:"phaseCounts"`
+	PhaseOperators  map[string]uint64 `json:"phaseOperators"`
+}
+
+// queryRequestTimeLayouts are the formats which the query service has used for request times.
+var queryRequestTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999 -0700",
+	time.RFC3339Nano,
+}
+
+func (r jsonQueryRequest) toQueryRequest() QueryRequest {
+	req := QueryRequest{
+		RequestID:       r.RequestID,
+		ClientContextID: r.ClientContextID,
+		Statement:       r.Statement,
+		PreparedName:    r.PreparedName,
+		State:           r.State,
+		Users:           r.Users,
+		Node:            r.Node,
+		RemoteAddr:      r.RemoteAddr,
+		UserAgent:       r.UserAgent,
+		ElapsedTime:     parseQueryDuration(r.ElapsedTime),
+		ServiceTime:     parseQueryDuration(r.ServiceTime),
+		ResultCount:     r.ResultCount,
+		ResultSize:      r.ResultSize,
+		ErrorCount:      r.ErrorCount,
+		PhaseCounts:     r.PhaseCounts,
+		PhaseOperators:  r.PhaseOperators,
+	}
+
+	if r.RequestTime != "" {
+		for _, layout := range queryRequestTimeLayouts {
+			t, err := time.Parse(layout, r.RequestTime)
+			if err == nil {
+				req.RequestTime = t
+				break
+			}
+		}
+		if req.RequestTime.IsZero() {
+			logDebugf("Failed to parse request time %s", r.RequestTime)
+		}
+	}
+
+	if r.PhaseTimes != nil {
+		req.PhaseTimes = make(map[string]time.Duration, len(r.PhaseTimes))
+		for phase, t := range r.PhaseTimes {
+			req.PhaseTimes[phase] = parseQueryDuration(t)
+		}
+	}
+
+	return req
+}
+
+func (qm *QueryMonitor) requests(ctx context.Context, statement string, params []interface{}) ([]QueryRequest, error) {
+	// The request which reads system:active_requests is itself active, so it is identified in order to skip it.
+	contextID := "gocb-monitor-" + uuid.New().String()
+	results, err := qm.cluster.Query(statement, &QueryOptions{
+		Context:              ctx,
+		PositionalParameters: params,
+		ClientContextID:      contextID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []QueryRequest
+	var row jsonQueryRequest
+	for results.Next(&row) {
+		if row.ClientContextID != contextID {
+			requests = append(requests, row.toQueryRequest())
+		}
+		row = jsonQueryRequest{}
+	}
+	if err := results.Close(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// ActiveQueryRequestsOptions is the set of options available to the query monitor ActiveRequests operation.
+type ActiveQueryRequestsOptions struct {
+	Timeout time.Duration
+	Context context.Context
+}
+
+// ActiveRequests returns the requests which are executing on every query node.
+func (qm *QueryMonitor) ActiveRequests(opts *ActiveQueryRequestsOptions) ([]QueryRequest, error) {
+	if opts == nil {
+		opts = &ActiveQueryRequestsOptions{}
+	}
+
+	ctx, cancel := contextFromMaybeTimeout(opts.Context, opts.Timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	return qm.requests(ctx, "SELECT r.* FROM system:active_requests AS r", nil)
+}
+
+// QueryRequestFilter restricts the completed requests returned by the query monitor, fields which are not set
+// do not restrict the requests.
+type QueryRequestFilter struct {
+	// MinElapsedTime only includes requests which took at least this long, such as to find slow queries.
+	MinElapsedTime  time.Duration
+	State           string
+	Users           string
+	ClientContextID string
+	// StatementContains only includes requests whose statement contains this text.
+	StatementContains string
+	// Limit restricts the number of requests returned, the most recent requests are returned first.
+	Limit int
+}
+
+func (f QueryRequestFilter) where() (string, []interface{}) {
+	var conds []string
+	var params []interface{}
+	add := func(cond string, param interface{}) {
+		conds = append(conds, cond)
+		params = append(params, param)
+	}
+
+	if f.MinElapsedTime > 0 {
+		add("STR_TO_DURATION(r.elapsedTime) >= ?", int64(f.MinElapsedTime))
+	}
+	if f.State != "" {
+		add("r.state = ?", f.State)
+	}
+	if f.Users != "" {
+		add("r.users = ?", f.Users)
+	}
+	if f.ClientContextID != "" {
+		add("r.clientContextID = ?", f.ClientContextID)
+	}
+	if f.StatementContains != "" {
+		add("CONTAINS(r.statement, ?)", f.StatementContains)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conds, " AND "), params
+}
+
+// CompletedQueryRequestsOptions is the set of options available to the query monitor CompletedRequests operation.
+type CompletedQueryRequestsOptions struct {
+	Timeout time.Duration
+	Context context.Context
+}
+
+// CompletedRequests returns the requests matching filter which the query nodes have recently completed. Each node
+// only keeps the requests which meet its completed requests threshold, by default those which took over a second.
+func (qm *QueryMonitor) CompletedRequests(filter QueryRequestFilter,
+	opts *CompletedQueryRequestsOptions) ([]QueryRequest, error) {
+	if filter.Limit < 0 || filter.MinElapsedTime < 0 {
+		return nil, invalidArgumentsError{message: "limit and min elapsed time cannot be negative"}
+	}
+
+	if opts == nil {
+		opts = &CompletedQueryRequestsOptions{}
+	}
+
+	ctx, cancel := contextFromMaybeTimeout(opts.Context, opts.Timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	where, params := filter.where()
+	statement := "SELECT r.* FROM system:completed_requests AS r" + where + " ORDER BY r.requestTime DESC"
+	if filter.Limit > 0 {
+		statement += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	return qm.requests(ctx, statement, params)
+}
+
+// Cancel cancels the request with requestID, see Cluster.CancelQuery.
+func (qm *QueryMonitor) Cancel(requestID string, opts *CancelQueryOptions) error {
+	return qm.cluster.CancelQuery(requestID, opts)
+}
+
+// QueryVitals are the vitals of a query node.
+type QueryVitals struct {
+	Node    string
+	Version string
+	Uptime  time.Duration
+	Cores   int
+
+	TotalThreads   int
+	MemoryUsage    uint64
+	MemoryTotal    uint64
+	MemorySystem   uint64
+	CPUUserPercent float64
+	CPUSysPercent  float64
+	GCPauseTime    time.Duration
+	GCPausePercent float64
+
+	ActiveRequests        uint64
+	CompletedRequests     uint64
+	RequestsPerSecond1Min float64
+	RequestsPerSecond5Min float64
+	// PreparedPercent is the percentage of requests which executed prepared statements.
+	PreparedPercent float64
+
+	RequestTimeMean         time.Duration
+	RequestTimeMedian       time.Duration
+	RequestTime80Percentile time.Duration
+	RequestTime95Percentile time.Duration
+	RequestTime99Percentile time.Duration
+}
+
+type jsonQueryVitals struct {
+	Version               string  `json:"version"`
+	Uptime                string  `json:"uptime"`
+	Cores                 int     `json:"cores"`
+	TotalThreads          int     `json:"total.threads"`
+	MemoryUsage           uint64  `json:"memory.usage"`
+	MemoryTotal           uint64  `json:"memory.total"`
+	MemorySystem          uint64  `json:"memory.system"`
+	CPUUserPercent        float64 `json:"cpu.user.percent"`
+	CPUSysPercent         float64 `json:"cpu.sys.percent"`
+	GCPauseTime           string  `json:"gc.pause.time"`
+	GCPausePercent        float64 `json:"gc.pause.percent"`
+	ActiveRequests        uint64  `json:"request.active.count"`
+	CompletedRequests     uint64  `json:"request.completed.count"`
+	RequestsPerSecond1Min float64 `json:"request.per.sec.1min"`
+	RequestsPerSecond5Min float64 `json:"request.per.sec.5min"`
+	PreparedPercent       float64 `json:"request.prepared.percent"`
+	RequestTimeMean       string  `json:"request_time.mean"`
+	RequestTimeMedian     string  `json:"request_time.median"`
+	RequestTime80         string  `json:"request_time.80percentile"`
+	RequestTime95         string  `json:"request_time.95percentile"`
+	RequestTime99         string  `json:"request_time.99percentile"`
+}
+
+// QueryVitalsOptions is the set of options available to the query monitor Vitals operation.
+type QueryVitalsOptions struct {
+	Timeout time.Duration
+	Context context.Context
+}
+
+// Vitals returns the vitals of a query node. Each call may be answered by a different node, see QueryVitals.Node.
+func (qm *QueryMonitor) Vitals(opts *QueryVitalsOptions) (*QueryVitals, error) {
+	if opts == nil {
+		opts = &QueryVitalsOptions{}
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = qm.cluster.sb.QueryTimeout
+	}
+
+	ctx, cancel := contextFromMaybeTimeout(opts.Context, timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	vitals, _, err := qm.vitals(ctx, "")
+	return vitals, err
+}
+
+// vitals fetches the vitals of the query node at endpoint, or of any query node if endpoint is empty. The endpoint
+// which answered is returned so that later calls can be pinned to the same node.
+func (qm *QueryMonitor) vitals(ctx context.Context, endpoint string) (*QueryVitals, string, error) {
+	provider, err := qm.cluster.getHTTPProvider()
+	if err != nil {
+		return nil, "", err
+	}
+
+	req := &gocbcore.HttpRequest{
+		Service:  gocbcore.N1qlService,
+		Path:     "/admin/vitals",
+		Method:   "GET",
+		Context:  ctx,
+		Endpoint: endpoint,
+	}
+
+	resp, err := provider.DoHttpRequest(req)
+	if err != nil {
+		if err == gocbcore.ErrNoN1qlService {
+			return nil, "", serviceNotAvailableError{message: gocbcore.ErrNoN1qlService.Error()}
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, "", timeoutError{}
+		}
+		return nil, "", errors.Wrap(err, "could not complete query vitals http request")
+	}
+
+	defer func() {
+		bodyErr := resp.Body.Close()
+		if bodyErr != nil {
+			logDebugf("Failed to close response body, %s", bodyErr.Error())
+		}
+	}()
+
+	if resp.StatusCode != 200 {
+		return nil, "", errors.Errorf("query service returned status %d for vitals", resp.StatusCode)
+	}
+
+	var vitals jsonQueryVitals
+	err = json.NewDecoder(resp.Body).Decode(&vitals)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "could not decode query vitals")
+	}
+
+	var node string
+	if epInfo, err := url.Parse(resp.Endpoint); err == nil {
+		node = epInfo.Host
+	}
+
+	return &QueryVitals{
+		Node:                    node,
+		Version:                 vitals.Version,
+		Uptime:                  parseQueryDuration(vitals.Uptime),
+		Cores:                   vitals.Cores,
+		TotalThreads:            vitals.TotalThreads,
+		MemoryUsage:             vitals.MemoryUsage,
+		MemoryTotal:             vitals.MemoryTotal,
+		MemorySystem:            vitals.MemorySystem,
+		CPUUserPercent:          vitals.CPUUserPercent,
+		CPUSysPercent:           vitals.CPUSysPercent,
+		GCPauseTime:             parseQueryDuration(vitals.GCPauseTime),
+		GCPausePercent:          vitals.GCPausePercent,
+		ActiveRequests:          vitals.ActiveRequests,
+		CompletedRequests:       vitals.CompletedRequests,
+		RequestsPerSecond1Min:   vitals.RequestsPerSecond1Min,
+		RequestsPerSecond5Min:   vitals.RequestsPerSecond5Min,
+		PreparedPercent:         vitals.PreparedPercent,
+		RequestTimeMean:         parseQueryDuration(vitals.RequestTimeMean),
+		RequestTimeMedian:       parseQueryDuration(vitals.RequestTimeMedian),
+		RequestTime80Percentile: parseQueryDuration(vitals.RequestTime80),
+		RequestTime95Percentile: parseQueryDuration(vitals.RequestTime95),
+		RequestTime99Percentile: parseQueryDuration(vitals.RequestTime99),
+	}, resp.Endpoint, nil
+}
+
+// QueryMonitorSample is a sample taken by QueryMonitor.Watch.
+type QueryMonitorSample struct {
+	Time           time.Time
+	ActiveRequests []QueryRequest
+	Vitals         *QueryVitals
+	err            error
+}
+
+// Err returns the error, if any, which occurred while taking the sample.
+func (s QueryMonitorSample) Err() error {
+	return s.err
+}
+
+// WatchQueryMonitorOptions is the set of options available to the query monitor Watch operation.
+type WatchQueryMonitorOptions struct {
+	// Timeout applies to taking each sample, defaults to the query timeout of the cluster.
+	Timeout time.Duration
+	// Context ends the watch once it is done.
+	Context context.Context
+}
+
+// QueryMonitorWatch is a stream of samples of the active requests and vitals of the query service.
+type QueryMonitorWatch struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	sampleCh chan QueryMonitorSample
+}
+
+// Watch samples the active requests and vitals of the query service every interval, starting immediately. The
+// vitals of every sample are taken from the same query node, see QueryVitals.Node, so that they can be compared
+// between samples. The watch only moves to another node once that node fails to report its vitals. A failure to
+// take a sample is reported through QueryMonitorSample.Err rather than ending the watch. The watch must be closed
+// once finished with.
+func (qm *QueryMonitor) Watch(interval time.Duration, opts *WatchQueryMonitorOptions) (*QueryMonitorWatch, error) {
+	if interval <= 0 {
+		return nil, invalidArgumentsError{message: "watch interval must be greater than zero"}
+	}
+
+	if opts == nil {
+		opts = &WatchQueryMonitorOptions{}
+	}
+
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = qm.cluster.sb.QueryTimeout
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	watch := &QueryMonitorWatch{
+		ctx:      ctx,
+		cancel:   cancel,
+		sampleCh: make(chan QueryMonitorSample),
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var endpoint string
+		for {
+			var sample QueryMonitorSample
+			sample, endpoint = qm.sample(ctx, timeout, endpoint)
+			select {
+			case watch.sampleCh <- sample:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return watch, nil
+}
+
+// sample takes a sample with the vitals of the query node at endpoint, or of any query node if endpoint is empty,
+// returning the endpoint to take the next sample from.
+func (qm *QueryMonitor) sample(ctx context.Context, timeout time.Duration,
+	endpoint string) (QueryMonitorSample, string) {
+	sample := QueryMonitorSample{Time: time.Now()}
+
+	sampleCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sample.ActiveRequests, sample.err = qm.requests(sampleCtx, "SELECT r.* FROM system:active_requests AS r", nil)
+	if sample.err != nil {
+		return sample, endpoint
+	}
+
+	sample.Vitals, endpoint, sample.err = qm.vitals(sampleCtx, endpoint)
+
+	return sample, endpoint
+}
+
+// Next assigns the next sample into samplePtr, waiting until it has been taken. It returns false once the watch
+// has been closed or its context is done.
+func (w *QueryMonitorWatch) Next(samplePtr *QueryMonitorSample) bool {
+	if w.ctx.Err() != nil {
+		return false
+	}
+
+	select {
+	case sample := <-w.sampleCh:
+		*samplePtr = sample
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
+
+// Close ends the watch, cancelling any sample which is being taken. Failures to take samples are reported by each
+// sample so there is no error to return, it exists so that QueryMonitorWatch is an io.Closer.
+func (w *QueryMonitorWatch) Close() error {
+	w.cancel()
+	return nil
+}
@@ -0,0 +1,214 @@
+package gocb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+const testQueryVitalsResponse = `{"uptime":"7h39m32.36s","local.time":"2019-08-22 09:54:42.6011776 +0000 UTC",` +
+	`"version":"2.0.0-N1QL","total.threads":191,"cores":8,"gc.num":62871,"gc.pause.time":"21.33ms",` +
+	`"gc.pause.percent":0,"memory.usage":10186416,"memory.total":3592826480,"memory.system":143767800,` +
+	`"cpu.user.percent":1.5,"cpu.sys.percent":0.5,"request.completed.count":140,"request.active.count":2,` +
+	`"request.per.sec.1min":0.25,"request.per.sec.5min":0.1,"request.per.sec.15min":0.05,` +
+	`"request_time.mean":"536.227µs","request_time.median":"501.112µs","request_time.80percentile":"1.2ms",` +
+	`"request_time.95percentile":"3ms","request_time.99percentile":"15ms","request.prepared.percent":25}`
+
+func testGetQueryMonitorProvider(t *testing.T, rows string, executed *map[string]interface{}) *mockHTTPProvider {
+	// Requests which are not pinned to an endpoint are answered by each query node in turn.
+	nodes := []string{"http://10.112.0.1:8093", "http://10.112.0.2:8093", "http://10.112.0.3:8093"}
+	var requests int
+	return &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			respBody := testQueryVitalsResponse
+			if req.Path == "/query/service" {
+				var body map[string]interface{}
+				err := json.Unmarshal(req.Body, &body)
+				if err != nil {
+					t.Fatalf("Failed to unmarshal request body %v", err)
+				}
+				if executed != nil {
+					*executed = body
+				}
+
+				// The monitoring request is itself active, so it is included in the results.
+				monitorRow := `{"requestId":"monitor","clientContextID":"` + body["client_context_id"].(string) +
+					`","state":"running","statement":"SELECT r.* FROM system:active_requests AS r"}`
+				respBody = `{"results":[` + monitorRow + rows + `],"status":"success"}`
+			} else if req.Path != "/admin/vitals" || req.Method != "GET" {
+				t.Fatalf("Unexpected request %s %s", req.Method, req.Path)
+			}
+
+			endpoint := req.Endpoint
+			if endpoint == "" {
+				endpoint = nodes[requests%len(nodes)]
+				requests++
+			}
+
+			return &gocbcore.HttpResponse{
+				Endpoint:   endpoint,
+				StatusCode: 200,
+				Body:       &testReadCloser{bytes.NewBufferString(respBody), nil},
+			}, nil
+		},
+	}
+}
+
+func TestQueryMonitorActiveRequests(t *testing.T) {
+	rows := `,{"requestId":"c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1","clientContextID":"orders-report",` +
+		`"statement":"SELECT * FROM orders","state":"running","users":"Administrator","node":"10.112.0.1:8091",` +
+		`"remoteAddr":"10.112.0.5:52345","userAgent":"gocb/v2","requestTime":"2019-08-22 09:54:42.6011776 +0000 UTC",` +
+		`"elapsedTime":"12.5s","serviceTime":"12.4s","resultCount":1500,"resultSize":60000,` +
+		`"phaseTimes":{"fetch":"10s","primaryScan":"2s"},"phaseCounts":{"fetch":1500},"phaseOperators":{"fetch":1}}`
+
+	cluster := testGetClusterForHTTP(testGetQueryMonitorProvider(t, rows, nil), time.Second, 0, 0)
+
+	requests, err := cluster.QueryMonitor().ActiveRequests(nil)
+	if err != nil {
+		t.Fatalf("Expected active requests to succeed but was %v", err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected the monitoring request to be skipped but requests were %+v", requests)
+	}
+
+	req := requests[0]
+	if req.RequestID != "c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1" || req.ClientContextID != "orders-report" ||
+		req.Statement != "SELECT * FROM orders" || req.State != "running" || req.Users != "Administrator" ||
+		req.Node != "10.112.0.1:8091" {
+		t.Fatalf("Expected request to be decoded but was %+v", req)
+	}
+
+	if req.ElapsedTime != 12500*time.Millisecond || req.ServiceTime != 12400*time.Millisecond {
+		t.Fatalf("Expected durations to be parsed but were %s and %s", req.ElapsedTime, req.ServiceTime)
+	}
+
+	expectedTime := time.Date(2019, 8, 22, 9, 54, 42, 601177600, time.UTC)
+	if !req.RequestTime.Equal(expectedTime) {
+		t.Fatalf("Expected request time to be %s but was %s", expectedTime, req.RequestTime)
+	}
+
+	if req.PhaseTimes["fetch"] != 10*time.Second || req.PhaseCounts["fetch"] != 1500 || req.PhaseOperators["fetch"] != 1 {
+		t.Fatalf("Expected phases to be decoded but were %v, %v and %v", req.PhaseTimes, req.PhaseCounts,
+			req.PhaseOperators)
+	}
+}
+
+func TestQueryMonitorCompletedRequests(t *testing.T) {
+	var executed map[string]interface{}
+	cluster := testGetClusterForHTTP(testGetQueryMonitorProvider(t, "", &executed), time.Second, 0, 0)
+	monitor := cluster.QueryMonitor()
+
+	_, err := monitor.CompletedRequests(QueryRequestFilter{
+		MinElapsedTime:    5 * time.Second,
+		State:             "completed",
+		StatementContains: "orders",
+		Limit:             10,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected completed requests to succeed but was %v", err)
+	}
+
+	expectedStatement := "SELECT r.* FROM system:completed_requests AS r WHERE STR_TO_DURATION(r.elapsedTime) >= ? " +
+		"AND r.state = ? AND CONTAINS(r.statement, ?) ORDER BY r.requestTime DESC LIMIT 10"
+	if executed["statement"] != expectedStatement {
+		t.Fatalf("Expected statement to be %s but was %s", expectedStatement, executed["statement"])
+	}
+
+	args, _ := executed["args"].([]interface{})
+	if len(args) != 3 || args[0] != float64(5*time.Second) || args[1] != "completed" || args[2] != "orders" {
+		t.Fatalf("Expected filter to be bound but args were %v", executed["args"])
+	}
+
+	_, err = monitor.CompletedRequests(QueryRequestFilter{}, nil)
+	if err != nil {
+		t.Fatalf("Expected completed requests to succeed but was %v", err)
+	}
+
+	if executed["statement"] != "SELECT r.* FROM system:completed_requests AS r ORDER BY r.requestTime DESC" {
+		t.Fatalf("Expected an empty filter not to restrict requests but statement was %s", executed["statement"])
+	}
+
+	_, err = monitor.CompletedRequests(QueryRequestFilter{Limit: -1}, nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected negative limit to be invalid but was %v", err)
+	}
+}
+
+func TestQueryMonitorVitals(t *testing.T) {
+	cluster := testGetClusterForHTTP(testGetQueryMonitorProvider(t, "", nil), time.Second, 0, 0)
+
+	vitals, err := cluster.QueryMonitor().Vitals(nil)
+	if err != nil {
+		t.Fatalf("Expected vitals to succeed but was %v", err)
+	}
+
+	if vitals.Node != "10.112.0.1:8093" || vitals.Version != "2.0.0-N1QL" || vitals.Cores != 8 ||
+		vitals.TotalThreads != 191 || vitals.MemoryUsage != 10186416 || vitals.CPUUserPercent != 1.5 {
+		t.Fatalf("Expected vitals to be decoded but were %+v", vitals)
+	}
+
+	if vitals.ActiveRequests != 2 || vitals.CompletedRequests != 140 || vitals.RequestsPerSecond1Min != 0.25 ||
+		vitals.PreparedPercent != 25 {
+		t.Fatalf("Expected request vitals to be decoded but were %+v", vitals)
+	}
+
+	if vitals.Uptime != 7*time.Hour+39*time.Minute+32360*time.Millisecond || vitals.GCPauseTime != 21330*time.Microsecond ||
+		vitals.RequestTimeMean != 536227*time.Nanosecond || vitals.RequestTime99Percentile != 15*time.Millisecond {
+		t.Fatalf("Expected durations to be parsed but were %+v", vitals)
+	}
+}
+
+func TestQueryMonitorWatch(t *testing.T) {
+	rows := `,{"requestId":"c8d1a8e6-2fb7-4cd5-a7c7-9a59b6c5d5a1","statement":"SELECT * FROM orders","state":"running"}`
+	cluster := testGetClusterForHTTP(testGetQueryMonitorProvider(t, rows, nil), time.Second, 0, 0)
+	monitor := cluster.QueryMonitor()
+
+	_, err := monitor.Watch(0, nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected zero interval to be invalid but was %v", err)
+	}
+
+	watch, err := monitor.Watch(10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("Expected watch to succeed but was %v", err)
+	}
+
+	var sample QueryMonitorSample
+	var node string
+	for i := 0; i < 3; i++ {
+		if !watch.Next(&sample) {
+			t.Fatalf("Expected sample %d to be taken", i)
+		}
+
+		if sample.Err() != nil {
+			t.Fatalf("Expected sample to succeed but was %v", sample.Err())
+		}
+
+		if len(sample.ActiveRequests) != 1 || sample.ActiveRequests[0].Statement != "SELECT * FROM orders" {
+			t.Fatalf("Expected sample to contain the active request but was %+v", sample.ActiveRequests)
+		}
+
+		if sample.Vitals == nil || sample.Vitals.ActiveRequests != 2 || sample.Time.IsZero() {
+			t.Fatalf("Expected sample to contain vitals but was %+v", sample)
+		}
+
+		if node == "" {
+			node = sample.Vitals.Node
+		} else if sample.Vitals.Node != node {
+			t.Fatalf("Expected vitals of every sample to be from node %s but were from %s", node, sample.Vitals.Node)
+		}
+	}
+
+	err = watch.Close()
+	if err != nil {
+		t.Fatalf("Expected close to succeed but was %v", err)
+	}
+
+	if watch.Next(&sample) {
+		t.Fatalf("Expected no samples once closed")
+	}
+}
@@ -0,0 +1,400 @@
+package gocb
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/couchbase/gocb/v2/n1ql"
+	"github.com/pkg/errors"
+)
+
+// QueryPagerPredicate is a placeholder for use within the WHERE clause of statements paged by a QueryPager. It is
+// replaced with a predicate which restricts the statement to the rows after the previous page.
+const QueryPagerPredicate = "${QueryPager.predicate}"
+
+// QueryPagerKeys is a placeholder for use within the projection of statements paged by a QueryPager. It is
+// replaced with the values of the pager keys, which are read from each page to find where the next page begins.
+const QueryPagerKeys = "${QueryPager.keys}"
+
+// queryPagerKeysField is the field of each row which the pager keys are projected as.
+const queryPagerKeysField = "gocbPagerKeys"
+
+// queryPagerParameter is the prefix of the named parameters which the keys of the previous page are bound to.
+const queryPagerParameter = "gocbPagerKey"
+
+const defaultQueryPageSize = 100
+
+// QueryPagerOptions is the set of options available when creating a QueryPager.
+type QueryPagerOptions struct {
+	// PageSize is the maximum number of rows in each page, defaults to 100.
+	PageSize int
+	// Descending pages through the keys in descending rather than ascending order.
+	Descending bool
+	// Token continues paging after the page which returned it, see QueryPage.Token.
+	Token string
+
+	Consistency ConsistencyMode
+	// ConsistentWith is carried by the tokens of each page, so that pages fetched by a pager resumed from a token
+	// are consistent with the same mutations. It replaces the mutation state of Token when set, as does Consistency.
+	ConsistentWith  *MutationState
+	Prepared        bool
+	NamedParameters map[string]interface{}
+	// Parameters is a struct whose fields are bound as named parameters, see QueryOptions.Parameters.
+	Parameters interface{}
+	// Timeout applies to fetching each page, defaults to the query timeout of the cluster.
+	Timeout time.Duration
+	// Serializer is used to decode the rows of each page, defaults to the serializer of the cluster.
+	Serializer JSONSerializer
+}
+
+// QueryPager pages through the results of a N1QL statement using keyset pagination. Rather than skipping the rows
+// of previous pages with OFFSET, which becomes slower for every page, each page selects the rows whose keys follow
+// the last row of the previous page. The keys must uniquely identify each row, such as by ending with META().id,
+// and rows whose keys are NULL or MISSING are never returned.
+type QueryPager struct {
+	cluster     *Cluster
+	statement   string
+	keys        []string
+	fingerprint string
+	opts        QueryPagerOptions
+	params      map[string]interface{}
+	checkParams bool
+
+	consistentWith *MutationState
+	last           []json.RawMessage
+	done           bool
+}
+
+type queryPagerToken struct {
+	Fingerprint    string            `json:"f"`
+	Keys           []json.RawMessage `json:"k"`
+	ConsistentWith *MutationState    `json:"c,omitempty"`
+}
+
+// QueryPager creates a pager for statement which orders rows by the expressions in keys, such as n1ql.I("a", "name")
+// and n1ql.MetaID("a"). Keys cannot bind values. The statement must contain the QueryPagerPredicate placeholder
+// within its WHERE clause and the QueryPagerKeys placeholder within its projection, such as SELECT a.*,
+// ${QueryPager.keys} FROM airline AS a WHERE a.country = $country AND ${QueryPager.predicate}. The pager adds the
+// ORDER BY and LIMIT clauses, so the statement must not have its own, nor any comments. A trailing semicolon is
+// removed. Positional parameters cannot be used as the keys of the previous page are bound as named parameters.
+func (c *Cluster) QueryPager(statement string, keys []n1ql.Expr, opts *QueryPagerOptions) (*QueryPager, error) {
+	if opts == nil {
+		opts = &QueryPagerOptions{}
+	}
+
+	if len(keys) == 0 {
+		return nil, invalidArgumentsError{message: "at least one key must be specified"}
+	}
+
+	rendered := make([]string, len(keys))
+	for i, key := range keys {
+		var err error
+		rendered[i], err = n1ql.Render(key)
+		if err != nil {
+			return nil, invalidArgumentsError{message: fmt.Sprintf("key %d is invalid, %s", i, err)}
+		}
+	}
+
+	statement, err := checkPagedStatement(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.Contains(statement, QueryPagerPredicate) || !strings.Contains(statement, QueryPagerKeys) {
+		return nil, invalidArgumentsError{message: "statement must contain the pager predicate and keys placeholders"}
+	}
+
+	if opts.PageSize < 0 {
+		return nil, invalidArgumentsError{message: "page size cannot be negative"}
+	}
+
+	if opts.Consistency != 0 && opts.ConsistentWith != nil {
+		return nil, invalidArgumentsError{message: "Consistency and ConsistentWith must be used exclusively"}
+	}
+
+	pager := &QueryPager{
+		cluster:        c,
+		statement:      statement,
+		keys:           rendered,
+		opts:           *opts,
+		params:         opts.NamedParameters,
+		consistentWith: opts.ConsistentWith,
+	}
+
+	if pager.opts.PageSize == 0 {
+		pager.opts.PageSize = defaultQueryPageSize
+	}
+
+	if pager.opts.Serializer == nil {
+		pager.opts.Serializer = c.sb.Serializer
+	}
+
+	if opts.Parameters != nil {
+		if opts.NamedParameters != nil {
+			return nil, invalidArgumentsError{message: "Parameters cannot be used with named parameters"}
+		}
+
+		pager.params, err = structParameters(opts.Parameters, pager.opts.Serializer)
+		if err != nil {
+			return nil, err
+		}
+		pager.checkParams = true
+	}
+
+	for key := range pager.params {
+		if strings.HasPrefix(strings.TrimPrefix(key, "$"), queryPagerParameter) {
+			return nil, invalidArgumentsError{message: fmt.Sprintf("named parameter %s is reserved", key)}
+		}
+	}
+
+	hash := sha1.Sum([]byte(strings.Join(append([]string{statement, strconv.FormatBool(opts.Descending)}, rendered...),
+		"\x00")))
+	pager.fingerprint = hex.EncodeToString(hash[:8])
+
+	if opts.Token != "" {
+		token, err := pager.decodeToken(opts.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		pager.last = token.Keys
+		if pager.consistentWith == nil && opts.Consistency == 0 {
+			pager.consistentWith = token.ConsistentWith
+		}
+	}
+
+	return pager, nil
+}
+
+// checkPagedStatement removes any trailing semicolon from statement, then checks that the clauses which the pager
+// appends can follow it.
+func checkPagedStatement(statement string) (string, error) {
+	statement = strings.TrimRight(strings.TrimSpace(statement), ";")
+	statement = strings.TrimSpace(statement)
+
+	tokens, err := scanN1qlTokens(statement)
+	if err != nil {
+		return "", err
+	}
+
+	for _, token := range tokens {
+		if token.depth == 0 && (token.text == "ORDER" || token.text == "LIMIT" || token.text == "OFFSET") {
+			return "", invalidArgumentsError{message: "statement cannot contain its own " + token.text + " clause"}
+		}
+	}
+
+	return statement, nil
+}
+
+func (p *QueryPager) decodeToken(encoded string) (*queryPagerToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalidArgumentsError{message: "continuation token is invalid"}
+	}
+
+	var token queryPagerToken
+	err = json.Unmarshal(data, &token)
+	if err != nil || len(token.Keys) != len(p.keys) {
+		return nil, invalidArgumentsError{message: "continuation token is invalid"}
+	}
+
+	if token.Fingerprint != p.fingerprint {
+		return nil, invalidArgumentsError{message: "continuation token was issued for a different statement"}
+	}
+
+	return &token, nil
+}
+
+func (p *QueryPager) encodeToken() (string, error) {
+	data, err := json.Marshal(queryPagerToken{
+		Fingerprint:    p.fingerprint,
+		Keys:           p.last,
+		ConsistentWith: p.consistentWith,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "could not encode continuation token")
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// pageStatement returns the statement for the page after the keys in p.last, along with the named parameters
+// which bind them.
+func (p *QueryPager) pageStatement() (string, map[string]interface{}) {
+	params := make(map[string]interface{}, len(p.params)+len(p.last))
+	for key, value := range p.params {
+		params[key] = value
+	}
+
+	op, direction := ">", ""
+	if p.opts.Descending {
+		op, direction = "<", " DESC"
+	}
+
+	// Rows whose keys are NULL or MISSING sort before every other row, so they are excluded from every page.
+	// Otherwise a page could end on one of them and the keys of the next page would be compared against NULL.
+	conds := make([]string, len(p.keys), len(p.keys)+1)
+	for i, key := range p.keys {
+		conds[i] = fmt.Sprintf("(%s) IS VALUED", key)
+	}
+
+	// The rows after (k1, k2) are those with k1 > $1, or k1 = $1 and k2 > $2, which unlike comparing arrays of
+	// the keys can be answered by an index scan.
+	if p.last != nil {
+		disjuncts := make([]string, len(p.keys))
+		for i := range p.keys {
+			conjuncts := make([]string, i+1)
+			for j := 0; j < i; j++ {
+				conjuncts[j] = fmt.Sprintf("(%s) = $%s%d", p.keys[j], queryPagerParameter, j)
+			}
+			conjuncts[i] = fmt.Sprintf("(%s) %s $%s%d", p.keys[i], op, queryPagerParameter, i)
+			disjuncts[i] = "(" + strings.Join(conjuncts, " AND ") + ")"
+			params[queryPagerParameter+strconv.Itoa(i)] = p.last[i]
+		}
+		conds = append(conds, "("+strings.Join(disjuncts, " OR ")+")")
+	}
+
+	predicate := "(" + strings.Join(conds, " AND ") + ")"
+
+	orderBy := make([]string, len(p.keys))
+	for i, key := range p.keys {
+		orderBy[i] = key + direction
+	}
+
+	statement := strings.Replace(p.statement, QueryPagerPredicate, predicate, -1)
+	statement = strings.Replace(statement, QueryPagerKeys, "["+strings.Join(p.keys, ", ")+"] AS "+queryPagerKeysField, -1)
+	statement += " ORDER BY " + strings.Join(orderBy, ", ") + " LIMIT " + strconv.Itoa(p.opts.PageSize+1)
+
+	return statement, params
+}
+
+// HasMore returns whether there may be another page to fetch.
+func (p *QueryPager) HasMore() bool {
+	return !p.done
+}
+
+// Next fetches the next page. Once the last page has been fetched HasMore returns false, and Next returns a no
+// results error.
+func (p *QueryPager) Next(ctx context.Context) (*QueryPage, error) {
+	if p.done {
+		return nil, noResultsError{}
+	}
+
+	statement, params := p.pageStatement()
+	if p.checkParams {
+		err := checkNamedParameters(statement, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := p.cluster.Query(statement, &QueryOptions{
+		Context:         ctx,
+		Timeout:         p.opts.Timeout,
+		Consistency:     p.opts.Consistency,
+		ConsistentWith:  p.consistentWith,
+		Prepared:        p.opts.Prepared,
+		NamedParameters: params,
+		Serializer:      p.opts.Serializer,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []json.RawMessage
+	for row := results.NextBytes(); row != nil; row = results.NextBytes() {
+		rows = append(rows, row)
+	}
+	if err := results.Close(); err != nil {
+		return nil, err
+	}
+
+	page := &QueryPage{
+		serializer: p.opts.Serializer,
+	}
+
+	// One more row than the page size is fetched to find out whether there is another page.
+	if len(rows) <= p.opts.PageSize {
+		page.rows = rows
+		p.done = true
+		return page, nil
+	}
+	page.rows = rows[:p.opts.PageSize]
+
+	var keyed struct {
+		Keys []json.RawMessage `json:"gocbPagerKeys"`
+	}
+	err = json.Unmarshal(page.rows[len(page.rows)-1], &keyed)
+	if err != nil || len(keyed.Keys) != len(p.keys) {
+		return nil, errors.New("could not read the pager keys of the last row, the projection must be an object " +
+			"containing the pager keys placeholder")
+	}
+	p.last = keyed.Keys
+
+	page.token, err = p.encodeToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// QueryPage is a page of rows fetched by a QueryPager.
+type QueryPage struct {
+	rows       []json.RawMessage
+	serializer JSONSerializer
+	token      string
+}
+
+// Len returns the number of rows in the page.
+func (p *QueryPage) Len() int {
+	return len(p.rows)
+}
+
+// Row decodes row i of the page into valuePtr.
+func (p *QueryPage) Row(i int, valuePtr interface{}) error {
+	if i < 0 || i >= len(p.rows) {
+		return invalidArgumentsError{message: fmt.Sprintf("row %d is out of range for a page of %d rows", i, len(p.rows))}
+	}
+
+	return p.serializer.Deserialize(p.rows[i], valuePtr)
+}
+
+// Rows decodes every row of the page, appending them to the slice pointed to by slicePtr.
+func (p *QueryPage) Rows(slicePtr interface{}) error {
+	slice := reflect.ValueOf(slicePtr)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return invalidArgumentsError{message: fmt.Sprintf("rows must be decoded into a pointer to a slice, not %T", slicePtr)}
+	}
+	slice = slice.Elem()
+
+	for _, row := range p.rows {
+		value := reflect.New(slice.Type().Elem())
+		err := p.serializer.Deserialize(row, value.Interface())
+		if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, value.Elem()))
+	}
+
+	return nil
+}
+
+// Token returns an opaque token which continues paging after this page, see QueryPagerOptions.Token. It is empty
+// for the last page.
+func (p *QueryPage) Token() string {
+	return p.token
+}
+
+// HasMore returns whether there is another page after this one.
+func (p *QueryPage) HasMore() bool {
+	return p.token != ""
+}
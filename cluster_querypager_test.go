@@ -0,0 +1,336 @@
+package gocb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/couchbase/gocb/v2/n1ql"
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+const testPagerStatement = "SELECT a.id, " + QueryPagerKeys + " FROM airline AS a WHERE a.country = $country AND " +
+	QueryPagerPredicate
+
+type testPagerRow struct {
+	ID int `json:"id"`
+}
+
+// testGetPagerProvider serves the airlines with ids 1 to count, applying the pager predicate and limit to them.
+// The first missing airlines have no id, so sort first, and are only excluded when the statement requires the key
+// to be valued.
+func testGetPagerProvider(t *testing.T, count, missing int, executed *[]map[string]interface{}) *mockHTTPProvider {
+	return &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			var body map[string]interface{}
+			err := json.Unmarshal(req.Body, &body)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal request body %v", err)
+			}
+			*executed = append(*executed, body)
+
+			statement := body["statement"].(string)
+			var limit int
+			_, err = fmt.Sscanf(statement[strings.LastIndex(statement, "LIMIT "):], "LIMIT %d", &limit)
+			if err != nil {
+				t.Fatalf("Expected statement to be limited but was %s", statement)
+			}
+
+			after := 0
+			if last, ok := body["$gocbPagerKey0"].(float64); ok {
+				after = int(last)
+			}
+
+			var rows []string
+			if _, ok := body["$gocbPagerKey0"]; !ok && !strings.Contains(statement, "IS VALUED") {
+				for i := 0; i < missing && len(rows) < limit; i++ {
+					rows = append(rows, `{"gocbPagerKeys":[null]}`)
+				}
+			}
+			for id := after + 1; id <= count && len(rows) < limit; id++ {
+				rows = append(rows, fmt.Sprintf(`{"id":%d,"gocbPagerKeys":[%d]}`, id, id))
+			}
+
+			return &gocbcore.HttpResponse{
+				Endpoint:   "http://localhost:8093",
+				StatusCode: 200,
+				Body: &testReadCloser{bytes.NewBufferString(`{"results":[` + strings.Join(rows, ",") +
+					`],"status":"success"}`), nil},
+			}, nil
+		},
+	}
+}
+
+func TestQueryPager(t *testing.T) {
+	var executed []map[string]interface{}
+	cluster := testGetClusterForHTTP(testGetPagerProvider(t, 5, 0, &executed), time.Second, 0, 0)
+
+	pager, err := cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		PageSize:        2,
+		NamedParameters: map[string]interface{}{"country": "France"},
+	})
+	if err != nil {
+		t.Fatalf("Expected pager to be created but was %v", err)
+	}
+
+	var ids []int
+	var tokens []string
+	for pager.HasMore() {
+		page, err := pager.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Expected page to be fetched but was %v", err)
+		}
+
+		var rows []testPagerRow
+		err = page.Rows(&rows)
+		if err != nil {
+			t.Fatalf("Expected rows to be decoded but was %v", err)
+		}
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+		}
+
+		if page.HasMore() != pager.HasMore() {
+			t.Fatalf("Expected page and pager to agree on whether there are more pages")
+		}
+		tokens = append(tokens, page.Token())
+	}
+
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" {
+		t.Fatalf("Expected every row to be paged through once but was %v", ids)
+	}
+
+	if len(tokens) != 3 || tokens[0] == "" || tokens[1] == "" || tokens[2] != "" {
+		t.Fatalf("Expected a token for every page but the last but was %v", tokens)
+	}
+
+	expectedFirst := "SELECT a.id, [`a`.`id`] AS gocbPagerKeys FROM airline AS a WHERE a.country = $country AND " +
+		"((`a`.`id`) IS VALUED) ORDER BY `a`.`id` LIMIT 3"
+	if executed[0]["statement"] != expectedFirst {
+		t.Fatalf("Expected first statement to be %s but was %s", expectedFirst, executed[0]["statement"])
+	}
+
+	expectedNext := "SELECT a.id, [`a`.`id`] AS gocbPagerKeys FROM airline AS a WHERE a.country = $country AND " +
+		"((`a`.`id`) IS VALUED AND (((`a`.`id`) > $gocbPagerKey0))) ORDER BY `a`.`id` LIMIT 3"
+	if executed[1]["statement"] != expectedNext {
+		t.Fatalf("Expected next statement to be %s but was %s", expectedNext, executed[1]["statement"])
+	}
+
+	if executed[1]["$country"] != "France" || executed[1]["$gocbPagerKey0"] != float64(2) {
+		t.Fatalf("Expected parameters to be bound but request was %v", executed[1])
+	}
+
+	_, err = pager.Next(context.Background())
+	if !IsNoResultsError(err) {
+		t.Fatalf("Expected no results once paged through but was %v", err)
+	}
+
+	var row testPagerRow
+	resumed, err := cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		PageSize:        2,
+		NamedParameters: map[string]interface{}{"country": "France"},
+		Token:           tokens[1],
+	})
+	if err != nil {
+		t.Fatalf("Expected pager to be resumed but was %v", err)
+	}
+
+	page, err := resumed.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Expected page to be fetched but was %v", err)
+	}
+
+	if page.Len() != 1 || page.Row(0, &row) != nil || row.ID != 5 {
+		t.Fatalf("Expected resumed pager to continue after the token but page had %d rows", page.Len())
+	}
+}
+
+func TestQueryPagerCompositeKeys(t *testing.T) {
+	var executed []map[string]interface{}
+	cluster := testGetClusterForHTTP(testGetPagerProvider(t, 5, 0, &executed), time.Second, 0, 0)
+
+	pager, err := cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "name"), n1ql.MetaID("a")}, &QueryPagerOptions{
+		PageSize:        10,
+		Descending:      true,
+		NamedParameters: map[string]interface{}{"country": "France"},
+	})
+	if err != nil {
+		t.Fatalf("Expected pager to be created but was %v", err)
+	}
+
+	pager.last = []json.RawMessage{json.RawMessage(`"Air France"`), json.RawMessage(`"airline_137"`)}
+	statement, params := pager.pageStatement()
+
+	expected := "SELECT a.id, [`a`.`name`, META(`a`).id] AS gocbPagerKeys FROM airline AS a WHERE a.country = $country " +
+		"AND ((`a`.`name`) IS VALUED AND (META(`a`).id) IS VALUED AND (((`a`.`name`) < $gocbPagerKey0) OR " +
+		"((`a`.`name`) = $gocbPagerKey0 AND (META(`a`).id) < $gocbPagerKey1))) " +
+		"ORDER BY `a`.`name` DESC, META(`a`).id DESC LIMIT 11"
+	if statement != expected {
+		t.Fatalf("Expected statement to be %s but was %s", expected, statement)
+	}
+
+	if len(params) != 3 || string(params["gocbPagerKey1"].(json.RawMessage)) != `"airline_137"` {
+		t.Fatalf("Expected keys to be bound but parameters were %v", params)
+	}
+}
+
+func TestQueryPagerConsistentWith(t *testing.T) {
+	var executed []map[string]interface{}
+	cluster := testGetClusterForHTTP(testGetPagerProvider(t, 5, 0, &executed), time.Second, 0, 0)
+
+	pager, err := cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		PageSize:        2,
+		NamedParameters: map[string]interface{}{"country": "France"},
+		ConsistentWith:  testReplicaMutationState(10),
+	})
+	if err != nil {
+		t.Fatalf("Expected pager to be created but was %v", err)
+	}
+
+	page, err := pager.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Expected page to be fetched but was %v", err)
+	}
+
+	resumed, err := cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		PageSize:        2,
+		NamedParameters: map[string]interface{}{"country": "France"},
+		Token:           page.Token(),
+	})
+	if err != nil {
+		t.Fatalf("Expected pager to be resumed but was %v", err)
+	}
+
+	_, err = resumed.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Expected page to be fetched but was %v", err)
+	}
+
+	for i, body := range executed {
+		if body["scan_consistency"] != "at_plus" {
+			t.Fatalf("Expected page %d to be consistent with the mutation state but was %v", i, body)
+		}
+
+		vectors, _ := json.Marshal(body["scan_vectors"])
+		if string(vectors) != `{"mock":{"12":[10,"1234"]}}` {
+			t.Fatalf("Expected page %d to be consistent with the mutation state but vectors were %s", i, vectors)
+		}
+	}
+}
+
+func TestQueryPagerSkipsMissingKeys(t *testing.T) {
+	var executed []map[string]interface{}
+	cluster := testGetClusterForHTTP(testGetPagerProvider(t, 5, 3, &executed), time.Second, 0, 0)
+
+	pager, err := cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		PageSize:        2,
+		NamedParameters: map[string]interface{}{"country": "France"},
+	})
+	if err != nil {
+		t.Fatalf("Expected pager to be created but was %v", err)
+	}
+
+	var ids []int
+	for pager.HasMore() {
+		page, err := pager.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Expected page to be fetched but was %v", err)
+		}
+
+		var rows []testPagerRow
+		err = page.Rows(&rows)
+		if err != nil {
+			t.Fatalf("Expected rows to be decoded but was %v", err)
+		}
+
+		for _, row := range rows {
+			if row.ID == 0 {
+				t.Fatalf("Expected rows with a missing key to be skipped but page was %+v", rows)
+			}
+			ids = append(ids, row.ID)
+		}
+	}
+
+	if len(ids) != 5 || ids[0] != 1 || ids[4] != 5 {
+		t.Fatalf("Expected every row with a key to be returned but was %v", ids)
+	}
+}
+
+func TestQueryPagerInvalid(t *testing.T) {
+	var executed []map[string]interface{}
+	cluster := testGetClusterForHTTP(testGetPagerProvider(t, 5, 0, &executed), time.Second, 0, 0)
+
+	_, err := cluster.QueryPager(testPagerStatement, nil, nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected pager without keys to be invalid but was %v", err)
+	}
+
+	_, err = cluster.QueryPager("SELECT a.* FROM airline AS a", []n1ql.Expr{n1ql.I("a", "id")}, nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected statement without placeholders to be invalid but was %v", err)
+	}
+
+	_, err = cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id").Eq(1)}, nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected key which binds a value to be invalid but was %v", err)
+	}
+
+	invalidStatements := []string{
+		testPagerStatement + " -- trailing comment",
+		testPagerStatement + " /* comment */",
+		testPagerStatement + "; DELETE FROM airline",
+		testPagerStatement + " ORDER BY a.name",
+		testPagerStatement + " LIMIT 10",
+	}
+	for _, statement := range invalidStatements {
+		_, err = cluster.QueryPager(statement, []n1ql.Expr{n1ql.I("a", "id")}, nil)
+		if !IsInvalidArgumentsError(err) {
+			t.Fatalf("Expected statement %s to be invalid but was %v", statement, err)
+		}
+	}
+
+	pager, err := cluster.QueryPager(testPagerStatement+" ; ", []n1ql.Expr{n1ql.I("a", "id")}, nil)
+	if err != nil {
+		t.Fatalf("Expected statement with a trailing semicolon to be valid but was %v", err)
+	}
+	if statement, _ := pager.pageStatement(); !strings.HasSuffix(statement, "AND ((`a`.`id`) IS VALUED) ORDER BY `a`.`id` LIMIT 101") {
+		t.Fatalf("Expected trailing semicolon to be removed but statement was %s", statement)
+	}
+
+	_, err = cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		NamedParameters: map[string]interface{}{"country": "France", "gocbPagerKey0": 1},
+	})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected reserved parameter to be invalid but was %v", err)
+	}
+
+	_, err = cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{Token: "not a token"})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected malformed token to be invalid but was %v", err)
+	}
+
+	pager, err = cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		PageSize:        2,
+		NamedParameters: map[string]interface{}{"country": "France"},
+	})
+	if err != nil {
+		t.Fatalf("Expected pager to be created but was %v", err)
+	}
+
+	page, err := pager.Next(context.Background())
+	if err != nil {
+		t.Fatalf("Expected page to be fetched but was %v", err)
+	}
+
+	_, err = cluster.QueryPager(testPagerStatement, []n1ql.Expr{n1ql.I("a", "id")}, &QueryPagerOptions{
+		Descending: true,
+		Token:      page.Token(),
+	})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected token for a different pager to be invalid but was %v", err)
+	}
+}
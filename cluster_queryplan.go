@@ -0,0 +1,329 @@
+package gocb
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// QueryPlanOperator is an operator within a query plan or profile, such as an index scan or a fetch.
+type QueryPlanOperator struct {
+	// Operator is the name of the operator, such as IndexScan3, PrimaryScan3 or Fetch.
+	Operator string
+	Keyspace string
+	Index    string
+	// Covers are the expressions provided by a covering index scan.
+	Covers   []string
+	Children []*QueryPlanOperator
+	// Stats is only set for operators within the execution timings of a profile.
+	Stats *QueryOperatorStats
+	// Properties contains every property of the operator as returned by the server.
+	Properties map[string]interface{}
+}
+
+// QueryOperatorStats are the execution statistics of an operator, as returned when profiling with QueryProfileTimings.
+type QueryOperatorStats struct {
+	ItemsIn       uint64
+	ItemsOut      uint64
+	PhaseSwitches uint64
+	ExecTime      time.Duration
+	KernTime      time.Duration
+	ServTime      time.Duration
+}
+
+func newQueryPlanOperator(raw map[string]interface{}) *QueryPlanOperator {
+	op := &QueryPlanOperator{Properties: raw}
+	op.Operator, _ = raw["#operator"].(string)
+	op.Keyspace, _ = raw["keyspace"].(string)
+	op.Index, _ = raw["index"].(string)
+
+	if covers, ok := raw["covers"].([]interface{}); ok {
+		for _, cover := range covers {
+			if s, ok := cover.(string); ok {
+				op.Covers = append(op.Covers, s)
+			}
+		}
+	}
+
+	if stats, ok := raw["#stats"].(map[string]interface{}); ok {
+		op.Stats = &QueryOperatorStats{
+			ItemsIn:       planUint(stats["#itemsIn"]),
+			ItemsOut:      planUint(stats["#itemsOut"]),
+			PhaseSwitches: planUint(stats["#phaseSwitches"]),
+			ExecTime:      parseQueryDuration(stats["execTime"]),
+			KernTime:      parseQueryDuration(stats["kernTime"]),
+			ServTime:      parseQueryDuration(stats["servTime"]),
+		}
+	}
+
+	// Children are held under different properties depending on the operator, such as ~children for a Sequence,
+	// ~child for a Parallel and scans for an IntersectScan, so any nested operator is treated as a child.
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch v := raw[key].(type) {
+		case map[string]interface{}:
+			if isPlanOperator(v) {
+				op.Children = append(op.Children, newQueryPlanOperator(v))
+			}
+		case []interface{}:
+			for _, elem := range v {
+				if child, ok := elem.(map[string]interface{}); ok && isPlanOperator(child) {
+					op.Children = append(op.Children, newQueryPlanOperator(child))
+				}
+			}
+		}
+	}
+
+	return op
+}
+
+func isPlanOperator(raw map[string]interface{}) bool {
+	_, ok := raw["#operator"].(string)
+	return ok
+}
+
+func planUint(v interface{}) uint64 {
+	f, _ := v.(float64)
+	return uint64(f)
+}
+
+func parseQueryDuration(v interface{}) time.Duration {
+	s, _ := v.(string)
+	if s == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		logDebugf("Failed to parse query duration (%s)", err)
+	}
+
+	return d
+}
+
+// Walk calls fn for op and every operator beneath it, parents before their children.
+func (op *QueryPlanOperator) Walk(fn func(op *QueryPlanOperator)) {
+	if op == nil {
+		return
+	}
+
+	fn(op)
+	for _, child := range op.Children {
+		child.Walk(fn)
+	}
+}
+
+// Find returns op and every operator beneath it whose name begins with operator, so that IndexScan matches
+// IndexScan2 and IndexScan3.
+func (op *QueryPlanOperator) Find(operator string) []*QueryPlanOperator {
+	var found []*QueryPlanOperator
+	op.Walk(func(o *QueryPlanOperator) {
+		if strings.HasPrefix(o.Operator, operator) {
+			found = append(found, o)
+		}
+	})
+
+	return found
+}
+
+// fullScan returns whether op reads an entire index.
+func (op *QueryPlanOperator) fullScan() bool {
+	if strings.HasPrefix(op.Operator, "PrimaryScan") {
+		return true
+	}
+
+	if !strings.HasPrefix(op.Operator, "IndexScan") {
+		return false
+	}
+
+	spans, ok := op.Properties["spans"].([]interface{})
+	if !ok || len(spans) == 0 {
+		return false
+	}
+
+	for _, span := range spans {
+		spanMap, _ := span.(map[string]interface{})
+		if !unboundedSpan(spanMap) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unboundedSpan returns whether an index span has no lower or upper bound. Older scans use a single Range object
+// while IndexScan3 uses a range array with an entry per index key.
+func unboundedSpan(span map[string]interface{}) bool {
+	var ranges []interface{}
+	if r, ok := span["Range"].(map[string]interface{}); ok {
+		ranges = append(ranges, r)
+	}
+	if r, ok := span["range"].([]interface{}); ok {
+		ranges = append(ranges, r...)
+	}
+	if len(ranges) == 0 {
+		return false
+	}
+
+	for _, rng := range ranges {
+		rangeMap, _ := rng.(map[string]interface{})
+		for key, bound := range rangeMap {
+			if !strings.EqualFold(key, "low") && !strings.EqualFold(key, "high") {
+				continue
+			}
+			if !unboundedValue(bound) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func unboundedValue(bound interface{}) bool {
+	switch b := bound.(type) {
+	case nil:
+		return true
+	case string:
+		return b == "" || b == "null"
+	case []interface{}:
+		for _, elem := range b {
+			if !unboundedValue(elem) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// QueryPlan is the plan chosen by the query service for a statement.
+type QueryPlan struct {
+	// Text is the statement as it was planned.
+	Text string
+	Root *QueryPlanOperator
+}
+
+// Operators returns every operator in the plan whose name begins with operator.
+func (p *QueryPlan) Operators(operator string) []*QueryPlanOperator {
+	return p.Root.Find(operator)
+}
+
+// PrimaryScans returns the operators which scan a primary index.
+func (p *QueryPlan) PrimaryScans() []*QueryPlanOperator {
+	return p.Operators("PrimaryScan")
+}
+
+// UsesPrimaryIndex returns whether the plan scans a primary index.
+func (p *QueryPlan) UsesPrimaryIndex() bool {
+	return len(p.PrimaryScans()) > 0
+}
+
+// FullScans returns the operators which read an entire index, either a primary index or a secondary index scanned
+// without a lower or upper bound.
+func (p *QueryPlan) FullScans() []*QueryPlanOperator {
+	var scans []*QueryPlanOperator
+	p.Root.Walk(func(op *QueryPlanOperator) {
+		if op.fullScan() {
+			scans = append(scans, op)
+		}
+	})
+
+	return scans
+}
+
+// HasFullScan returns whether the plan reads an entire index.
+func (p *QueryPlan) HasFullScan() bool {
+	return len(p.FullScans()) > 0
+}
+
+// UncoveredFetches returns the Fetch operators in the plan, each of which loads documents from the data service
+// because the index which was scanned does not cover the statement.
+func (p *QueryPlan) UncoveredFetches() []*QueryPlanOperator {
+	return p.Operators("Fetch")
+}
+
+// Covered returns whether the statement is answered by its indexes alone, without fetching any documents.
+func (p *QueryPlan) Covered() bool {
+	return len(p.UncoveredFetches()) == 0
+}
+
+// ExplainOptions is the set of options available to Cluster.Explain.
+type ExplainOptions struct {
+	Timeout time.Duration
+	Context context.Context
+}
+
+// Explain returns the plan that the query service would use to execute statement, without executing it.
+func (c *Cluster) Explain(statement string, opts *ExplainOptions) (*QueryPlan, error) {
+	if opts == nil {
+		opts = &ExplainOptions{}
+	}
+
+	results, err := c.Query("EXPLAIN "+statement, &QueryOptions{
+		Timeout: opts.Timeout,
+		Context: opts.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var explained struct {
+		Plan map[string]interface{} `json:"plan"`
+		Text string                 `json:"text"`
+	}
+	err = results.One(&explained)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryPlan{
+		Text: explained.Text,
+		Root: newQueryPlanOperator(explained.Plan),
+	}, nil
+}
+
+// QueryProfile is the profiling information returned for a query executed with QueryOptions.Profile.
+type QueryProfile struct {
+	// PhaseTimes is the time spent in each phase of execution, such as authorize, parse, plan and fetch.
+	PhaseTimes map[string]time.Duration
+	// PhaseCounts is the number of items processed by each phase.
+	PhaseCounts map[string]uint64
+	// PhaseOperators is the number of operators executing each phase.
+	PhaseOperators map[string]uint64
+	// ExecutionTimings is the executed plan along with the statistics of each operator, it is only set when
+	// profiling with QueryProfileTimings.
+	ExecutionTimings *QueryPlanOperator
+}
+
+type n1qlResponseProfile struct {
+	PhaseTimes       map[string]string      `json:"phaseTimes"`
+	PhaseCounts      map[string]uint64      `json:"phaseCounts"`
+	PhaseOperators   map[string]uint64      `json:"phaseOperators"`
+	ExecutionTimings map[string]interface{} `json:"executionTimings"`
+}
+
+func newQueryProfile(raw n1qlResponseProfile) *QueryProfile {
+	profile := &QueryProfile{
+		PhaseTimes:     make(map[string]time.Duration, len(raw.PhaseTimes)),
+		PhaseCounts:    raw.PhaseCounts,
+		PhaseOperators: raw.PhaseOperators,
+	}
+
+	for phase, t := range raw.PhaseTimes {
+		profile.PhaseTimes[phase] = parseQueryDuration(t)
+	}
+
+	if raw.ExecutionTimings != nil {
+		profile.ExecutionTimings = newQueryPlanOperator(raw.ExecutionTimings)
+	}
+
+	return profile
+}
@@ -0,0 +1,180 @@
+package gocb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func testGetClusterForResponses(t *testing.T, responses map[string]string) *Cluster {
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var body map[string]interface{}
+		err := json.Unmarshal(req.Body, &body)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+
+		respBody, ok := responses[body["statement"].(string)]
+		if !ok {
+			t.Fatalf("Unexpected statement %v", body["statement"])
+		}
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBufferString(respBody), nil},
+		}, nil
+	}
+
+	return testGetClusterForHTTP(&mockHTTPProvider{doFn: doHTTP}, time.Second, 0, 0)
+}
+
+func TestExplain(t *testing.T) {
+	cluster := testGetClusterForResponses(t, map[string]string{
+		"EXPLAIN SELECT * FROM airline WHERE name = $name": `{"results":[{"plan":{"#operator":"Sequence","~children":[` +
+			`{"#operator":"IndexScan3","index":"def_name","keyspace":"airline",` +
+			`"spans":[{"exact":true,"range":[{"high":"$name","inclusion":3,"low":"$name"}]}]},` +
+			`{"#operator":"Fetch","keyspace":"airline"}]},"text":"SELECT * FROM airline WHERE name = $name"}],` +
+			`"status":"success"}`,
+		"EXPLAIN SELECT * FROM route": `{"results":[{"plan":{"#operator":"Sequence","~children":[` +
+			`{"#operator":"PrimaryScan3","index":"#primary","keyspace":"route"},` +
+			`{"#operator":"Parallel","~child":{"#operator":"Sequence","~children":[` +
+			`{"#operator":"Fetch","keyspace":"route"},{"#operator":"InitialProject"}]}}]}}],"status":"success"}`,
+		"EXPLAIN SELECT name FROM hotel": `{"results":[{"plan":{"#operator":"Sequence","~children":[` +
+			`{"#operator":"IndexScan3","covers":["cover ((hotel.name))","cover ((meta(hotel).id))"],` +
+			`"index":"def_name","keyspace":"hotel","spans":[{"range":[{"inclusion":0,"low":"null"}]}]},` +
+			`{"#operator":"InitialProject"}]}}],"status":"success"}`,
+	})
+
+	plan, err := cluster.Explain("SELECT * FROM airline WHERE name = $name", nil)
+	if err != nil {
+		t.Fatalf("Expected explain to succeed but was %v", err)
+	}
+
+	if plan.Text != "SELECT * FROM airline WHERE name = $name" {
+		t.Fatalf("Expected plan text to be the statement but was %s", plan.Text)
+	}
+
+	if plan.Root.Operator != "Sequence" || len(plan.Root.Children) != 2 {
+		t.Fatalf("Expected root to be a sequence of 2 operators but was %+v", plan.Root)
+	}
+
+	scans := plan.Operators("IndexScan")
+	if len(scans) != 1 || scans[0].Index != "def_name" || scans[0].Keyspace != "airline" {
+		t.Fatalf("Expected an index scan of def_name but was %+v", scans)
+	}
+
+	if plan.UsesPrimaryIndex() || plan.HasFullScan() {
+		t.Fatalf("Expected bounded index scan not to be a full scan")
+	}
+
+	if plan.Covered() || len(plan.UncoveredFetches()) != 1 {
+		t.Fatalf("Expected plan to fetch documents")
+	}
+
+	plan, err = cluster.Explain("SELECT * FROM route", nil)
+	if err != nil {
+		t.Fatalf("Expected explain to succeed but was %v", err)
+	}
+
+	if !plan.UsesPrimaryIndex() || len(plan.PrimaryScans()) != 1 || plan.PrimaryScans()[0].Keyspace != "route" {
+		t.Fatalf("Expected plan to scan the primary index of route")
+	}
+
+	if len(plan.FullScans()) != 1 {
+		t.Fatalf("Expected primary scan to be a full scan but was %+v", plan.FullScans())
+	}
+
+	if fetches := plan.UncoveredFetches(); len(fetches) != 1 || fetches[0].Keyspace != "route" {
+		t.Fatalf("Expected fetch beneath parallel operator to be found but was %+v", fetches)
+	}
+
+	plan, err = cluster.Explain("SELECT name FROM hotel", nil)
+	if err != nil {
+		t.Fatalf("Expected explain to succeed but was %v", err)
+	}
+
+	if plan.UsesPrimaryIndex() || !plan.HasFullScan() {
+		t.Fatalf("Expected unbounded index scan to be a full scan")
+	}
+
+	if !plan.Covered() || len(plan.Operators("IndexScan")[0].Covers) != 2 {
+		t.Fatalf("Expected plan to be covered")
+	}
+}
+
+func TestQueryProfile(t *testing.T) {
+	cluster := testGetClusterForResponses(t, map[string]string{
+		"SELECT * FROM route": `{"results":[{"id":1}],"status":"success","profile":{` +
+			`"phaseTimes":{"authorize":"1.5ms","fetch":"20ms","primaryScan":"2.25ms"},` +
+			`"phaseCounts":{"fetch":16,"primaryScan":16},"phaseOperators":{"fetch":1,"primaryScan":1},` +
+			`"executionTimings":{"#operator":"Sequence","#stats":{"#phaseSwitches":2,"execTime":"1.5µs"},"~children":[` +
+			`{"#operator":"PrimaryScan3","#stats":{"#itemsOut":16,"#phaseSwitches":67,"execTime":"2ms","kernTime":"1ms",` +
+			`"servTime":"250µs"},"keyspace":"route"},{"#operator":"Fetch","#stats":{"#itemsIn":16,"#itemsOut":16},` +
+			`"keyspace":"route"}]}}}`,
+		"SELECT 1": `{"results":[{"$1":1}],"status":"success"}`,
+	})
+
+	results, err := cluster.Query("SELECT * FROM route", &QueryOptions{Profile: QueryProfileTimings})
+	if err != nil {
+		t.Fatalf("Expected query to succeed but was %v", err)
+	}
+
+	var row interface{}
+	err = results.One(&row)
+	if err != nil {
+		t.Fatalf("Expected row but was %v", err)
+	}
+
+	metadata, err := results.Metadata()
+	if err != nil {
+		t.Fatalf("Expected metadata but was %v", err)
+	}
+
+	profile := metadata.Profile()
+	if profile == nil {
+		t.Fatalf("Expected profile to be set")
+	}
+
+	if profile.PhaseTimes["fetch"] != 20*time.Millisecond || profile.PhaseTimes["primaryScan"] != 2250*time.Microsecond {
+		t.Fatalf("Expected phase times to be parsed but were %v", profile.PhaseTimes)
+	}
+
+	if profile.PhaseCounts["fetch"] != 16 || profile.PhaseOperators["primaryScan"] != 1 {
+		t.Fatalf("Expected phase counts and operators to be set but were %v and %v", profile.PhaseCounts,
+			profile.PhaseOperators)
+	}
+
+	scans := profile.ExecutionTimings.Find("PrimaryScan")
+	if len(scans) != 1 {
+		t.Fatalf("Expected execution timings to contain a primary scan but was %+v", profile.ExecutionTimings)
+	}
+
+	stats := scans[0].Stats
+	if stats == nil || stats.ItemsOut != 16 || stats.PhaseSwitches != 67 || stats.ExecTime != 2*time.Millisecond ||
+		stats.KernTime != time.Millisecond || stats.ServTime != 250*time.Microsecond {
+		t.Fatalf("Expected primary scan stats to be parsed but were %+v", stats)
+	}
+
+	results, err = cluster.Query("SELECT 1", nil)
+	if err != nil {
+		t.Fatalf("Expected query to succeed but was %v", err)
+	}
+
+	err = results.One(&row)
+	if err != nil {
+		t.Fatalf("Expected row but was %v", err)
+	}
+
+	metadata, err = results.Metadata()
+	if err != nil {
+		t.Fatalf("Expected metadata but was %v", err)
+	}
+
+	if metadata.Profile() != nil {
+		t.Fatalf("Expected profile to be nil when not requested but was %+v", metadata.Profile())
+	}
+}
@@ -0,0 +1,201 @@
+package gocb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// QueryRegistry holds named N1QL statements which are loaded from .n1ql files, so that they can be validated
+// against the cluster when an application starts and then executed by name.
+type QueryRegistry struct {
+	cluster    *Cluster
+	statements map[string]string
+	names      []string
+}
+
+// LoadQueryRegistry loads every .n1ql file within fsys, use os.DirFS to load the files within a directory. Each
+// statement is named by its path without the extension, so queries/users/findByEmail.n1ql within os.DirFS("queries")
+// is named users/findByEmail. Each file must contain a single statement.
+func (c *Cluster) LoadQueryRegistry(fsys fs.FS) (*QueryRegistry, error) {
+	registry := &QueryRegistry{
+		cluster:    c,
+		statements: make(map[string]string),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || path.Ext(filePath) != ".n1ql" {
+			return nil
+		}
+
+		contents, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+
+		statement := strings.TrimSpace(string(contents))
+		statement = strings.TrimSpace(strings.TrimSuffix(statement, ";"))
+		if statement == "" {
+			return invalidArgumentsError{message: fmt.Sprintf("query file %s is empty", filePath)}
+		}
+
+		name := strings.TrimSuffix(filePath, ".n1ql")
+		registry.statements[name] = statement
+		registry.names = append(registry.names, name)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load query registry")
+	}
+
+	sort.Strings(registry.names)
+
+	return registry, nil
+}
+
+// Names returns the names of the statements in the registry, in sorted order.
+func (r *QueryRegistry) Names() []string {
+	names := make([]string, len(r.names))
+	copy(names, r.names)
+	return names
+}
+
+// Statement returns the statement with name, and whether it exists.
+func (r *QueryRegistry) Statement(name string) (string, bool) {
+	statement, ok := r.statements[name]
+	return statement, ok
+}
+
+// Query executes the statement with name as a prepared statement. params are bound to the statement and may be a
+// map of named parameters, a slice of positional parameters or a struct as accepted by QueryOptions.Parameters.
+func (r *QueryRegistry) Query(name string, params interface{}, opts *QueryOptions) (*QueryResults, error) {
+	statement, ok := r.statements[name]
+	if !ok {
+		return nil, invalidArgumentsError{message: fmt.Sprintf("query %s is not in the registry", name)}
+	}
+
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+
+	queryOpts := *opts
+	queryOpts.Prepared = true
+
+	if params != nil {
+		if opts.PositionalParameters != nil || opts.NamedParameters != nil || opts.Parameters != nil {
+			return nil, invalidArgumentsError{message: "params cannot be used along with parameters in opts"}
+		}
+
+		switch p := params.(type) {
+		case map[string]interface{}:
+			queryOpts.NamedParameters = p
+		case []interface{}:
+			queryOpts.PositionalParameters = p
+		default:
+			queryOpts.Parameters = p
+		}
+	}
+
+	return r.cluster.Query(statement, &queryOpts)
+}
+
+// QueryValidationIssue describes a problem with a statement in a QueryRegistry.
+type QueryValidationIssue struct {
+	Name      string
+	Statement string
+	Message   string
+}
+
+// QueryValidationResult is the outcome of validating the statements in a QueryRegistry.
+type QueryValidationResult struct {
+	// Errors are statements which the server could not plan, such as those with syntax errors.
+	Errors []QueryValidationIssue
+	// Warnings are statements which can be executed but are unlikely to perform well, such as those which have no
+	// suitable index or which scan a primary index.
+	Warnings []QueryValidationIssue
+}
+
+// Valid returns whether every statement could be planned.
+func (r *QueryValidationResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Validate runs EXPLAIN for every statement in the registry. Statements which cannot be planned are reported as
+// errors, those which have no suitable index or which would scan a primary index are reported as warnings. The
+// returned error is only set if the statements could not be explained, such as when the cluster cannot be reached.
+func (r *QueryRegistry) Validate(ctx context.Context) (*QueryValidationResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	result := &QueryValidationResult{}
+	for _, name := range r.names {
+		statement := r.statements[name]
+		issue := func(format string, args ...interface{}) QueryValidationIssue {
+			return QueryValidationIssue{Name: name, Statement: statement, Message: fmt.Sprintf(format, args...)}
+		}
+
+		plan, err := r.cluster.Explain(statement, &ExplainOptions{Context: ctx})
+		if err != nil {
+			qErr, ok := errors.Cause(err).(QueryError)
+			if !ok {
+				return nil, errors.Wrapf(err, "could not explain query %s", name)
+			}
+
+			if missingIndex(qErr) {
+				result.Warnings = append(result.Warnings, issue("no usable index: %s", qErr.Error()))
+			} else {
+				result.Errors = append(result.Errors, issue("%s", qErr.Error()))
+			}
+			continue
+		}
+
+		for _, keyspace := range primaryScanKeyspaces(plan) {
+			result.Warnings = append(result.Warnings, issue("plan scans the primary index of %s", keyspace))
+		}
+	}
+
+	return result, nil
+}
+
+// missingIndex returns whether a statement could not be planned because there is no index which it can use.
+func missingIndex(qErr QueryError) bool {
+	if IsIndexFailure(qErr) {
+		return true
+	}
+
+	for _, entry := range qErr.Errors() {
+		if entry.Code == 4000 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// primaryScanKeyspaces returns the keyspaces which plan scans using a primary index, in sorted order.
+func primaryScanKeyspaces(plan *QueryPlan) []string {
+	var keyspaces []string
+	for _, scan := range plan.PrimaryScans() {
+		keyspaces = append(keyspaces, scan.Keyspace)
+	}
+
+	sort.Strings(keyspaces)
+	unique := keyspaces[:0]
+	for i, keyspace := range keyspaces {
+		if i == 0 || keyspace != keyspaces[i-1] {
+			unique = append(unique, keyspace)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,179 @@
+package gocb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func TestLoadQueryRegistry(t *testing.T) {
+	cluster := testGetClusterForHTTP(&mockHTTPProvider{}, time.Second, 0, 0)
+
+	registry, err := cluster.LoadQueryRegistry(fstest.MapFS{
+		"airlines.n1ql":             {Data: []byte("SELECT * FROM airline;\n")},
+		"users/findByEmail.n1ql":    {Data: []byte("  SELECT * FROM users WHERE email = $email  ")},
+		"users/README.md":           {Data: []byte("not a query")},
+		"routes/byAirline.n1ql.bak": {Data: []byte("SELECT 1")},
+	})
+	if err != nil {
+		t.Fatalf("Expected registry to load but was %v", err)
+	}
+
+	names := registry.Names()
+	if len(names) != 2 || names[0] != "airlines" || names[1] != "users/findByEmail" {
+		t.Fatalf("Expected names to be airlines and users/findByEmail but were %v", names)
+	}
+
+	statement, ok := registry.Statement("airlines")
+	if !ok || statement != "SELECT * FROM airline" {
+		t.Fatalf("Expected statement to have its terminator removed but was %q", statement)
+	}
+
+	statement, ok = registry.Statement("users/findByEmail")
+	if !ok || statement != "SELECT * FROM users WHERE email = $email" {
+		t.Fatalf("Expected statement to be trimmed but was %q", statement)
+	}
+
+	if _, ok := registry.Statement("routes/byAirline"); ok {
+		t.Fatalf("Expected files without the .n1ql extension to be ignored")
+	}
+
+	_, err = cluster.LoadQueryRegistry(fstest.MapFS{
+		"empty.n1ql": {Data: []byte(" ;\n")},
+	})
+	if err == nil || !IsInvalidArgumentsError(err) || !strings.Contains(err.Error(), "empty.n1ql is empty") {
+		t.Fatalf("Expected empty file to be rejected but was %v", err)
+	}
+}
+
+func TestQueryRegistryValidate(t *testing.T) {
+	cluster := testGetClusterForResponses(t, map[string]string{
+		"EXPLAIN SELECT * FROM airline WHERE name = $name": `{"results":[{"plan":{"#operator":"Sequence","~children":[` +
+			`{"#operator":"IndexScan3","index":"def_name","keyspace":"airline"},{"#operator":"Fetch","keyspace":"airline"}]}}],` +
+			`"status":"success"}`,
+		"EXPLAIN SELECT * FROM route": `{"results":[{"plan":{"#operator":"Sequence","~children":[` +
+			`{"#operator":"PrimaryScan3","index":"#primary","keyspace":"route"},{"#operator":"Fetch","keyspace":"route"}]}}],` +
+			`"status":"success"}`,
+		"EXPLAIN SELECT * FROM hotel WHERE city = $city": `{"errors":[{"code":4000,` +
+			`"msg":"No index available on keyspace hotel that matches your query."}],"status":"errors"}`,
+		"EXPLAIN SELEC * FROM landmark": `{"errors":[{"code":3000,"msg":"syntax error - at SELEC"}],"status":"errors"}`,
+	})
+
+	registry, err := cluster.LoadQueryRegistry(fstest.MapFS{
+		"airlines.n1ql":  {Data: []byte("SELECT * FROM airline WHERE name = $name")},
+		"routes.n1ql":    {Data: []byte("SELECT * FROM route")},
+		"hotels.n1ql":    {Data: []byte("SELECT * FROM hotel WHERE city = $city")},
+		"landmarks.n1ql": {Data: []byte("SELEC * FROM landmark")},
+	})
+	if err != nil {
+		t.Fatalf("Expected registry to load but was %v", err)
+	}
+
+	result, err := registry.Validate(context.Background())
+	if err != nil {
+		t.Fatalf("Expected validate to succeed but was %v", err)
+	}
+
+	if result.Valid() {
+		t.Fatalf("Expected result to be invalid")
+	}
+
+	if len(result.Errors) != 1 || result.Errors[0].Name != "landmarks" ||
+		!strings.Contains(result.Errors[0].Message, "syntax error") {
+		t.Fatalf("Expected landmarks to have a syntax error but errors were %+v", result.Errors)
+	}
+
+	if len(result.Warnings) != 2 {
+		t.Fatalf("Expected 2 warnings but were %+v", result.Warnings)
+	}
+
+	if result.Warnings[0].Name != "hotels" || !strings.Contains(result.Warnings[0].Message, "no usable index") {
+		t.Fatalf("Expected hotels to have no usable index but was %+v", result.Warnings[0])
+	}
+
+	if result.Warnings[1].Name != "routes" || result.Warnings[1].Statement != "SELECT * FROM route" ||
+		result.Warnings[1].Message != "plan scans the primary index of route" {
+		t.Fatalf("Expected routes to scan the primary index but was %+v", result.Warnings[1])
+	}
+}
+
+func TestQueryRegistryQuery(t *testing.T) {
+	prepareBytes, err := loadRawTestDataset("query_enhanced_statement")
+	if err != nil {
+		t.Fatalf("Could not read test dataset: %v", err)
+	}
+
+	var executed map[string]interface{}
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var body map[string]interface{}
+		err := json.Unmarshal(req.Body, &body)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+
+		respBody := prepareBytes
+		if statement, _ := body["statement"].(string); !strings.HasPrefix(statement, "PREPARE ") {
+			executed = body
+			respBody = []byte(`{"results":[{"name":"Jane"}],"status":"success"}`)
+		}
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(respBody), nil},
+		}, nil
+	}
+
+	provider := &mockHTTPProvider{
+		doFn: doHTTP,
+		supportFn: func(capability gocbcore.ClusterCapability) bool {
+			return false
+		},
+	}
+
+	cluster := testGetClusterForHTTP(provider, time.Second, 0, 0)
+	registry, err := cluster.LoadQueryRegistry(fstest.MapFS{
+		"users/findByEmail.n1ql": {Data: []byte("SELECT name FROM users WHERE email = $email")},
+	})
+	if err != nil {
+		t.Fatalf("Expected registry to load but was %v", err)
+	}
+
+	results, err := registry.Query("users/findByEmail", map[string]interface{}{"email": "jane@example.com"}, nil)
+	if err != nil {
+		t.Fatalf("Expected query to succeed but was %v", err)
+	}
+
+	var row struct {
+		Name string `json:"name"`
+	}
+	err = results.One(&row)
+	if err != nil || row.Name != "Jane" {
+		t.Fatalf("Expected row to be Jane but was %+v, %v", row, err)
+	}
+
+	if executed["prepared"] != "[127.0.0.1:8091]32f2405d-5715-5915-b2b2-d2c557da4996" {
+		t.Fatalf("Expected query to be executed as a prepared statement but was %v", executed)
+	}
+
+	if executed["$email"] != "jane@example.com" {
+		t.Fatalf("Expected $email to be bound but was %v", executed["$email"])
+	}
+
+	_, err = registry.Query("users/missing", nil, nil)
+	if err == nil || !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected unknown query to fail but was %v", err)
+	}
+
+	_, err = registry.Query("users/findByEmail", map[string]interface{}{"email": "jane@example.com"},
+		&QueryOptions{PositionalParameters: []interface{}{1}})
+	if err == nil || !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected params along with parameters in opts to fail but was %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"time"
 
@@ -99,9 +100,10 @@ type SearchResultsMetadata struct {
 
 // SearchResults allows access to the results of a search query.
 type SearchResults struct {
-	metadata SearchResultsMetadata
-	err      error
-	facets   map[string]SearchResultFacet
+	metadata  SearchResultsMetadata
+	indexName string
+	err       error
+	facets    map[string]SearchResultFacet
 
 	httpStatus   int
 	streamResult *streamingResult
@@ -311,32 +313,34 @@ func (r *SearchResults) readAttribute(decoder *json.Decoder, t json.Token) (bool
 			return false, nil
 		}
 
-		var statusErrors []string
-		if statusError, ok := status.Errors.([]string); ok {
-			statusErrors = statusError
+		var errs []SearchError
+		if statusError, ok := status.Errors.([]interface{}); ok {
+			for _, v := range statusError {
+				msg, ok := v.(string)
+				if !ok {
+					return false, errors.New("could not parse errors")
+				}
+				errs = append(errs, newSearchError(r.httpStatus, "", msg))
+			}
 		} else if statusError, ok := status.Errors.(map[string]interface{}); ok {
+			// Errors keyed by partition are the failures of individual index partitions.
 			for k, v := range statusError {
 				msg, ok := v.(string)
 				if !ok {
 					return false, errors.New("could not parse errors")
 				}
-				statusErrors = append(statusErrors, fmt.Sprintf("%s-%s", k, msg))
+				errs = append(errs, newSearchError(r.httpStatus, k, msg))
 			}
 		} else {
 			return false, errors.New("could not parse errors")
 		}
 
-		if len(statusErrors) > 0 {
-			errs := make([]SearchError, len(statusErrors))
-			for i, err := range statusErrors {
-				errs[i] = searchError{
-					message: err,
-				}
-			}
+		if len(errs) > 0 {
 			r.err = searchMultiError{
 				errors:     errs,
 				endpoint:   r.metadata.sourceAddr,
 				httpStatus: r.httpStatus,
+				indexName:  r.indexName,
 			}
 		}
 	case "total_hits":
@@ -547,40 +551,45 @@ func (c *Cluster) executeSearchQuery(ctx context.Context, query jsonx.DelayedObj
 		if err != nil {
 			return nil, err
 		}
-		respErrs := []string{buf.String()}
-		var errs []SearchError
-		for _, err := range respErrs {
-			errs = append(errs, searchError{
-				message: err,
-			})
-		}
 		return nil, searchMultiError{
-			errors:     errs,
+			errors:     []SearchError{newSearchError(resp.StatusCode, "", searchErrorMessage(buf.Bytes()))},
 			endpoint:   epInfo.Host,
 			httpStatus: resp.StatusCode,
+			indexName:  qIndexName,
 		}
 	case 401:
 		// This goes against the FTS RFC but makes a better experience in Go
 		return nil, searchMultiError{
 			errors: []SearchError{
-				searchError{
-					message: "The requested consistency level could not be satisfied before the timeout was reached",
-				},
+				newSearchError(resp.StatusCode, "",
+					"The requested consistency level could not be satisfied before the timeout was reached"),
 			},
 			endpoint:   epInfo.Host,
 			httpStatus: resp.StatusCode,
+			indexName:  qIndexName,
 		}
 	}
 
 	if resp.StatusCode != 200 {
+		message := "An unknown error occurred"
+		if resp.Body != nil {
+			data, err := ioutil.ReadAll(resp.Body)
+			if err == nil && len(data) > 0 {
+				message = searchErrorMessage(data)
+			}
+			err = resp.Body.Close()
+			if err != nil {
+				logDebugf("Failed to close socket (%s)", err)
+			}
+		}
+
 		err = searchMultiError{
 			errors: []SearchError{
-				searchError{
-					message: "An unknown error occurred",
-				},
+				newSearchError(resp.StatusCode, "", message),
 			},
 			endpoint:   epInfo.Host,
 			httpStatus: resp.StatusCode,
+			indexName:  qIndexName,
 		}
 
 		return nil, err
@@ -590,6 +599,7 @@ func (c *Cluster) executeSearchQuery(ctx context.Context, query jsonx.DelayedObj
 		metadata: SearchResultsMetadata{
 			sourceAddr: epInfo.Host,
 		},
+		indexName:  qIndexName,
 		httpStatus: resp.StatusCode,
 	}
 
@@ -625,3 +635,16 @@ func (c *Cluster) executeSearchQuery(ctx context.Context, query jsonx.DelayedObj
 	}
 	return queryResults, nil
 }
+
+// searchErrorMessage returns the message of an error response from the search service, which is either a JSON
+// object containing the error or the error as plain text.
+func searchErrorMessage(data []byte) string {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &errResp) == nil && errResp.Error != "" {
+		return errResp.Error
+	}
+
+	return string(data)
+}
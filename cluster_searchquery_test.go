@@ -256,6 +256,51 @@ func TestSearchQueryRetries(t *testing.T) {
 	}
 }
 
+func TestSearchQueryRetriesTooManyRequests(t *testing.T) {
+	q := SearchQuery{
+		Name:  "test",
+		Query: NewMatchQuery("test"),
+	}
+	timeout := 60 * time.Second
+
+	var retries int
+
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		retries++
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8094",
+			StatusCode: 429,
+		}, nil
+	}
+
+	provider := &mockHTTPProvider{
+		doFn: doHTTP,
+	}
+
+	cluster := testGetClusterForHTTP(provider, timeout, 0, 0)
+	cluster.sb.SearchRetryBehavior = StandardDelayRetryBehavior(3, 1, 100*time.Millisecond, LinearDelayFunction)
+
+	_, err := cluster.SearchQuery(q, nil)
+	if err == nil {
+		t.Fatal("Expected query execution to error")
+	}
+
+	if retries != 3 {
+		t.Fatalf("Expected query to be retried 3 time but ws retried %d times", retries)
+	}
+
+	searchErrs, ok := err.(SearchErrors)
+	if !ok {
+		t.Fatalf("Expected error to be SearchErrors but was %v", err)
+	}
+
+	if searchErrs.IndexName() != "test" || searchErrs.HTTPStatus() != 429 || searchErrs.Endpoint() != "localhost:8094" {
+		t.Fatalf("Expected error to contain the server context but was %v %d %s", searchErrs.IndexName(),
+			searchErrs.HTTPStatus(), searchErrs.Endpoint())
+	}
+}
+
 func TestSearchQueryServerObjectError(t *testing.T) {
 	q := SearchQuery{
 		Name:  "test",
@@ -397,3 +442,64 @@ func TestSearchQuery401Error(t *testing.T) {
 		t.Fatalf("Expected result to be nil but was %v", res)
 	}
 }
+
+func TestSearchQueryErrorClassification(t *testing.T) {
+	q := SearchQuery{
+		Name:  "test",
+		Query: NewMatchQuery("test"),
+	}
+
+	var status int
+	var body string
+	provider := &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			return &gocbcore.HttpResponse{
+				Endpoint:   "http://localhost:8094",
+				StatusCode: status,
+				Body:       &testReadCloser{bytes.NewBufferString(body), nil},
+			}, nil
+		},
+	}
+	cluster := testGetClusterForHTTP(provider, 0, 0, time.Second)
+
+	status, body = 400, `{"error":"rest_index: Query, indexName: test, err: index not found","status":"fail"}`
+	_, err := cluster.SearchQuery(q, nil)
+	if !IsSearchIndexNotFound(err) || IsSearchInvalidQuery(err) {
+		t.Fatalf("Expected index not found error but was %v", err)
+	}
+
+	searchErrs := err.(SearchErrors)
+	if msg := searchErrs.Errors()[0].Message(); msg != "rest_index: Query, indexName: test, err: index not found" {
+		t.Fatalf("Expected message to be read from the error response but was %s", msg)
+	}
+
+	status, body = 400, "parse error"
+	_, err = cluster.SearchQuery(q, nil)
+	if !IsSearchInvalidQuery(err) {
+		t.Fatalf("Expected invalid query error but was %v", err)
+	}
+
+	status, body = 200, `{"status":{"total":2,"failed":1,"successful":1,`+
+		`"errors":{"test_1234_a":"context deadline exceeded"}},"total_hits":0}`
+	_, err = cluster.SearchQuery(q, nil)
+	if !IsSearchPartitionFailure(err) {
+		t.Fatalf("Expected partition failure but was %v", err)
+	}
+
+	entry := err.(SearchErrors).Errors()[0]
+	if entry.Partition() != "test_1234_a" || entry.Code() != SearchErrorPartitionFailure ||
+		entry.Error() != "test_1234_a-context deadline exceeded" {
+		t.Fatalf("Expected partition to be reported but was %s %d %s", entry.Partition(), entry.Code(), entry.Error())
+	}
+
+	if (searchMultiError{errors: []SearchError{newSearchError(429, "", "rate limited")}}).retryable() != true {
+		t.Fatalf("Expected too many requests errors to be retryable")
+	}
+
+	if (searchMultiError{errors: []SearchError{
+		newSearchError(429, "", "rate limited"),
+		newSearchError(429, "", "index not found"),
+	}}).retryable() {
+		t.Fatalf("Expected errors to not be retryable unless every error is")
+	}
+}
@@ -8,6 +8,12 @@ import (
 // Collection represents a single collection.
 type Collection struct {
 	sb stateBlock
+
+	interceptors []KvInterceptor
+	keyPrefix    string
+	nearCache    *nearCache
+	// openErr is returned by every operation when the collection could not be opened with its options.
+	openErr error
 }
 
 // CollectionOptions are the options available when opening a collection.
@@ -15,6 +21,12 @@ type CollectionOptions struct {
 	// Timeout specifies the amount of time to wait for the collection ID to be fetched.
 	Timeout time.Duration
 	Context context.Context
+	// Interceptors are invoked, in order, around every key-value request made by the collection.
+	Interceptors []KvInterceptor
+	// HotKeys enables sampling of the keys accessed through the collection, see Cluster.KvStats.
+	HotKeys *HotKeyOptions
+	// NearCache enables an in-process cache of documents fetched using Get, see NearCacheOptions.
+	NearCache *NearCacheOptions
 }
 
 func newCollection(scope *Scope, collectionName string, opts *CollectionOptions) *Collection {
@@ -23,10 +35,32 @@ func newCollection(scope *Scope, collectionName string, opts *CollectionOptions)
 	}
 
 	collection := &Collection{
-		sb: scope.stateBlock(),
+		sb:           scope.stateBlock(),
+		interceptors: opts.Interceptors,
 	}
 	collection.sb.CollectionName = collectionName
 
+	if opts.NearCache != nil {
+		cache, err := collection.sb.NearCaches.cacheFor(&collection.sb, *opts.NearCache)
+		if err != nil {
+			collection.openErr = err
+		} else {
+			collection.nearCache = cache
+			collection.interceptors = append(collection.interceptors[:len(collection.interceptors):len(collection.interceptors)],
+				collection.nearCache)
+		}
+	}
+
+	if opts.HotKeys != nil {
+		sampler, err := collection.sb.KvStats.samplerFor(&collection.sb, *opts.HotKeys)
+		if err != nil {
+			collection.openErr = err
+		} else {
+			// The sampler runs last so that its latencies exclude time spent in other interceptors.
+			collection.interceptors = append(collection.interceptors[:len(collection.interceptors):len(collection.interceptors)], sampler)
+		}
+	}
+
 	deadlinedCtx, cancel := collection.context(opts.Context, opts.Timeout)
 	if cancel != nil {
 		defer cancel()
@@ -52,15 +86,42 @@ func (c *Collection) clone() *Collection {
 }
 
 func (c *Collection) getKvProvider() (kvProvider, error) {
+	if c.openErr != nil {
+		return nil, c.openErr
+	}
+
 	cli := c.sb.getCachedClient()
 	agent, err := cli.getKvProvider()
 	if err != nil {
 		return nil, err
 	}
 
+	if len(c.interceptors) > 0 {
+		agent = &interceptedKvProvider{
+			kvProvider:   agent,
+			bucketName:   c.sb.BucketName,
+			interceptors: c.interceptors,
+		}
+	}
+
+	// The prefix is applied before the interceptors so that they see the full key.
+	if c.keyPrefix != "" {
+		agent = &prefixedKvProvider{
+			kvProvider: agent,
+			prefix:     c.keyPrefix,
+		}
+	}
+
 	return agent, nil
 }
 
+// kvServerAddresses returns the addresses of the kv nodes, indexed by their position within the vbucket map.
+// The addresses are read from the server list of the bucket configuration, which is fetched in the background, so
+// will be empty until it has been fetched.
+func (c *Collection) kvServerAddresses() []string {
+	return clientKvServerAddresses(c.sb.getCachedClient())
+}
+
 // Name returns the name of the collection.
 func (c *Collection) Name() string {
 	return c.sb.CollectionName
@@ -16,6 +16,8 @@ type CollectionBinary struct {
 type AppendOptions struct {
 	Timeout         time.Duration
 	Context         context.Context
+	PersistTo       uint
+	ReplicateTo     uint
 	DurabilityLevel DurabilityLevel
 }
 
@@ -25,18 +27,47 @@ func (c *CollectionBinary) Append(key string, val []byte, opts *AppendOptions) (
 		opts = &AppendOptions{}
 	}
 
+	if (opts.PersistTo != 0 || opts.ReplicateTo != 0) && !c.sb.clientStateBlock.UseMutationTokens {
+		return nil, configurationError{"cannot use observe based durability without mutation tokens"}
+	}
+
+	err := c.verifyObserveOptions(opts.PersistTo, opts.ReplicateTo, opts.DurabilityLevel)
+	if err != nil {
+		return nil, err
+	}
+
 	// Only update ctx if necessary, this means that the original ctx.Done() signal will be triggered as expected
 	ctx, cancel := c.context(opts.Context, opts.Timeout)
 	if cancel != nil {
 		defer cancel()
 	}
 
-	res, err := c.append(ctx, key, val, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.append(ctx, key, val, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
+		return res, nil
+	}
+	durabilityRes, err := c.durability(durabilitySettings{
+		ctx:            opts.Context,
+		key:            key,
+		cas:            res.Cas(),
+		mt:             res.MutationToken(),
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
+		forDelete:      false,
+		scopeName:      c.scopeName(),
+		collectionName: c.name(),
+	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *CollectionBinary) append(ctx context.Context, key string, val []byte, opts AppendOptions) (mutOut *MutationResult, errOut error) {
@@ -89,6 +120,8 @@ func (c *CollectionBinary) append(ctx context.Context, key string, val []byte, o
 type PrependOptions struct {
 	Timeout         time.Duration
 	Context         context.Context
+	PersistTo       uint
+	ReplicateTo     uint
 	DurabilityLevel DurabilityLevel
 }
 
@@ -98,18 +131,47 @@ func (c *CollectionBinary) Prepend(key string, val []byte, opts *PrependOptions)
 		opts = &PrependOptions{}
 	}
 
+	if (opts.PersistTo != 0 || opts.ReplicateTo != 0) && !c.sb.clientStateBlock.UseMutationTokens {
+		return nil, configurationError{"cannot use observe based durability without mutation tokens"}
+	}
+
+	err := c.verifyObserveOptions(opts.PersistTo, opts.ReplicateTo, opts.DurabilityLevel)
+	if err != nil {
+		return nil, err
+	}
+
 	// Only update ctx if necessary, this means that the original ctx.Done() signal will be triggered as expected
 	ctx, cancel := c.context(opts.Context, opts.Timeout)
 	if cancel != nil {
 		defer cancel()
 	}
 
-	res, err := c.prepend(ctx, key, val, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.prepend(ctx, key, val, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
+		return res, nil
+	}
+	durabilityRes, err := c.durability(durabilitySettings{
+		ctx:            opts.Context,
+		key:            key,
+		cas:            res.Cas(),
+		mt:             res.MutationToken(),
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
+		forDelete:      false,
+		scopeName:      c.scopeName(),
+		collectionName: c.name(),
+	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *CollectionBinary) prepend(ctx context.Context, key string, val []byte, opts PrependOptions) (mutOut *MutationResult, errOut error) {
@@ -162,14 +224,15 @@ func (c *CollectionBinary) prepend(ctx context.Context, key string, val []byte,
 type CounterOptions struct {
 	Timeout time.Duration
 	Context context.Context
-	// Expiration is the length of time in seconds that the document will be stored in Couchbase.
-	// A value of 0 will set the document to never expire.
-	Expiration uint32
+	// Expiration is when the document will expire, the zero value sets the document to never expire.
+	Expiration Expiry
 	// Initial, if non-negative, is the `initial` value to use for the document if it does not exist.
 	// If present, this is the value that will be returned by a successful operation.
 	Initial int64
 	// Delta is the value to use for incrementing/decrementing if Initial is not present.
 	Delta           uint64
+	PersistTo       uint
+	ReplicateTo     uint
 	DurabilityLevel DurabilityLevel
 }
 
@@ -181,18 +244,47 @@ func (c *CollectionBinary) Increment(key string, opts *CounterOptions) (countOut
 		opts = &CounterOptions{}
 	}
 
+	if (opts.PersistTo != 0 || opts.ReplicateTo != 0) && !c.sb.clientStateBlock.UseMutationTokens {
+		return nil, configurationError{"cannot use observe based durability without mutation tokens"}
+	}
+
+	err := c.verifyObserveOptions(opts.PersistTo, opts.ReplicateTo, opts.DurabilityLevel)
+	if err != nil {
+		return nil, err
+	}
+
 	// Only update ctx if necessary, this means that the original ctx.Done() signal will be triggered as expected
 	ctx, cancel := c.context(opts.Context, opts.Timeout)
 	if cancel != nil {
 		defer cancel()
 	}
 
-	res, err := c.increment(ctx, key, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.increment(ctx, key, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
+		return res, nil
+	}
+	durabilityRes, err := c.durability(durabilitySettings{
+		ctx:            opts.Context,
+		key:            key,
+		cas:            res.Cas(),
+		mt:             res.MutationToken(),
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
+		forDelete:      false,
+		scopeName:      c.scopeName(),
+		collectionName: c.name(),
+	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *CollectionBinary) increment(ctx context.Context, key string, opts CounterOptions) (countOut *CounterResult, errOut error) {
@@ -206,6 +298,11 @@ func (c *CollectionBinary) increment(ctx context.Context, key string, opts Count
 		return nil, err
 	}
 
+	expiry, err := opts.Expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	coerced, durabilityTimeout := c.durabilityTimeout(ctx, opts.DurabilityLevel)
 	if coerced {
 		var cancel context.CancelFunc
@@ -218,7 +315,7 @@ func (c *CollectionBinary) increment(ctx context.Context, key string, opts Count
 		Key:                    []byte(key),
 		Delta:                  opts.Delta,
 		Initial:                realInitial,
-		Expiry:                 opts.Expiration,
+		Expiry:                 expiry,
 		CollectionName:         c.name(),
 		ScopeName:              c.scopeName(),
 		DurabilityLevel:        gocbcore.DurabilityLevel(opts.DurabilityLevel),
@@ -261,18 +358,47 @@ func (c *CollectionBinary) Decrement(key string, opts *CounterOptions) (countOut
 		opts = &CounterOptions{}
 	}
 
+	if (opts.PersistTo != 0 || opts.ReplicateTo != 0) && !c.sb.clientStateBlock.UseMutationTokens {
+		return nil, configurationError{"cannot use observe based durability without mutation tokens"}
+	}
+
+	err := c.verifyObserveOptions(opts.PersistTo, opts.ReplicateTo, opts.DurabilityLevel)
+	if err != nil {
+		return nil, err
+	}
+
 	// Only update ctx if necessary, this means that the original ctx.Done() signal will be triggered as expected
 	ctx, cancel := c.context(opts.Context, opts.Timeout)
 	if cancel != nil {
 		defer cancel()
 	}
 
-	res, err := c.decrement(ctx, key, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.decrement(ctx, key, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	return res, nil
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
+		return res, nil
+	}
+	durabilityRes, err := c.durability(durabilitySettings{
+		ctx:            opts.Context,
+		key:            key,
+		cas:            res.Cas(),
+		mt:             res.MutationToken(),
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
+		forDelete:      false,
+		scopeName:      c.scopeName(),
+		collectionName: c.name(),
+	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *CollectionBinary) decrement(ctx context.Context, key string, opts CounterOptions) (countOut *CounterResult, errOut error) {
@@ -286,6 +412,11 @@ func (c *CollectionBinary) decrement(ctx context.Context, key string, opts Count
 		realInitial = uint64(opts.Initial)
 	}
 
+	expiry, err := opts.Expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	coerced, durabilityTimeout := c.durabilityTimeout(ctx, opts.DurabilityLevel)
 	if coerced {
 		var cancel context.CancelFunc
@@ -298,7 +429,7 @@ func (c *CollectionBinary) decrement(ctx context.Context, key string, opts Count
 		Key:                    []byte(key),
 		Delta:                  opts.Delta,
 		Initial:                realInitial,
-		Expiry:                 opts.Expiration,
+		Expiry:                 expiry,
 		CollectionName:         c.name(),
 		ScopeName:              c.scopeName(),
 		DurabilityLevel:        gocbcore.DurabilityLevel(opts.DurabilityLevel),
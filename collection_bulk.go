@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
 )
 
 type bulkOp struct {
@@ -24,11 +25,18 @@ func (op *bulkOp) cancel() bool {
 // You can create a bulk operation by instantiating one of the implementations of BulkOp,
 // such as GetOp, UpsertOp, ReplaceOp, and more.
 type BulkOp interface {
-	execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp)
+	execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+		durabilityTimeout uint16, signal chan BulkOp)
 	markError(err error)
 	cancel() bool
 }
 
+// bulkMutationOp is a BulkOp which mutates a document and so can be observed for durability.
+type bulkMutationOp interface {
+	BulkOp
+	mutationResult() *MutationResult
+}
+
 // BulkOpOptions are the set of options available when performing BulkOps using Do.
 type BulkOpOptions struct {
 	Timeout time.Duration
@@ -37,6 +45,13 @@ type BulkOpOptions struct {
 	// Transcoder is used to encode values for operations that perform mutations and to decode values for
 	// operations that fetch values. It does not apply to all BulkOp operations.
 	Transcoder Transcoder
+
+	// PersistTo, ReplicateTo and DurabilityLevel apply to the operations that perform mutations, excluding
+	// TouchOp. Observe based durability is checked once all operations have completed, with any operation
+	// failing to meet the requirements having its Err set.
+	PersistTo       uint
+	ReplicateTo     uint
+	DurabilityLevel DurabilityLevel
 }
 
 // Do execute one or more `BulkOp` items in parallel.
@@ -45,6 +60,15 @@ func (c *Collection) Do(ops []BulkOp, opts *BulkOpOptions) error {
 		opts = &BulkOpOptions{}
 	}
 
+	if (opts.PersistTo != 0 || opts.ReplicateTo != 0) && !c.sb.clientStateBlock.UseMutationTokens {
+		return configurationError{"cannot use observe based durability without mutation tokens"}
+	}
+
+	err := c.verifyObserveOptions(opts.PersistTo, opts.ReplicateTo, opts.DurabilityLevel)
+	if err != nil {
+		return err
+	}
+
 	if opts.Timeout == 0 {
 		// no operation level timeouts set, use cluster level
 		opts.Timeout = c.sb.KvTimeout * time.Duration(len(ops))
@@ -70,12 +94,44 @@ func (c *Collection) Do(ops []BulkOp, opts *BulkOpOptions) error {
 		return err
 	}
 
+	durabilityLevel, persistTo, replicateTo := c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo,
+		opts.ReplicateTo)
+
+	coerced, durabilityTimeout := c.durabilityTimeout(ctx, durabilityLevel)
+	if coerced {
+		var durabilityCancel context.CancelFunc
+		ctx, durabilityCancel = context.WithTimeout(ctx, time.Duration(durabilityTimeout)*time.Millisecond)
+		defer durabilityCancel()
+	}
+
+	err = c.executeBulk(ctx, agent, ops, opts.Transcoder, durabilityLevel, durabilityTimeout)
+	if err != nil {
+		return err
+	}
+
+	mode := durabilityModeFor(durabilityLevel, persistTo, replicateTo)
+	for _, item := range ops {
+		if mutOp, ok := item.(bulkMutationOp); ok {
+			if res := mutOp.mutationResult(); res != nil {
+				res.durabilityMode = mode
+			}
+		}
+	}
+
+	if persistTo == 0 && replicateTo == 0 {
+		return nil
+	}
+	return c.bulkDurability(opts.Context, ops, replicateTo, persistTo)
+}
+
+func (c *Collection) executeBulk(ctx context.Context, agent kvProvider, ops []BulkOp, transcoder Transcoder,
+	durabilityLevel DurabilityLevel, durabilityTimeout uint16) error {
 	// Make the channel big enough to hold all our ops in case
 	//   we get delayed inside execute (don't want to block the
 	//   individual op handlers when they dispatch their signal).
 	signal := make(chan BulkOp, len(ops))
 	for _, item := range ops {
-		item.execute(c, agent, opts.Transcoder, signal)
+		item.execute(c, agent, transcoder, durabilityLevel, durabilityTimeout, signal)
 	}
 	for range ops {
 		select {
@@ -98,6 +154,7 @@ func (c *Collection) Do(ops []BulkOp, opts *BulkOpOptions) error {
 			return timeoutError{}
 		}
 	}
+
 	return nil
 }
 
@@ -114,7 +171,8 @@ func (item *GetOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *GetOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *GetOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	op, err := provider.GetEx(gocbcore.GetOptions{
 		Key:            []byte(item.Key),
 		CollectionName: c.name(),
@@ -141,12 +199,54 @@ func (item *GetOp) execute(c *Collection, provider kvProvider, transcoder Transc
 	}
 }
 
+// GetMetaOp represents a type of `BulkOp` used for GetMeta operations. See BulkOp.
+type GetMetaOp struct {
+	bulkOp
+
+	Key           string
+	AccessDeleted bool
+	Result        *GetMetaResult
+	Err           error
+}
+
+func (item *GetMetaOp) markError(err error) {
+	item.Err = err
+}
+
+func (item *GetMetaOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
+	op, err := provider.LookupInEx(gocbcore.LookupInOptions{
+		Key:            []byte(item.Key),
+		Flags:          documentMetaFlags(item.AccessDeleted),
+		Ops:            []gocbcore.SubDocOp{documentMetaOp()},
+		CollectionName: c.name(),
+		ScopeName:      c.scopeName(),
+	}, func(res *gocbcore.LookupInResult, err error) {
+		if err != nil && !isLookupInPartialSuccess(err) {
+			item.Err = maybeEnhanceKVErr(err, item.Key, false)
+		} else if res == nil || len(res.Ops) != 1 {
+			item.Err = errors.New("invalid response for document metadata")
+		} else if res.Ops[0].Err != nil {
+			item.Err = maybeEnhanceKVErr(res.Ops[0].Err, item.Key, false)
+		} else {
+			item.Result, item.Err = newGetMetaResult(res.Ops[0].Value)
+		}
+		signal <- item
+	})
+	if err != nil {
+		item.Err = err
+		signal <- item
+	} else {
+		item.bulkOp.pendop = op
+	}
+}
+
 // GetAndTouchOp represents a type of `BulkOp` used for GetAndTouch operations. See BulkOp.
 type GetAndTouchOp struct {
 	bulkOp
 
 	Key    string
-	Expiry uint32
+	Expiry Expiry
 	Result *GetResult
 	Err    error
 }
@@ -155,10 +255,18 @@ func (item *GetAndTouchOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *GetAndTouchOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *GetAndTouchOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
+	expiry, err := item.Expiry.wire(time.Now())
+	if err != nil {
+		item.Err = err
+		signal <- item
+		return
+	}
+
 	op, err := provider.GetAndTouchEx(gocbcore.GetAndTouchOptions{
 		Key:            []byte(item.Key),
-		Expiry:         item.Expiry,
+		Expiry:         expiry,
 		CollectionName: c.name(),
 		ScopeName:      c.scopeName(),
 	}, func(res *gocbcore.GetAndTouchResult, err error) {
@@ -188,7 +296,7 @@ type TouchOp struct {
 	bulkOp
 
 	Key    string
-	Expiry uint32
+	Expiry Expiry
 	Result *MutationResult
 	Err    error
 }
@@ -197,10 +305,18 @@ func (item *TouchOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *TouchOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *TouchOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
+	expiry, err := item.Expiry.wire(time.Now())
+	if err != nil {
+		item.Err = err
+		signal <- item
+		return
+	}
+
 	op, err := provider.TouchEx(gocbcore.TouchOptions{
 		Key:            []byte(item.Key),
-		Expiry:         item.Expiry,
+		Expiry:         expiry,
 		CollectionName: c.name(),
 		ScopeName:      c.scopeName(),
 	}, func(res *gocbcore.TouchResult, err error) {
@@ -241,12 +357,22 @@ func (item *RemoveOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *RemoveOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *RemoveOp) mutationResult() *MutationResult {
+	if item.Err != nil {
+		return nil
+	}
+	return item.Result
+}
+
+func (item *RemoveOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	op, err := provider.DeleteEx(gocbcore.DeleteOptions{
-		Key:            []byte(item.Key),
-		Cas:            gocbcore.Cas(item.Cas),
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Cas:                    gocbcore.Cas(item.Cas),
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.DeleteResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, false)
 		if item.Err == nil {
@@ -277,7 +403,7 @@ type UpsertOp struct {
 
 	Key    string
 	Value  interface{}
-	Expiry uint32
+	Expiry Expiry
 	Cas    Cas
 	Result *MutationResult
 	Err    error
@@ -287,7 +413,15 @@ func (item *UpsertOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *UpsertOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *UpsertOp) mutationResult() *MutationResult {
+	if item.Err != nil {
+		return nil
+	}
+	return item.Result
+}
+
+func (item *UpsertOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	bytes, flags, err := transcoder.Encode(item.Value)
 	if err != nil {
 		item.Err = err
@@ -295,13 +429,22 @@ func (item *UpsertOp) execute(c *Collection, provider kvProvider, transcoder Tra
 		return
 	}
 
+	expiry, err := item.Expiry.wire(time.Now())
+	if err != nil {
+		item.Err = err
+		signal <- item
+		return
+	}
+
 	op, err := provider.SetEx(gocbcore.SetOptions{
-		Key:            []byte(item.Key),
-		Value:          bytes,
-		Flags:          flags,
-		Expiry:         item.Expiry,
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Value:                  bytes,
+		Flags:                  flags,
+		Expiry:                 expiry,
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.StoreResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, false)
 		if item.Err == nil {
@@ -332,7 +475,7 @@ type InsertOp struct {
 
 	Key    string
 	Value  interface{}
-	Expiry uint32
+	Expiry Expiry
 	Result *MutationResult
 	Err    error
 }
@@ -341,7 +484,15 @@ func (item *InsertOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *InsertOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *InsertOp) mutationResult() *MutationResult {
+	if item.Err != nil {
+		return nil
+	}
+	return item.Result
+}
+
+func (item *InsertOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	bytes, flags, err := transcoder.Encode(item.Value)
 	if err != nil {
 		item.Err = err
@@ -349,13 +500,22 @@ func (item *InsertOp) execute(c *Collection, provider kvProvider, transcoder Tra
 		return
 	}
 
+	expiry, err := item.Expiry.wire(time.Now())
+	if err != nil {
+		item.Err = err
+		signal <- item
+		return
+	}
+
 	op, err := provider.AddEx(gocbcore.AddOptions{
-		Key:            []byte(item.Key),
-		Value:          bytes,
-		Flags:          flags,
-		Expiry:         item.Expiry,
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Value:                  bytes,
+		Flags:                  flags,
+		Expiry:                 expiry,
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.StoreResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, true)
 		if item.Err == nil {
@@ -386,7 +546,7 @@ type ReplaceOp struct {
 
 	Key    string
 	Value  interface{}
-	Expiry uint32
+	Expiry Expiry
 	Cas    Cas
 	Result *MutationResult
 	Err    error
@@ -396,7 +556,15 @@ func (item *ReplaceOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *ReplaceOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *ReplaceOp) mutationResult() *MutationResult {
+	if item.Err != nil {
+		return nil
+	}
+	return item.Result
+}
+
+func (item *ReplaceOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	bytes, flags, err := transcoder.Encode(item.Value)
 	if err != nil {
 		item.Err = err
@@ -404,14 +572,23 @@ func (item *ReplaceOp) execute(c *Collection, provider kvProvider, transcoder Tr
 		return
 	}
 
+	expiry, err := item.Expiry.wire(time.Now())
+	if err != nil {
+		item.Err = err
+		signal <- item
+		return
+	}
+
 	op, err := provider.ReplaceEx(gocbcore.ReplaceOptions{
-		Key:            []byte(item.Key),
-		Value:          bytes,
-		Flags:          flags,
-		Cas:            gocbcore.Cas(item.Cas),
-		Expiry:         item.Expiry,
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Value:                  bytes,
+		Flags:                  flags,
+		Cas:                    gocbcore.Cas(item.Cas),
+		Expiry:                 expiry,
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.StoreResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, true)
 		if item.Err == nil {
@@ -450,12 +627,22 @@ func (item *AppendOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *AppendOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *AppendOp) mutationResult() *MutationResult {
+	if item.Err != nil {
+		return nil
+	}
+	return item.Result
+}
+
+func (item *AppendOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	op, err := provider.AppendEx(gocbcore.AdjoinOptions{
-		Key:            []byte(item.Key),
-		Value:          []byte(item.Value),
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Value:                  []byte(item.Value),
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.AdjoinResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, true)
 		if item.Err == nil {
@@ -494,12 +681,22 @@ func (item *PrependOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *PrependOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *PrependOp) mutationResult() *MutationResult {
+	if item.Err != nil {
+		return nil
+	}
+	return item.Result
+}
+
+func (item *PrependOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	op, err := provider.PrependEx(gocbcore.AdjoinOptions{
-		Key:            []byte(item.Key),
-		Value:          []byte(item.Value),
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Value:                  []byte(item.Value),
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.AdjoinResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, true)
 		if item.Err == nil {
@@ -531,7 +728,7 @@ type IncrementOp struct {
 	Key     string
 	Delta   int64
 	Initial int64
-	Expiry  uint32
+	Expiry  Expiry
 
 	Result *CounterResult
 	Err    error
@@ -541,19 +738,36 @@ func (item *IncrementOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *IncrementOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *IncrementOp) mutationResult() *MutationResult {
+	if item.Err != nil || item.Result == nil {
+		return nil
+	}
+	return &item.Result.MutationResult
+}
+
+func (item *IncrementOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	realInitial := uint64(0xFFFFFFFFFFFFFFFF)
 	if item.Initial > 0 {
 		realInitial = uint64(item.Initial)
 	}
 
+	expiry, err := item.Expiry.wire(time.Now())
+	if err != nil {
+		item.Err = err
+		signal <- item
+		return
+	}
+
 	op, err := provider.IncrementEx(gocbcore.CounterOptions{
-		Key:            []byte(item.Key),
-		Delta:          uint64(item.Delta),
-		Initial:        realInitial,
-		Expiry:         item.Expiry,
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Delta:                  uint64(item.Delta),
+		Initial:                realInitial,
+		Expiry:                 expiry,
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.CounterResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, true)
 		if item.Err == nil {
@@ -588,7 +802,7 @@ type DecrementOp struct {
 	Key     string
 	Delta   int64
 	Initial int64
-	Expiry  uint32
+	Expiry  Expiry
 
 	Result *CounterResult
 	Err    error
@@ -598,19 +812,36 @@ func (item *DecrementOp) markError(err error) {
 	item.Err = err
 }
 
-func (item *DecrementOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, signal chan BulkOp) {
+func (item *DecrementOp) mutationResult() *MutationResult {
+	if item.Err != nil || item.Result == nil {
+		return nil
+	}
+	return &item.Result.MutationResult
+}
+
+func (item *DecrementOp) execute(c *Collection, provider kvProvider, transcoder Transcoder, durabilityLevel DurabilityLevel,
+	durabilityTimeout uint16, signal chan BulkOp) {
 	realInitial := uint64(0xFFFFFFFFFFFFFFFF)
 	if item.Initial > 0 {
 		realInitial = uint64(item.Initial)
 	}
 
+	expiry, err := item.Expiry.wire(time.Now())
+	if err != nil {
+		item.Err = err
+		signal <- item
+		return
+	}
+
 	op, err := provider.DecrementEx(gocbcore.CounterOptions{
-		Key:            []byte(item.Key),
-		Delta:          uint64(item.Delta),
-		Initial:        realInitial,
-		Expiry:         item.Expiry,
-		CollectionName: c.name(),
-		ScopeName:      c.scopeName(),
+		Key:                    []byte(item.Key),
+		Delta:                  uint64(item.Delta),
+		Initial:                realInitial,
+		Expiry:                 expiry,
+		CollectionName:         c.name(),
+		ScopeName:              c.scopeName(),
+		DurabilityLevel:        gocbcore.DurabilityLevel(durabilityLevel),
+		DurabilityLevelTimeout: durabilityTimeout,
 	}, func(res *gocbcore.CounterResult, err error) {
 		item.Err = maybeEnhanceKVErr(err, item.Key, true)
 		if item.Err == nil {
@@ -2,7 +2,11 @@ package gocb
 
 import (
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
 )
 
 func TestUpsertGetBulk(t *testing.T) {
@@ -11,7 +15,7 @@ func TestUpsertGetBulk(t *testing.T) {
 		ops = append(ops, &UpsertOp{
 			Key:    fmt.Sprintf("%d", i),
 			Value:  "test",
-			Expiry: 20,
+			Expiry: ExpiryIn(20 * time.Second),
 		})
 	}
 
@@ -72,3 +76,87 @@ func TestUpsertGetBulk(t *testing.T) {
 		}
 	}
 }
+
+func TestBulkObserveDurabilityBatchedPerVbucket(t *testing.T) {
+	provider := &mockKvProvider{
+		cas: gocbcore.Cas(1),
+		mt: gocbcore.MutationToken{
+			VbId:   12,
+			VbUuid: 1234,
+			SeqNo:  5,
+		},
+		numReplicas: 1,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.UseMutationTokens = true
+	col.sb.DuraTimeout = 1000 * time.Millisecond
+	col.sb.DuraPollTimeout = 10 * time.Millisecond
+
+	var ops []BulkOp
+	for i := 0; i < 20; i++ {
+		ops = append(ops, &UpsertOp{
+			Key:   fmt.Sprintf("%d", i),
+			Value: "test",
+		})
+	}
+
+	err := col.Do(ops, &BulkOpOptions{ReplicateTo: 1, PersistTo: 2})
+	if err != nil {
+		t.Fatalf("Expected Do to not error for upserts %v", err)
+	}
+
+	for _, op := range ops {
+		if op.(*UpsertOp).Err != nil {
+			t.Fatalf("Expected UpsertOp Err to be nil but was %v", op.(*UpsertOp).Err)
+		}
+	}
+
+	// All of the mutations are within the same vbucket so there should be a single observe per node.
+	if count := atomic.LoadUint32(&provider.observeVbCount); count != 2 {
+		t.Fatalf("Expected 2 observe requests but was %d", count)
+	}
+}
+
+func TestBulkObserveDurabilityNotEnoughReplicas(t *testing.T) {
+	provider := &mockKvProvider{
+		cas: gocbcore.Cas(1),
+	}
+	col := testGetCollection(t, provider)
+	col.sb.UseMutationTokens = true
+	col.sb.DuraTimeout = 1000 * time.Millisecond
+
+	ops := []BulkOp{
+		&UpsertOp{Key: "bulkNotEnoughReplicas", Value: "test"},
+		&TouchOp{Key: "bulkNotEnoughReplicas"},
+	}
+	err := col.Do(ops, &BulkOpOptions{ReplicateTo: 1})
+	if err != nil {
+		t.Fatalf("Expected Do to not error %v", err)
+	}
+
+	if !IsDurabilityError(ops[0].(*UpsertOp).Err) {
+		t.Fatalf("Expected UpsertOp Err to be a durability error but was %v", ops[0].(*UpsertOp).Err)
+	}
+
+	if ops[1].(*TouchOp).Err != nil {
+		t.Fatalf("Expected TouchOp Err to be nil but was %v", ops[1].(*TouchOp).Err)
+	}
+}
+
+func TestBulkObserveDurabilityWithoutMutationTokens(t *testing.T) {
+	col := testGetCollection(t, &mockKvProvider{})
+
+	err := col.Do([]BulkOp{&UpsertOp{Key: "bulkNoTokens", Value: "test"}}, &BulkOpOptions{PersistTo: 1})
+	if !IsConfigurationError(err) {
+		t.Fatalf("Expected Do to return a configuration error but was %v", err)
+	}
+
+	col.sb.UseMutationTokens = true
+	err = col.Do([]BulkOp{&UpsertOp{Key: "bulkNoTokens", Value: "test"}}, &BulkOpOptions{
+		PersistTo:       1,
+		DurabilityLevel: DurabilityLevelMajority,
+	})
+	if !IsConfigurationError(err) {
+		t.Fatalf("Expected Do to return a configuration error but was %v", err)
+	}
+}
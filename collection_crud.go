@@ -29,6 +29,10 @@ type kvProvider interface {
 	PrependEx(opts gocbcore.AdjoinOptions, cb gocbcore.AdjoinExCallback) (gocbcore.PendingOp, error)
 	PingKvEx(opts gocbcore.PingKvOptions, cb gocbcore.PingKvExCallback) (gocbcore.PendingOp, error)
 	NumReplicas() int
+	NumVbuckets() int
+	NumServers() int
+	KeyToVbucket(key []byte) uint16
+	VbucketToServer(vbID uint16, replicaIdx uint32) int
 }
 
 func (c *Collection) context(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
@@ -121,8 +125,8 @@ func (c *Collection) verifyObserveOptions(persistTo, replicateTo uint, durabilit
 type UpsertOptions struct {
 	Timeout time.Duration
 	Context context.Context
-	// The expiration length in seconds
-	Expiration      uint32
+	// Expiration is when the document will expire, the zero value sets the document to never expire.
+	Expiration      Expiry
 	PersistTo       uint
 	ReplicateTo     uint
 	DurabilityLevel DurabilityLevel
@@ -133,8 +137,8 @@ type UpsertOptions struct {
 type InsertOptions struct {
 	Timeout time.Duration
 	Context context.Context
-	// The expiration length in seconds
-	Expiration      uint32
+	// Expiration is when the document will expire, the zero value sets the document to never expire.
+	Expiration      Expiry
 	PersistTo       uint
 	ReplicateTo     uint
 	DurabilityLevel DurabilityLevel
@@ -161,25 +165,32 @@ func (c *Collection) Insert(key string, val interface{}, opts *InsertOptions) (m
 		return nil, err
 	}
 
-	res, err := c.insert(ctx, key, val, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.insert(ctx, key, val, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.PersistTo == 0 && opts.ReplicateTo == 0 {
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
 		return res, nil
 	}
-	return res, c.durability(durabilitySettings{
+	durabilityRes, err := c.durability(durabilitySettings{
 		ctx:            opts.Context,
 		key:            key,
 		cas:            res.Cas(),
 		mt:             res.MutationToken(),
-		replicaTo:      opts.ReplicateTo,
-		persistTo:      opts.PersistTo,
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
 		forDelete:      false,
 		scopeName:      c.scopeName(),
 		collectionName: c.name(),
 	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *Collection) insert(ctx context.Context, key string, val interface{}, opts InsertOptions) (mutOut *MutationResult, errOut error) {
@@ -200,6 +211,11 @@ func (c *Collection) insert(ctx context.Context, key string, val interface{}, op
 		return
 	}
 
+	expiry, err := opts.Expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	coerced, durabilityTimeout := c.durabilityTimeout(ctx, opts.DurabilityLevel)
 	if coerced {
 		var cancel context.CancelFunc
@@ -212,7 +228,7 @@ func (c *Collection) insert(ctx context.Context, key string, val interface{}, op
 		Key:                    []byte(key),
 		Value:                  bytes,
 		Flags:                  flags,
-		Expiry:                 opts.Expiration,
+		Expiry:                 expiry,
 		CollectionName:         c.name(),
 		ScopeName:              c.scopeName(),
 		DurabilityLevel:        gocbcore.DurabilityLevel(opts.DurabilityLevel),
@@ -262,25 +278,32 @@ func (c *Collection) Upsert(key string, val interface{}, opts *UpsertOptions) (m
 		return nil, err
 	}
 
-	res, err := c.upsert(ctx, key, val, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.upsert(ctx, key, val, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.PersistTo == 0 && opts.ReplicateTo == 0 {
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
 		return res, nil
 	}
-	return res, c.durability(durabilitySettings{
+	durabilityRes, err := c.durability(durabilitySettings{
 		ctx:            opts.Context,
 		key:            key,
 		cas:            res.Cas(),
 		mt:             res.MutationToken(),
-		replicaTo:      opts.ReplicateTo,
-		persistTo:      opts.PersistTo,
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
 		forDelete:      false,
 		scopeName:      c.scopeName(),
 		collectionName: c.name(),
 	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *Collection) upsert(ctx context.Context, key string, val interface{}, opts UpsertOptions) (mutOut *MutationResult, errOut error) {
@@ -301,6 +324,11 @@ func (c *Collection) upsert(ctx context.Context, key string, val interface{}, op
 		return
 	}
 
+	expiry, err := opts.Expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	coerced, durabilityTimeout := c.durabilityTimeout(ctx, opts.DurabilityLevel)
 	if coerced {
 		var cancel context.CancelFunc
@@ -313,7 +341,7 @@ func (c *Collection) upsert(ctx context.Context, key string, val interface{}, op
 		Key:                    []byte(key),
 		Value:                  bytes,
 		Flags:                  flags,
-		Expiry:                 opts.Expiration,
+		Expiry:                 expiry,
 		CollectionName:         c.name(),
 		ScopeName:              c.scopeName(),
 		DurabilityLevel:        gocbcore.DurabilityLevel(opts.DurabilityLevel),
@@ -347,7 +375,7 @@ func (c *Collection) upsert(ctx context.Context, key string, val interface{}, op
 type ReplaceOptions struct {
 	Timeout         time.Duration
 	Context         context.Context
-	Expiration      uint32
+	Expiration      Expiry
 	Cas             Cas
 	PersistTo       uint
 	ReplicateTo     uint
@@ -375,25 +403,32 @@ func (c *Collection) Replace(key string, val interface{}, opts *ReplaceOptions)
 		return nil, err
 	}
 
-	res, err := c.replace(ctx, key, val, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.replace(ctx, key, val, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.PersistTo == 0 && opts.ReplicateTo == 0 {
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
 		return res, nil
 	}
-	return res, c.durability(durabilitySettings{
+	durabilityRes, err := c.durability(durabilitySettings{
 		ctx:            opts.Context,
 		key:            key,
 		cas:            res.Cas(),
 		mt:             res.MutationToken(),
-		replicaTo:      opts.ReplicateTo,
-		persistTo:      opts.PersistTo,
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
 		forDelete:      false,
 		scopeName:      c.scopeName(),
 		collectionName: c.name(),
 	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *Collection) replace(ctx context.Context, key string, val interface{}, opts ReplaceOptions) (mutOut *MutationResult, errOut error) {
@@ -413,6 +448,11 @@ func (c *Collection) replace(ctx context.Context, key string, val interface{}, o
 		return
 	}
 
+	expiry, err := opts.Expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	coerced, durabilityTimeout := c.durabilityTimeout(ctx, opts.DurabilityLevel)
 	if coerced {
 		var cancel context.CancelFunc
@@ -425,7 +465,7 @@ func (c *Collection) replace(ctx context.Context, key string, val interface{}, o
 		Key:                    []byte(key),
 		Value:                  bytes,
 		Flags:                  flags,
-		Expiry:                 opts.Expiration,
+		Expiry:                 expiry,
 		Cas:                    gocbcore.Cas(opts.Cas),
 		CollectionName:         c.name(),
 		ScopeName:              c.scopeName(),
@@ -466,6 +506,12 @@ type GetOptions struct {
 	// standard GetResult.
 	Project    *ProjectOptions
 	Transcoder Transcoder
+	// ReplicaFallback causes the Get operation to read the document from a replica when the active copy is slow
+	// or unreachable. It cannot be used alongside Project or WithExpiry.
+	ReplicaFallback *ReplicaFallbackOptions
+	// BypassNearCache causes the Get operation to fetch the document from the server even when it is held in the
+	// near cache of the collection, the cached entry is then replaced.
+	BypassNearCache bool
 }
 
 // ProjectOptions are the options for using projections as a part of a Get request.
@@ -491,8 +537,20 @@ func (c *Collection) Get(key string, opts *GetOptions) (docOut *GetResult, errOu
 		opts.Transcoder = c.sb.Transcoder
 	}
 
+	if opts.ReplicaFallback != nil && opts.ReplicaFallback.Policy != ReplicaFallbackNone {
+		if opts.Project != nil || opts.WithExpiry {
+			return nil, invalidArgumentsError{message: "ReplicaFallback cannot be used with Project or WithExpiry"}
+		}
+
+		return c.getWithReplicaFallback(ctx, key, opts)
+	}
+
 	if (opts.Project == nil || (opts.Project != nil && len(opts.Project.Fields) > 16)) && !opts.WithExpiry {
 		// Standard fulldoc
+		if c.nearCache != nil && opts.Project == nil {
+			return c.getNearCached(ctx, key, opts)
+		}
+
 		doc, err := c.get(ctx, key, opts)
 		if err != nil {
 			return nil, err
@@ -639,6 +697,16 @@ type GetFromReplicaOptions struct {
 	Timeout    time.Duration
 	Context    context.Context
 	Transcoder Transcoder
+	// ConsistentWith ensures that a copy of the document is only read once that copy has seen the mutations
+	// within the MutationState. GetAnyReplica waits for a copy to catch up whilst GetAllReplicas skips any
+	// copies which are behind.
+	ConsistentWith *MutationState
+	// ConsistencyPollInterval is how often GetAnyReplica observes the copies whilst waiting for one to catch up
+	// with ConsistentWith, defaults to 50ms.
+	ConsistencyPollInterval time.Duration
+	// ConsistencyTimeout is how long GetAnyReplica waits for a copy to catch up with ConsistentWith before
+	// returning a ReplicaConsistencyError, defaults to the operation timeout.
+	ConsistencyTimeout time.Duration
 }
 
 // GetAnyReplica returns the value of a particular document from a replica server.
@@ -671,6 +739,10 @@ func (c *Collection) getAnyReplica(ctx context.Context, key string,
 		opts.Transcoder = c.sb.Transcoder
 	}
 
+	if consistency := c.replicaConsistencyFor(agent, key, opts.ConsistentWith); consistency != nil {
+		return c.getAnyConsistentReplica(ctx, agent, key, consistency, opts)
+	}
+
 	ctrl := c.newOpManager(ctx)
 	err = ctrl.wait(agent.GetAnyReplicaEx(gocbcore.GetAnyReplicaOptions{
 		Key:            []byte(key),
@@ -705,7 +777,8 @@ func (c *Collection) getAnyReplica(ctx context.Context, key string,
 }
 
 // GetAllReplicas returns the value of a particular document from all replica servers. This will return an iterable
-// which streams results one at a time.
+// which streams results one at a time. See StreamReplicas for reading every copy in parallel whilst reporting
+// failures to read individual copies.
 func (c *Collection) GetAllReplicas(key string, opts *GetFromReplicaOptions) (docOut *GetAllReplicasResult, errOut error) {
 	if opts == nil {
 		opts = &GetFromReplicaOptions{}
@@ -732,6 +805,7 @@ func (c *Collection) GetAllReplicas(key string, opts *GetFromReplicaOptions) (do
 		provider:    agent,
 		cancel:      cancel,
 		maxReplicas: agent.NumReplicas(),
+		consistency: c.replicaConsistencyFor(agent, key, opts.ConsistentWith),
 	}, nil
 }
 
@@ -765,25 +839,32 @@ func (c *Collection) Remove(key string, opts *RemoveOptions) (mutOut *MutationRe
 		return nil, err
 	}
 
-	res, err := c.remove(ctx, key, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.remove(ctx, key, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.PersistTo == 0 && opts.ReplicateTo == 0 {
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
 		return res, nil
 	}
-	return res, c.durability(durabilitySettings{
+	durabilityRes, err := c.durability(durabilitySettings{
 		ctx:            opts.Context,
 		key:            key,
 		cas:            res.Cas(),
 		mt:             res.MutationToken(),
-		replicaTo:      opts.ReplicateTo,
-		persistTo:      opts.PersistTo,
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
 		forDelete:      true,
 		scopeName:      c.scopeName(),
 		collectionName: c.name(),
 	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *Collection) remove(ctx context.Context, key string, opts RemoveOptions) (mutOut *MutationResult, errOut error) {
@@ -840,7 +921,7 @@ type GetAndTouchOptions struct {
 }
 
 // GetAndTouch retrieves a document and simultaneously updates its expiry time.
-func (c *Collection) GetAndTouch(key string, expiration uint32, opts *GetAndTouchOptions) (docOut *GetResult, errOut error) {
+func (c *Collection) GetAndTouch(key string, expiration Expiry, opts *GetAndTouchOptions) (docOut *GetResult, errOut error) {
 	if opts == nil {
 		opts = &GetAndTouchOptions{}
 	}
@@ -858,12 +939,17 @@ func (c *Collection) GetAndTouch(key string, expiration uint32, opts *GetAndTouc
 	return res, nil
 }
 
-func (c *Collection) getAndTouch(ctx context.Context, key string, expiration uint32, opts GetAndTouchOptions) (docOut *GetResult, errOut error) {
+func (c *Collection) getAndTouch(ctx context.Context, key string, expiration Expiry, opts GetAndTouchOptions) (docOut *GetResult, errOut error) {
 	agent, err := c.getKvProvider()
 	if err != nil {
 		return nil, err
 	}
 
+	expiry, err := expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	if opts.Transcoder == nil {
 		opts.Transcoder = c.sb.Transcoder
 	}
@@ -871,7 +957,7 @@ func (c *Collection) getAndTouch(ctx context.Context, key string, expiration uin
 	ctrl := c.newOpManager(ctx)
 	err = ctrl.wait(agent.GetAndTouchEx(gocbcore.GetAndTouchOptions{
 		Key:            []byte(key),
-		Expiry:         expiration,
+		Expiry:         expiry,
 		CollectionName: c.name(),
 		ScopeName:      c.scopeName(),
 	}, func(res *gocbcore.GetAndTouchResult, err error) {
@@ -909,8 +995,9 @@ type GetAndLockOptions struct {
 	Transcoder Transcoder
 }
 
-// GetAndLock locks a document for a period of time, providing exclusive RW access to it.
-func (c *Collection) GetAndLock(key string, expiration uint32, opts *GetAndLockOptions) (docOut *GetResult, errOut error) {
+// GetAndLock locks a document for a period of time, providing exclusive RW access to it. The lock time is rounded up
+// to the nearest second, the server caps it at 30 seconds.
+func (c *Collection) GetAndLock(key string, lockTime time.Duration, opts *GetAndLockOptions) (docOut *GetResult, errOut error) {
 	if opts == nil {
 		opts = &GetAndLockOptions{}
 	}
@@ -920,19 +1007,24 @@ func (c *Collection) GetAndLock(key string, expiration uint32, opts *GetAndLockO
 		defer cancel()
 	}
 
-	res, err := c.getAndLock(ctx, key, expiration, *opts)
+	res, err := c.getAndLock(ctx, key, lockTime, *opts)
 	if err != nil {
 		return nil, err
 	}
 
 	return res, nil
 }
-func (c *Collection) getAndLock(ctx context.Context, key string, expiration uint32, opts GetAndLockOptions) (docOut *GetResult, errOut error) {
+func (c *Collection) getAndLock(ctx context.Context, key string, lockTime time.Duration, opts GetAndLockOptions) (docOut *GetResult, errOut error) {
 	agent, err := c.getKvProvider()
 	if err != nil {
 		return nil, err
 	}
 
+	lockSecs, err := lockTimeToWire(lockTime)
+	if err != nil {
+		return nil, err
+	}
+
 	if opts.Transcoder == nil {
 		opts.Transcoder = c.sb.Transcoder
 	}
@@ -940,7 +1032,7 @@ func (c *Collection) getAndLock(ctx context.Context, key string, expiration uint
 	ctrl := c.newOpManager(ctx)
 	err = ctrl.wait(agent.GetAndLockEx(gocbcore.GetAndLockOptions{
 		Key:            []byte(key),
-		LockTime:       expiration,
+		LockTime:       lockSecs,
 		CollectionName: c.name(),
 		ScopeName:      c.scopeName(),
 	}, func(res *gocbcore.GetAndLockResult, err error) {
@@ -1042,7 +1134,7 @@ type TouchOptions struct {
 }
 
 // Touch touches a document, specifying a new expiry time for it.
-func (c *Collection) Touch(key string, expiration uint32, opts *TouchOptions) (mutOut *MutationResult, errOut error) {
+func (c *Collection) Touch(key string, expiration Expiry, opts *TouchOptions) (mutOut *MutationResult, errOut error) {
 	if opts == nil {
 		opts = &TouchOptions{}
 	}
@@ -1060,12 +1152,17 @@ func (c *Collection) Touch(key string, expiration uint32, opts *TouchOptions) (m
 	return res, nil
 }
 
-func (c *Collection) touch(ctx context.Context, key string, expiration uint32, opts TouchOptions) (mutOut *MutationResult, errOut error) {
+func (c *Collection) touch(ctx context.Context, key string, expiration Expiry, opts TouchOptions) (mutOut *MutationResult, errOut error) {
 	agent, err := c.getKvProvider()
 	if err != nil {
 		return nil, err
 	}
 
+	expiry, err := expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	coerced, durabilityTimeout := c.durabilityTimeout(ctx, opts.DurabilityLevel)
 	if coerced {
 		var cancel context.CancelFunc
@@ -1076,7 +1173,7 @@ func (c *Collection) touch(ctx context.Context, key string, expiration uint32, o
 	ctrl := c.newOpManager(ctx)
 	err = ctrl.wait(agent.TouchEx(gocbcore.TouchOptions{
 		Key:                    []byte(key),
-		Expiry:                 expiration,
+		Expiry:                 expiry,
 		CollectionName:         c.name(),
 		ScopeName:              c.scopeName(),
 		DurabilityLevel:        gocbcore.DurabilityLevel(opts.DurabilityLevel),
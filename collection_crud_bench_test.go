@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func BenchmarkUpsert(b *testing.B) {
@@ -139,7 +140,7 @@ func BenchmarkGetAndTouch(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			_, err = globalCollection.GetAndTouch("upsert-get-and-touch-1", 10, nil)
+			_, err = globalCollection.GetAndTouch("upsert-get-and-touch-1", ExpiryIn(10*time.Second), nil)
 			if err != nil {
 				b.Fatalf("failed to get and touch %v", err)
 			}
@@ -163,7 +164,7 @@ func BenchmarkTouch(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			_, err = globalCollection.Touch("upsert-touch-1", 10, nil)
+			_, err = globalCollection.Touch("upsert-touch-1", ExpiryIn(10*time.Second), nil)
 			if err != nil {
 				b.Fatalf("failed to touch %v", err)
 			}
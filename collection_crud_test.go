@@ -46,7 +46,7 @@ func TestInsertGetWithExpiry(t *testing.T) {
 		t.Fatalf("Could not read test dataset: %v", err)
 	}
 
-	mutRes, err := globalCollection.Insert("expiryDoc", doc, &InsertOptions{Expiration: 10})
+	mutRes, err := globalCollection.Insert("expiryDoc", doc, &InsertOptions{Expiration: ExpiryIn(10 * time.Second)})
 	if err != nil {
 		t.Fatalf("Insert failed, error was %v", err)
 	}
@@ -74,7 +74,7 @@ func TestInsertGetWithExpiry(t *testing.T) {
 		t.Fatalf("Expected document to have an expiry")
 	}
 
-	if insertedDoc.Expiration() == 0 {
+	if insertedDoc.Expiration().IsZero() {
 		t.Fatalf("Expected expiry value to be populated")
 	}
 }
@@ -409,7 +409,7 @@ func TestInsertGetProjection16FieldsExpiry(t *testing.T) {
 	}
 
 	mutRes, err := globalCollection.Upsert("projectDocTooManyFieldsExpiry", doc, &UpsertOptions{
-		Expiration: 60,
+		Expiration: ExpiryIn(60 * time.Second),
 	})
 	if err != nil {
 		t.Fatalf("Insert failed, error was %v", err)
@@ -444,7 +444,7 @@ func TestInsertGetProjection16FieldsExpiry(t *testing.T) {
 		t.Fatalf("Expected document to have an expiry")
 	}
 
-	if insertedDoc.Expiration() == 0 {
+	if insertedDoc.Expiration().IsZero() {
 		t.Fatalf("Expected expiry value to be populated")
 	}
 }
@@ -798,7 +798,7 @@ func TestGetAndTouch(t *testing.T) {
 		t.Fatalf("Upsert CAS was 0")
 	}
 
-	lockedDoc, err := globalCollection.GetAndTouch("getAndTouch", 10, nil)
+	lockedDoc, err := globalCollection.GetAndTouch("getAndTouch", ExpiryIn(10*time.Second), nil)
 	if err != nil {
 		t.Fatalf("Get failed, error was %v", err)
 	}
@@ -822,8 +822,8 @@ func TestGetAndTouch(t *testing.T) {
 		t.Fatalf("Expected doc to have an expiry")
 	}
 
-	if expireDoc.Expiration() == 0 {
-		t.Fatalf("Expected doc to have an expiry > 0, was %d", expireDoc.Expiration())
+	if expireDoc.Expiration().IsZero() {
+		t.Fatalf("Expected doc to have an expiry, was %v", expireDoc.Expiration())
 	}
 
 	var expireDocContent testBeerDocument
@@ -853,7 +853,7 @@ func TestGetAndLock(t *testing.T) {
 		t.Fatalf("Upsert CAS was 0")
 	}
 
-	lockedDoc, err := globalCollection.GetAndLock("getAndLock", 1, nil)
+	lockedDoc, err := globalCollection.GetAndLock("getAndLock", 1*time.Second, nil)
 	if err != nil {
 		t.Fatalf("Get failed, error was %v", err)
 	}
@@ -905,7 +905,7 @@ func TestUnlock(t *testing.T) {
 		t.Fatalf("Upsert CAS was 0")
 	}
 
-	lockedDoc, err := globalCollection.GetAndLock("unlock", 1, nil)
+	lockedDoc, err := globalCollection.GetAndLock("unlock", 1*time.Second, nil)
 	if err != nil {
 		t.Fatalf("Get failed, error was %v", err)
 	}
@@ -951,7 +951,7 @@ func TestUnlockInvalidCas(t *testing.T) {
 		t.Fatalf("Upsert CAS was 0")
 	}
 
-	lockedDoc, err := globalCollection.GetAndLock("unlockInvalidCas", 1, nil)
+	lockedDoc, err := globalCollection.GetAndLock("unlockInvalidCas", 1*time.Second, nil)
 	if err != nil {
 		t.Fatalf("Get failed, error was %v", err)
 	}
@@ -992,7 +992,7 @@ func TestDoubleLockFail(t *testing.T) {
 		t.Fatalf("Upsert CAS was 0")
 	}
 
-	lockedDoc, err := globalCollection.GetAndLock("doubleLock", 1, nil)
+	lockedDoc, err := globalCollection.GetAndLock("doubleLock", 1*time.Second, nil)
 	if err != nil {
 		t.Fatalf("Get failed, error was %v", err)
 	}
@@ -1007,7 +1007,7 @@ func TestDoubleLockFail(t *testing.T) {
 		t.Fatalf("Expected resulting doc to be %v but was %v", doc, lockedDocContent)
 	}
 
-	_, err = globalCollection.GetAndLock("doubleLock", 1, nil)
+	_, err = globalCollection.GetAndLock("doubleLock", 1*time.Second, nil)
 	if err == nil {
 		t.Fatalf("Expected GetAndLock to fail")
 	}
@@ -1048,7 +1048,7 @@ func TestTouch(t *testing.T) {
 		t.Fatalf("Upsert CAS was 0")
 	}
 
-	lockedDoc, err := globalCollection.GetAndTouch("touch", 2, nil)
+	lockedDoc, err := globalCollection.GetAndTouch("touch", ExpiryIn(2*time.Second), nil)
 	if err != nil {
 		t.Fatalf("Get failed, error was %v", err)
 	}
@@ -1065,7 +1065,7 @@ func TestTouch(t *testing.T) {
 
 	globalCluster.TimeTravel(1 * time.Second)
 
-	touchOut, err := globalCollection.Touch("touch", 3, nil)
+	touchOut, err := globalCollection.Touch("touch", ExpiryIn(3*time.Second), nil)
 	if err != nil {
 		t.Fatalf("Touch failed, error was %v", err)
 	}
@@ -1085,8 +1085,8 @@ func TestTouch(t *testing.T) {
 		t.Fatalf("Expected doc to have an expiry")
 	}
 
-	if expireDoc.Expiration() == 0 {
-		t.Fatalf("Expected doc to have an expiry > 0, was %d", expireDoc.Expiration())
+	if expireDoc.Expiration().IsZero() {
+		t.Fatalf("Expected doc to have an expiry, was %v", expireDoc.Expiration())
 	}
 
 	var expireDocContent testBeerDocument
@@ -1101,7 +1101,7 @@ func TestTouch(t *testing.T) {
 }
 
 func TestTouchMissingDocFail(t *testing.T) {
-	_, err := globalCollection.Touch("touchMissing", 3, nil)
+	_, err := globalCollection.Touch("touchMissing", ExpiryIn(3*time.Second), nil)
 	if err == nil {
 		t.Fatalf("Touch should have failed")
 	}
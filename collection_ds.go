@@ -4,17 +4,50 @@ import (
 	"fmt"
 )
 
+// CouchbaseListOptions are the options available when creating a CouchbaseList. The durability
+// requirements apply to every operation performed by the list that mutates the document.
+type CouchbaseListOptions struct {
+	PersistTo       uint
+	ReplicateTo     uint
+	DurabilityLevel DurabilityLevel
+	// Expiration is applied to the document by every mutation, the zero value sets the document to never expire.
+	Expiration Expiry
+}
+
 // CouchbaseList represents a list document.
 type CouchbaseList struct {
 	collection *Collection
 	key        string
+	opts       CouchbaseListOptions
 }
 
 // List returns a new CouchbaseList for the document specified by key.
 func (c *Collection) List(key string) *CouchbaseList {
+	return c.ListWithOptions(key, nil)
+}
+
+// ListWithOptions returns a new CouchbaseList whose mutations apply the durability requirements and expiration
+// in opts.
+func (c *Collection) ListWithOptions(key string, opts *CouchbaseListOptions) *CouchbaseList {
+	if opts == nil {
+		opts = &CouchbaseListOptions{}
+	}
+
 	return &CouchbaseList{
 		collection: c,
 		key:        key,
+		opts:       *opts,
+	}
+}
+
+func (cl *CouchbaseList) mutateInOptions(upsertDocument bool, cas Cas) *MutateInOptions {
+	return &MutateInOptions{
+		Cas:             cas,
+		UpsertDocument:  upsertDocument,
+		PersistTo:       cl.opts.PersistTo,
+		ReplicateTo:     cl.opts.ReplicateTo,
+		DurabilityLevel: cl.opts.DurabilityLevel,
+		Expiration:      cl.opts.Expiration,
 	}
 }
 
@@ -52,7 +85,7 @@ func (cl *CouchbaseList) RemoveAt(index int) error {
 	spec := MutateInSpec{}
 	ops := make([]MutateInOp, 1)
 	ops[0] = spec.Remove(fmt.Sprintf("[%d]", index), nil)
-	_, err := cl.collection.MutateIn(cl.key, ops, nil)
+	_, err := cl.collection.MutateIn(cl.key, ops, cl.mutateInOptions(false, 0))
 	if err != nil {
 		return err
 	}
@@ -65,7 +98,7 @@ func (cl *CouchbaseList) Append(val interface{}) error {
 	spec := MutateInSpec{}
 	ops := make([]MutateInOp, 1)
 	ops[0] = spec.ArrayAppend("", val, nil)
-	_, err := cl.collection.MutateIn(cl.key, ops, &MutateInOptions{UpsertDocument: true})
+	_, err := cl.collection.MutateIn(cl.key, ops, cl.mutateInOptions(true, 0))
 	if err != nil {
 		return err
 	}
@@ -78,7 +111,7 @@ func (cl *CouchbaseList) Prepend(val interface{}) error {
 	spec := MutateInSpec{}
 	ops := make([]MutateInOp, 1)
 	ops[0] = spec.ArrayPrepend("", val, nil)
-	_, err := cl.collection.MutateIn(cl.key, ops, &MutateInOptions{UpsertDocument: true})
+	_, err := cl.collection.MutateIn(cl.key, ops, cl.mutateInOptions(true, 0))
 	if err != nil {
 		return err
 	}
@@ -127,17 +160,49 @@ func (cl *CouchbaseList) Size() (int, error) {
 	return count, nil
 }
 
+// CouchbaseMapOptions are the options available when creating a CouchbaseMap. The durability
+// requirements apply to every operation performed by the map that mutates the document.
+type CouchbaseMapOptions struct {
+	PersistTo       uint
+	ReplicateTo     uint
+	DurabilityLevel DurabilityLevel
+	// Expiration is applied to the document by every mutation, the zero value sets the document to never expire.
+	Expiration Expiry
+}
+
 // CouchbaseMap represents a map document.
 type CouchbaseMap struct {
 	collection *Collection
 	key        string
+	opts       CouchbaseMapOptions
 }
 
 // Map returns a new CouchbaseMap.
 func (c *Collection) Map(key string) *CouchbaseMap {
+	return c.MapWithOptions(key, nil)
+}
+
+// MapWithOptions returns a new CouchbaseMap whose mutations apply the durability requirements and expiration
+// in opts.
+func (c *Collection) MapWithOptions(key string, opts *CouchbaseMapOptions) *CouchbaseMap {
+	if opts == nil {
+		opts = &CouchbaseMapOptions{}
+	}
+
 	return &CouchbaseMap{
 		collection: c,
 		key:        key,
+		opts:       *opts,
+	}
+}
+
+func (cl *CouchbaseMap) mutateInOptions(upsertDocument bool) *MutateInOptions {
+	return &MutateInOptions{
+		UpsertDocument:  upsertDocument,
+		PersistTo:       cl.opts.PersistTo,
+		ReplicateTo:     cl.opts.ReplicateTo,
+		DurabilityLevel: cl.opts.DurabilityLevel,
+		Expiration:      cl.opts.Expiration,
 	}
 }
 
@@ -175,7 +240,7 @@ func (cl *CouchbaseMap) Add(key string, val interface{}) error {
 	spec := MutateInSpec{}
 	ops := make([]MutateInOp, 1)
 	ops[0] = spec.Upsert(key, val, nil)
-	_, err := cl.collection.MutateIn(cl.key, ops, &MutateInOptions{UpsertDocument: true})
+	_, err := cl.collection.MutateIn(cl.key, ops, cl.mutateInOptions(true))
 	if err != nil {
 		return err
 	}
@@ -188,7 +253,7 @@ func (cl *CouchbaseMap) Remove(key string) error {
 	spec := MutateInSpec{}
 	ops := make([]MutateInOp, 1)
 	ops[0] = spec.Remove(key, nil)
-	_, err := cl.collection.MutateIn(cl.key, ops, nil)
+	_, err := cl.collection.MutateIn(cl.key, ops, cl.mutateInOptions(false))
 	if err != nil {
 		return err
 	}
@@ -270,6 +335,16 @@ func (cl *CouchbaseMap) Values() ([]interface{}, error) {
 	return values, nil
 }
 
+// CouchbaseSetOptions are the options available when creating a CouchbaseSet. The durability
+// requirements apply to every operation performed by the set that mutates the document.
+type CouchbaseSetOptions struct {
+	PersistTo       uint
+	ReplicateTo     uint
+	DurabilityLevel DurabilityLevel
+	// Expiration is applied to the document by every mutation, the zero value sets the document to never expire.
+	Expiration Expiry
+}
+
 // CouchbaseSet represents a set document.
 type CouchbaseSet struct {
 	key        string
@@ -278,9 +353,24 @@ type CouchbaseSet struct {
 
 // Set returns a new CouchbaseSet.
 func (c *Collection) Set(key string) *CouchbaseSet {
+	return c.SetWithOptions(key, nil)
+}
+
+// SetWithOptions returns a new CouchbaseSet whose mutations apply the durability requirements and expiration
+// in opts.
+func (c *Collection) SetWithOptions(key string, opts *CouchbaseSetOptions) *CouchbaseSet {
+	if opts == nil {
+		opts = &CouchbaseSetOptions{}
+	}
+
 	return &CouchbaseSet{
-		key:        key,
-		underlying: c.List(key),
+		key: key,
+		underlying: c.ListWithOptions(key, &CouchbaseListOptions{
+			PersistTo:       opts.PersistTo,
+			ReplicateTo:     opts.ReplicateTo,
+			DurabilityLevel: opts.DurabilityLevel,
+			Expiration:      opts.Expiration,
+		}),
 	}
 }
 
@@ -294,7 +384,7 @@ func (cs *CouchbaseSet) Add(val interface{}) error {
 	spec := MutateInSpec{}
 	ops := make([]MutateInOp, 1)
 	ops[0] = spec.ArrayAddUnique("", val, nil)
-	_, err := cs.underlying.collection.MutateIn(cs.key, ops, &MutateInOptions{UpsertDocument: true})
+	_, err := cs.underlying.collection.MutateIn(cs.key, ops, cs.underlying.mutateInOptions(true, 0))
 	if err != nil {
 		return err
 	}
@@ -329,7 +419,7 @@ func (cs *CouchbaseSet) Remove(val string) error {
 		if indexToRemove > -1 {
 			ops := make([]MutateInOp, 1)
 			ops[0] = spec.Remove(fmt.Sprintf("[%d]", indexToRemove), nil)
-			_, err = cs.underlying.collection.MutateIn(cs.key, ops, &MutateInOptions{Cas: cas})
+			_, err = cs.underlying.collection.MutateIn(cs.key, ops, cs.underlying.mutateInOptions(false, cas))
 			if IsCasMismatchError(err) {
 				continue
 			}
@@ -370,6 +460,16 @@ func (cs *CouchbaseSet) Size() (int, error) {
 	return cs.underlying.Size()
 }
 
+// CouchbaseQueueOptions are the options available when creating a CouchbaseQueue. The durability
+// requirements apply to every operation performed by the queue that mutates the document.
+type CouchbaseQueueOptions struct {
+	PersistTo       uint
+	ReplicateTo     uint
+	DurabilityLevel DurabilityLevel
+	// Expiration is applied to the document by every mutation, the zero value sets the document to never expire.
+	Expiration Expiry
+}
+
 // CouchbaseQueue represents a queue document.
 type CouchbaseQueue struct {
 	key        string
@@ -378,9 +478,24 @@ type CouchbaseQueue struct {
 
 // Queue returns a new CouchbaseQueue.
 func (c *Collection) Queue(key string) *CouchbaseQueue {
+	return c.QueueWithOptions(key, nil)
+}
+
+// QueueWithOptions returns a new CouchbaseQueue whose mutations apply the durability requirements and expiration
+// in opts.
+func (c *Collection) QueueWithOptions(key string, opts *CouchbaseQueueOptions) *CouchbaseQueue {
+	if opts == nil {
+		opts = &CouchbaseQueueOptions{}
+	}
+
 	return &CouchbaseQueue{
-		key:        key,
-		underlying: c.List(key),
+		key: key,
+		underlying: c.ListWithOptions(key, &CouchbaseListOptions{
+			PersistTo:       opts.PersistTo,
+			ReplicateTo:     opts.ReplicateTo,
+			DurabilityLevel: opts.DurabilityLevel,
+			Expiration:      opts.Expiration,
+		}),
 	}
 }
 
@@ -414,7 +529,7 @@ func (cs *CouchbaseQueue) Pop(valuePtr interface{}) error {
 
 		mutateOps := make([]MutateInOp, 1)
 		mutateOps[0] = mutateSpec.Remove("[-1]", nil)
-		_, err = cs.underlying.collection.MutateIn(cs.key, mutateOps, &MutateInOptions{Cas: cas})
+		_, err = cs.underlying.collection.MutateIn(cs.key, mutateOps, cs.underlying.mutateInOptions(false, cas))
 		if IsCasMismatchError(err) {
 			continue
 		}
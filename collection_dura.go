@@ -2,108 +2,314 @@ package gocb
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
 )
 
-func (c *Collection) observeOnceSeqNo(mt MutationToken, replicaIdx int, commCh chan uint) (pendingOp, error) {
-	agent, err := c.getKvProvider()
-	if err != nil {
-		return nil, err
-	}
+// observeVbucket tracks the observe state of a single vbucket against the highest sequence number
+// that must be reached for every mutation made within it to be considered durable.
+type observeVbucket struct {
+	vbID       uint16
+	vbUUID     gocbcore.VbUuid
+	seqNo      gocbcore.SeqNo
+	replicated []bool
+	persisted  []bool
+	nodes      []string
+	elapsed    time.Duration
+}
 
-	return agent.ObserveVbEx(gocbcore.ObserveVbOptions{
-		VbId:       mt.token.VbId,
-		VbUuid:     mt.token.VbUuid,
-		ReplicaIdx: replicaIdx,
-	}, func(res *gocbcore.ObserveVbResult, err error) {
-		if err != nil || res == nil {
-			commCh <- 0
-			return
-		}
+type observeVbucketKey struct {
+	vbID   uint16
+	vbUUID gocbcore.VbUuid
+}
 
-		didReplicate := res.CurrentSeqNo >= mt.token.SeqNo
-		didPersist := res.PersistSeqNo >= mt.token.SeqNo
+type observeVbResponse struct {
+	vb         *observeVbucket
+	replicaIdx int
+	res        *gocbcore.ObserveVbResult
+	err        error
+}
 
-		var out uint
-		if didReplicate {
-			out |= 1
+// satisfied returns whether or not the vbucket has met the durability requirements. The active node
+// always holds the mutation so it never counts towards replicateTo.
+func (vb *observeVbucket) satisfied(replicateTo, persistTo uint) bool {
+	replicas := uint(0)
+	persists := uint(0)
+	for replicaIdx := range vb.replicated {
+		if replicaIdx > 0 && vb.replicated[replicaIdx] {
+			replicas++
 		}
-		if didPersist {
-			out |= 2
+		if vb.persisted[replicaIdx] {
+			persists++
 		}
-		commCh <- out
-	})
+	}
+
+	return replicas >= replicateTo && persists >= persistTo
 }
 
-func (c *Collection) observeOne(ctx context.Context, key []byte, mt MutationToken,
-	cas Cas, forDelete bool, replicaIdx int, replicaCh, persistCh chan bool, scopeName, collectionName string) {
+func (vb *observeVbucket) result() *DurabilityResult {
+	res := &DurabilityResult{
+		Replicas: make([]DurabilityReplicaResult, len(vb.replicated)),
+		Elapsed:  vb.elapsed,
+	}
+	for replicaIdx := range vb.replicated {
+		res.Replicas[replicaIdx] = DurabilityReplicaResult{
+			ReplicaIdx: replicaIdx,
+			Node:       vb.nodes[replicaIdx],
+			Replicated: vb.replicated[replicaIdx],
+			Persisted:  vb.persisted[replicaIdx],
+		}
+	}
 
-	sentReplicated := false
-	sentPersisted := false
+	return res
+}
 
-	failMe := func() {
-		if !sentReplicated {
-			replicaCh <- false
-			sentReplicated = true
-		}
-		if !sentPersisted {
-			persistCh <- false
-			sentPersisted = true
-		}
+// nextPollInterval returns how long to wait before the next observe poll, or false if polling should stop.
+func (c *Collection) nextPollInterval(polls uint) (time.Duration, bool) {
+	if c.sb.DuraPollBehavior == nil {
+		return c.sb.DuraPollTimeout, true
 	}
 
-	// Doing this will set the context deadline to whichever is shorter, what is already set or the timeout
-	// value
-	var cancel context.CancelFunc
-	ctx, cancel = context.WithTimeout(ctx, c.sb.DuraTimeout)
-	defer cancel()
+	if !c.sb.DuraPollBehavior.CanRetry(polls) {
+		return 0, false
+	}
 
-	commCh := make(chan uint)
-	for {
-		op, err := c.observeOnceSeqNo(mt, replicaIdx, commCh)
-		if err != nil {
-			failMe()
-			return
-		}
+	return c.sb.DuraPollBehavior.NextInterval(polls), true
+}
 
-		select {
-		case val := <-commCh:
-			// Got Value
-			if (val&1) != 0 && !sentReplicated {
-				replicaCh <- true
-				sentReplicated = true
+func (c *Collection) durabilityFailedError() error {
+	if c.sb.AmbiguousDurabilityErrors {
+		return durabilityAmbiguousError{reason: "Durability requirements could not be confirmed, the mutation may or may not be durable."}
+	}
+
+	return durabilityError{reason: "Failed to meet durability requirements in time."}
+}
+
+// observeVbuckets polls every node for each of the vbuckets until either all of them have met the
+// durability requirements or ctx is done. A single ObserveVbEx request is sent per vbucket per node on
+// each poll, regardless of how many mutations are being observed within that vbucket.
+func (c *Collection) observeVbuckets(ctx context.Context, agent kvProvider, vbuckets []*observeVbucket,
+	replicateTo, persistTo uint) {
+	start := time.Now()
+	numServers := agent.NumReplicas() + 1
+	addresses := c.kvServerAddresses()
+	for _, vb := range vbuckets {
+		vb.replicated = make([]bool, numServers)
+		vb.persisted = make([]bool, numServers)
+		vb.nodes = make([]string, numServers)
+	}
+
+	// Satisfied vbuckets are no longer polled so their elapsed time is recorded as soon as they are done.
+	markElapsed := func() {
+		for _, vb := range vbuckets {
+			if vb.elapsed == 0 && vb.satisfied(replicateTo, persistTo) {
+				vb.elapsed = time.Since(start)
 			}
-			if (val&2) != 0 && !sentPersisted {
-				persistCh <- true
-				sentPersisted = true
+		}
+	}
+	defer func() {
+		for _, vb := range vbuckets {
+			if vb.elapsed == 0 {
+				vb.elapsed = time.Since(start)
 			}
+		}
+	}()
 
-			if sentReplicated && sentPersisted {
-				return
+	polls := uint(0)
+	for {
+		polls++
+		respCh := make(chan observeVbResponse, len(vbuckets)*numServers)
+		var ops []gocbcore.PendingOp
+		pending := 0
+		for _, vb := range vbuckets {
+			if vb.satisfied(replicateTo, persistTo) {
+				continue
 			}
+			pending++
+
+			for replicaIdx := 0; replicaIdx < numServers; replicaIdx++ {
+				if vb.replicated[replicaIdx] && vb.persisted[replicaIdx] {
+					continue
+				}
+
+				if serverIdx := agent.VbucketToServer(vb.vbID, uint32(replicaIdx)); serverIdx >= 0 && serverIdx < len(addresses) {
+					vb.nodes[replicaIdx] = addresses[serverIdx]
+				}
+
+				vb := vb
+				replicaIdx := replicaIdx
+				op, err := agent.ObserveVbEx(gocbcore.ObserveVbOptions{
+					VbId:       vb.vbID,
+					VbUuid:     vb.vbUUID,
+					ReplicaIdx: replicaIdx,
+				}, func(res *gocbcore.ObserveVbResult, err error) {
+					respCh <- observeVbResponse{
+						vb:         vb,
+						replicaIdx: replicaIdx,
+						res:        res,
+						err:        err,
+					}
+				})
+				if err != nil {
+					// The replica may not be available right now, we'll try again on the next poll.
+					continue
+				}
 
-			waitTmr := gocbcore.AcquireTimer(c.sb.DuraPollTimeout)
+				ops = append(ops, op)
+			}
+		}
+
+		if pending == 0 {
+			return
+		}
+
+		for range ops {
 			select {
-			case <-waitTmr.C:
-				gocbcore.ReleaseTimer(waitTmr, true)
-				// Fall through to outside for loop
+			case resp := <-respCh:
+				if resp.err != nil || resp.res == nil {
+					continue
+				}
+
+				if resp.res.CurrentSeqNo >= resp.vb.seqNo {
+					resp.vb.replicated[resp.replicaIdx] = true
+				}
+				if resp.res.PersistSeqNo >= resp.vb.seqNo {
+					resp.vb.persisted[resp.replicaIdx] = true
+				}
 			case <-ctx.Done():
-				op.Cancel()
-				gocbcore.ReleaseTimer(waitTmr, false)
-				failMe()
+				for _, op := range ops {
+					op.Cancel()
+				}
 				return
 			}
+		}
+
+		markElapsed()
+
+		allSatisfied := true
+		for _, vb := range vbuckets {
+			if !vb.satisfied(replicateTo, persistTo) {
+				allSatisfied = false
+				break
+			}
+		}
+		if allSatisfied {
+			return
+		}
+
+		interval, ok := c.nextPollInterval(polls)
+		if !ok {
+			return
+		}
 
+		waitTmr := gocbcore.AcquireTimer(interval)
+		select {
+		case <-waitTmr.C:
+			gocbcore.ReleaseTimer(waitTmr, true)
+			// Fall through to the next poll
 		case <-ctx.Done():
-			// Timed out
-			op.Cancel()
-			failMe()
+			gocbcore.ReleaseTimer(waitTmr, false)
 			return
 		}
 	}
 }
 
+// fallbackDurability maps a durability level onto the equivalent observe based persistTo and replicateTo when
+// durability fallback is enabled and the cluster does not support synchronous durability. Support is decided
+// before the operation is dispatched, see clusterSupportsEnhancedDurability.
+func (c *Collection) fallbackDurability(level DurabilityLevel, persistTo, replicateTo uint) (DurabilityLevel, uint, uint) {
+	if level == 0 || !c.sb.DurabilityFallback || !c.sb.UseMutationTokens ||
+		c.sb.getCachedClient().supportsEnhancedDurability() {
+		return level, persistTo, replicateTo
+	}
+
+	agent, err := c.getKvProvider()
+	if err != nil {
+		return level, persistTo, replicateTo
+	}
+
+	majority := uint((agent.NumReplicas()+1)/2 + 1)
+	switch level {
+	case DurabilityLevelMajority:
+		return 0, 0, majority - 1
+	case DurabilityLevelMajorityAndPersistActive:
+		return 0, 1, majority - 1
+	case DurabilityLevelPersistToMajority:
+		return 0, majority, majority - 1
+	}
+
+	return level, persistTo, replicateTo
+}
+
+// durabilitySupportInterval is how long support for synchronous durability is cached before it is detected again.
+const durabilitySupportInterval = time.Minute
+
+// enhancedDurabilityCompatibility is the cluster compatibility version, encoded as major<<16 | minor, from which
+// synchronous durability is supported.
+const enhancedDurabilityCompatibility = 6<<16 | 5
+
+// clusterSupportsEnhancedDurability reports whether every node of the cluster supports synchronous durability. The
+// cluster compatibility version only moves forward once every node of a cluster has been upgraded.
+func clusterSupportsEnhancedDurability(ctx context.Context, provider httpProvider) (bool, error) {
+	req := &gocbcore.HttpRequest{
+		Service: gocbcore.ServiceType(MgmtService),
+		Path:    "/pools/default",
+		Method:  "GET",
+		Context: ctx,
+	}
+
+	resp, err := provider.DoHttpRequest(req)
+	if err != nil {
+		return false, err
+	}
+
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logDebugf("Failed to close socket (%s)", err)
+		}
+	}()
+
+	if resp.StatusCode != 200 {
+		return false, errors.Errorf("cluster returned status %d when reading its compatibility version", resp.StatusCode)
+	}
+
+	var pool struct {
+		Nodes []struct {
+			ClusterCompatibility int `json:"clusterCompatibility"`
+		} `json:"nodes"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&pool)
+	if err != nil {
+		return false, errors.Wrap(err, "could not decode cluster compatibility version")
+	}
+
+	if len(pool.Nodes) == 0 {
+		return false, errors.New("cluster did not report any nodes")
+	}
+
+	for _, node := range pool.Nodes {
+		if node.ClusterCompatibility < enhancedDurabilityCompatibility {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func durabilityModeFor(level DurabilityLevel, persistTo, replicateTo uint) DurabilityMode {
+	if level > 0 {
+		return DurabilityModeEnhanced
+	} else if persistTo > 0 || replicateTo > 0 {
+		return DurabilityModeObserve
+	}
+
+	return DurabilityModeNone
+}
+
 type durabilitySettings struct {
 	ctx            context.Context
 	key            string
@@ -116,54 +322,110 @@ type durabilitySettings struct {
 	scopeName      string
 }
 
-func (c *Collection) durability(settings durabilitySettings) error {
+func (c *Collection) durability(settings durabilitySettings) (*DurabilityResult, error) {
 	if settings.ctx == nil {
 		settings.ctx = context.Background()
 	}
 
 	agent, err := c.getKvProvider()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	numServers := agent.NumReplicas() + 1
 
 	if settings.replicaTo > uint(numServers-1) || settings.persistTo > uint(numServers) {
-		return durabilityError{reason: "Not enough replicas to match durability requirements."}
+		return nil, durabilityError{reason: "Not enough replicas to match durability requirements."}
+	}
+
+	// Doing this will set the context deadline to whichever is shorter, what is already set or the timeout
+	// value
+	ctx, cancel := context.WithTimeout(settings.ctx, c.sb.DuraTimeout)
+	defer cancel()
+
+	vb := &observeVbucket{
+		vbID:   settings.mt.token.VbId,
+		vbUUID: settings.mt.token.VbUuid,
+		seqNo:  settings.mt.token.SeqNo,
 	}
+	c.observeVbuckets(ctx, agent, []*observeVbucket{vb}, settings.replicaTo, settings.persistTo)
 
-	keyBytes := []byte(settings.key)
+	if !vb.satisfied(settings.replicaTo, settings.persistTo) {
+		return vb.result(), c.durabilityFailedError()
+	}
 
-	replicaCh := make(chan bool, numServers)
-	persistCh := make(chan bool, numServers)
+	return vb.result(), nil
+}
 
-	for replicaIdx := 0; replicaIdx < numServers; replicaIdx++ {
-		go c.observeOne(settings.ctx, keyBytes, settings.mt, settings.cas, settings.forDelete,
-			replicaIdx, replicaCh, persistCh, settings.scopeName, settings.collectionName)
+// bulkDurability observes the mutations made by ops, batching the observe requests so that each vbucket
+// is only polled once per node regardless of how many of the ops mutated documents within it. Any op
+// which fails to meet the durability requirements has its error set.
+func (c *Collection) bulkDurability(ctx context.Context, ops []BulkOp, replicaTo, persistTo uint) error {
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	results := int(0)
-	replicas := uint(0)
-	persists := uint(0)
+	agent, err := c.getKvProvider()
+	if err != nil {
+		return err
+	}
 
-	for {
-		select {
-		case rV := <-replicaCh:
-			if rV {
-				replicas++
-			}
-			results++
-		case pV := <-persistCh:
-			if pV {
-				persists++
+	numServers := agent.NumReplicas() + 1
+
+	vbucketsByKey := make(map[observeVbucketKey]*observeVbucket)
+	opVbuckets := make(map[BulkOp]*observeVbucket)
+	var vbuckets []*observeVbucket
+	for _, op := range ops {
+		mutOp, ok := op.(bulkMutationOp)
+		if !ok {
+			continue
+		}
+
+		res := mutOp.mutationResult()
+		if res == nil {
+			continue
+		}
+
+		if replicaTo > uint(numServers-1) || persistTo > uint(numServers) {
+			op.markError(durabilityError{reason: "Not enough replicas to match durability requirements."})
+			continue
+		}
+
+		token := res.mt.token
+		key := observeVbucketKey{vbID: token.VbId, vbUUID: token.VbUuid}
+		vb, ok := vbucketsByKey[key]
+		if !ok {
+			vb = &observeVbucket{
+				vbID:   token.VbId,
+				vbUUID: token.VbUuid,
 			}
-			results++
+			vbucketsByKey[key] = vb
+			vbuckets = append(vbuckets, vb)
+		}
+		if token.SeqNo > vb.seqNo {
+			vb.seqNo = token.SeqNo
 		}
 
-		if replicas >= settings.replicaTo && persists >= settings.persistTo {
-			return nil
-		} else if results == (numServers * 2) {
-			return durabilityError{reason: "Failed to meet durability requirements in time."}
+		opVbuckets[op] = vb
+	}
+
+	if len(vbuckets) == 0 {
+		return nil
+	}
+
+	// Doing this will set the context deadline to whichever is shorter, what is already set or the timeout
+	// value
+	ctx, cancel := context.WithTimeout(ctx, c.sb.DuraTimeout)
+	defer cancel()
+
+	c.observeVbuckets(ctx, agent, vbuckets, replicaTo, persistTo)
+
+	for op, vb := range opVbuckets {
+		op.(bulkMutationOp).mutationResult().durability = vb.result()
+		if !vb.satisfied(replicaTo, persistTo) {
+			op.markError(c.durabilityFailedError())
 		}
 	}
+
+	return nil
 }
@@ -0,0 +1,246 @@
+package gocb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func TestObserveDurabilityResult(t *testing.T) {
+	provider := &mockKvProvider{
+		cas: gocbcore.Cas(1),
+		mt: gocbcore.MutationToken{
+			VbId:   12,
+			VbUuid: 1234,
+			SeqNo:  5,
+		},
+		numReplicas: 1,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.UseMutationTokens = true
+	col.sb.DuraTimeout = 1000 * time.Millisecond
+	col.sb.DuraPollTimeout = 10 * time.Millisecond
+
+	res, err := col.Upsert("observeDurabilityResult", "test", &UpsertOptions{ReplicateTo: 1, PersistTo: 1})
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	durability := res.Durability()
+	if durability == nil {
+		t.Fatalf("Expected durability result to not be nil")
+	}
+
+	if len(durability.Replicas) != 2 {
+		t.Fatalf("Expected durability result to contain 2 replicas but had %d", len(durability.Replicas))
+	}
+
+	for i, replica := range durability.Replicas {
+		if replica.ReplicaIdx != i {
+			t.Fatalf("Expected replica index to be %d but was %d", i, replica.ReplicaIdx)
+		}
+		if !replica.Replicated || !replica.Persisted {
+			t.Fatalf("Expected replica %d to be replicated and persisted", i)
+		}
+	}
+}
+
+func TestObserveDurabilityNoResultWithoutObserve(t *testing.T) {
+	provider := &mockKvProvider{
+		cas: gocbcore.Cas(1),
+	}
+	col := testGetCollection(t, provider)
+
+	res, err := col.Upsert("observeDurabilityNoResult", "test", nil)
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	if res.Durability() != nil {
+		t.Fatalf("Expected durability result to be nil")
+	}
+}
+
+func TestObserveDurabilityAmbiguous(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:         gocbcore.Cas(1),
+		numReplicas: 1,
+		observeErr:  gocbcore.ErrTimeout,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.UseMutationTokens = true
+	col.sb.DuraTimeout = 1000 * time.Millisecond
+	col.sb.DuraPollBehavior = StandardDelayRetryBehavior(2, 1, 5*time.Millisecond, LinearDelayFunction)
+
+	res, err := col.Upsert("observeDurabilityAmbiguous", "test", &UpsertOptions{ReplicateTo: 1})
+	if !IsDurabilityError(err) {
+		t.Fatalf("Expected Upsert to return a durability error but was %v", err)
+	}
+
+	if IsDurabilityAmbiguousError(err) {
+		t.Fatalf("Expected error to not be ambiguous without AmbiguousDurabilityErrors")
+	}
+
+	if res.Durability() == nil || res.Durability().Replicas[1].Replicated {
+		t.Fatalf("Expected durability result to show the replica as not replicated")
+	}
+
+	if count := atomic.LoadUint32(&provider.observeVbCount); count != 4 {
+		t.Fatalf("Expected 4 observe requests but was %d", count)
+	}
+
+	col.sb.AmbiguousDurabilityErrors = true
+	_, err = col.Upsert("observeDurabilityAmbiguous", "test", &UpsertOptions{ReplicateTo: 1})
+	if !IsDurabilityAmbiguousError(err) {
+		t.Fatalf("Expected Upsert to return an ambiguous durability error but was %v", err)
+	}
+}
+
+func TestDurabilityFallbackToObserve(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:         gocbcore.Cas(1),
+		numReplicas: 2,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.UseMutationTokens = true
+	col.sb.DuraTimeout = 1000 * time.Millisecond
+	col.sb.getCachedClient().(*mockClient).durabilityUnsupported = true
+
+	// Without fallback the durability level is sent as is, for the server to reject.
+	res, err := col.Upsert("durabilityFallback", "test", &UpsertOptions{DurabilityLevel: DurabilityLevelMajority})
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	if res.DurabilityMode() != DurabilityModeEnhanced {
+		t.Fatalf("Expected durability mode to be enhanced without fallback but was %d", res.DurabilityMode())
+	}
+
+	col.sb.DurabilityFallback = true
+	opts := &UpsertOptions{DurabilityLevel: DurabilityLevelPersistToMajority}
+	res, err = col.Upsert("durabilityFallback", "test", opts)
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	if res.DurabilityMode() != DurabilityModeObserve {
+		t.Fatalf("Expected durability mode to be observe but was %d", res.DurabilityMode())
+	}
+
+	if res.Durability() == nil || len(res.Durability().Replicas) != 3 {
+		t.Fatalf("Expected durability result to contain 3 replicas")
+	}
+
+	if opts.DurabilityLevel != DurabilityLevelPersistToMajority || opts.PersistTo != 0 || opts.ReplicateTo != 0 {
+		t.Fatalf("Expected options to not be modified")
+	}
+
+	ops := []BulkOp{&UpsertOp{Key: "durabilityFallback", Value: "test"}}
+	err = col.Do(ops, &BulkOpOptions{DurabilityLevel: DurabilityLevelMajority})
+	if err != nil {
+		t.Fatalf("Expected Do to not error %v", err)
+	}
+
+	upsertOp := ops[0].(*UpsertOp)
+	if upsertOp.Err != nil {
+		t.Fatalf("Expected UpsertOp Err to be nil but was %v", upsertOp.Err)
+	}
+
+	if upsertOp.Result.DurabilityMode() != DurabilityModeObserve {
+		t.Fatalf("Expected durability mode to be observe but was %d", upsertOp.Result.DurabilityMode())
+	}
+}
+
+func TestDurabilityFallbackBulkRetry(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:         gocbcore.Cas(1),
+		numReplicas: 1,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.UseMutationTokens = true
+	col.sb.DuraTimeout = 1000 * time.Millisecond
+	col.sb.DurabilityFallback = true
+	col.sb.getCachedClient().(*mockClient).durabilityUnsupported = true
+
+	ops := []BulkOp{
+		&UpsertOp{Key: "durabilityFallbackBulk1", Value: "test"},
+		&UpsertOp{Key: "durabilityFallbackBulk2", Value: "test"},
+	}
+	err := col.Do(ops, &BulkOpOptions{DurabilityLevel: DurabilityLevelMajority})
+	if err != nil {
+		t.Fatalf("Expected Do to not error %v", err)
+	}
+
+	for _, op := range ops {
+		upsertOp := op.(*UpsertOp)
+		if upsertOp.Err != nil {
+			t.Fatalf("Expected UpsertOp Err to be nil but was %v", upsertOp.Err)
+		}
+
+		if upsertOp.Result.DurabilityMode() != DurabilityModeObserve {
+			t.Fatalf("Expected durability mode to be observe but was %d", upsertOp.Result.DurabilityMode())
+		}
+	}
+}
+
+func TestDurabilityModeEnhanced(t *testing.T) {
+	provider := &mockKvProvider{
+		cas: gocbcore.Cas(1),
+	}
+	col := testGetCollection(t, provider)
+	col.sb.DurabilityFallback = true
+
+	res, err := col.Upsert("durabilityModeEnhanced", "test", &UpsertOptions{DurabilityLevel: DurabilityLevelMajority})
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	if res.DurabilityMode() != DurabilityModeEnhanced {
+		t.Fatalf("Expected durability mode to be enhanced but was %d", res.DurabilityMode())
+	}
+}
+
+func TestClusterSupportsEnhancedDurability(t *testing.T) {
+	var compatibility []int
+	provider := &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			if req.Service != gocbcore.MgmtService || req.Path != "/pools/default" || req.Method != "GET" {
+				t.Fatalf("Unexpected request %+v", req)
+			}
+
+			var nodes []string
+			for _, compat := range compatibility {
+				nodes = append(nodes, fmt.Sprintf(`{"clusterCompatibility":%d}`, compat))
+			}
+
+			return &gocbcore.HttpResponse{
+				StatusCode: 200,
+				Body:       &testReadCloser{bytes.NewBufferString(`{"nodes":[` + strings.Join(nodes, ",") + `]}`), nil},
+			}, nil
+		},
+	}
+
+	compatibility = []int{6<<16 | 5, 6<<16 | 5}
+	supported, err := clusterSupportsEnhancedDurability(context.Background(), provider)
+	if err != nil || !supported {
+		t.Fatalf("Expected 6.5 cluster to support enhanced durability but was %t, %v", supported, err)
+	}
+
+	compatibility = []int{6<<16 | 0, 6<<16 | 5}
+	supported, err = clusterSupportsEnhancedDurability(context.Background(), provider)
+	if err != nil || supported {
+		t.Fatalf("Expected partially upgraded cluster not to support enhanced durability but was %t, %v", supported, err)
+	}
+
+	compatibility = nil
+	_, err = clusterSupportsEnhancedDurability(context.Background(), provider)
+	if err == nil {
+		t.Fatalf("Expected cluster without nodes to fail detection")
+	}
+}
@@ -0,0 +1,407 @@
+package gocb
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+// HotKeyOptions enables sampling of the keys accessed through a collection, so that hot keys can be found using
+// Cluster.KvStats. Every opening of a collection within a cluster shares one sampler, so they must all use the
+// same options, once defaults are applied. Operations on a collection opened with different options fail with
+// an invalid arguments error.
+type HotKeyOptions struct {
+	// TopK is the number of keys reported for each measure, defaults to 10.
+	TopK int
+	// Capacity is the number of keys tracked for each measure, larger values give more accurate results at the
+	// cost of memory. Defaults to 10 times TopK.
+	Capacity int
+	// Window is the length of each sampling window, defaults to 1 minute.
+	Window time.Duration
+}
+
+// KvKeyStats are the statistics recorded for a single key within a sampling window.
+type KvKeyStats struct {
+	Key        string
+	Operations uint64
+	Bytes      uint64
+	Latency    time.Duration
+	// Error is the maximum amount by which the measure used to rank the key may be overestimated. Keys are only
+	// tracked whilst they are amongst the heaviest, so statistics for a key may not cover the whole window.
+	Error uint64
+}
+
+// KvStatsWindow are the statistics recorded for a collection within a sampling window.
+type KvStatsWindow struct {
+	Start           time.Time
+	End             time.Time
+	Operations      uint64
+	Bytes           uint64
+	TopByOperations []KvKeyStats
+	TopByBytes      []KvKeyStats
+	TopByLatency    []KvKeyStats
+}
+
+// CollectionKvStats are the statistics recorded for a collection which has hot key sampling enabled.
+type CollectionKvStats struct {
+	BucketName     string
+	ScopeName      string
+	CollectionName string
+	// Current is the window which is currently being recorded.
+	Current KvStatsWindow
+	// Previous is the most recently completed window, nil if no window has completed yet.
+	Previous *KvStatsWindow
+}
+
+// KvStatsResult encapsulates the results of a KvStats operation.
+type KvStatsResult struct {
+	Collections []CollectionKvStats
+}
+
+// kvStatsRegistry holds the hot key samplers of every collection opened from a cluster.
+type kvStatsRegistry struct {
+	lock     sync.Mutex
+	samplers map[string]*hotKeySampler
+}
+
+func newKvStatsRegistry() *kvStatsRegistry {
+	return &kvStatsRegistry{
+		samplers: make(map[string]*hotKeySampler),
+	}
+}
+
+// samplerFor returns the sampler for a collection, collections opened more than once share a sampler. An error is
+// returned if the collection was already opened with different options.
+func (r *kvStatsRegistry) samplerFor(sb *stateBlock, opts HotKeyOptions) (*hotKeySampler, error) {
+	if r == nil {
+		return newHotKeySampler(sb, opts), nil
+	}
+
+	id := sb.BucketName + "/" + sb.ScopeName + "/" + sb.CollectionName
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	sampler, ok := r.samplers[id]
+	if !ok {
+		sampler = newHotKeySampler(sb, opts)
+		r.samplers[id] = sampler
+	} else if sampler.opts != opts.withDefaults() {
+		return nil, invalidArgumentsError{message: "collection " + id + " is already sampled with different hot key options"}
+	}
+
+	return sampler, nil
+}
+
+func (r *kvStatsRegistry) stats() *KvStatsResult {
+	result := &KvStatsResult{}
+	if r == nil {
+		return result
+	}
+
+	r.lock.Lock()
+	samplers := make([]*hotKeySampler, 0, len(r.samplers))
+	for _, sampler := range r.samplers {
+		samplers = append(samplers, sampler)
+	}
+	r.lock.Unlock()
+
+	for _, sampler := range samplers {
+		result.Collections = append(result.Collections, sampler.stats())
+	}
+
+	sort.Slice(result.Collections, func(i, j int) bool {
+		a, b := result.Collections[i], result.Collections[j]
+		if a.BucketName != b.BucketName {
+			return a.BucketName < b.BucketName
+		}
+		if a.ScopeName != b.ScopeName {
+			return a.ScopeName < b.ScopeName
+		}
+		return a.CollectionName < b.CollectionName
+	})
+
+	return result
+}
+
+type hotKeyCounter struct {
+	key    string
+	weight uint64
+	err    uint64
+	// index is the position of the counter within the heap of its summary.
+	index int
+
+	operations uint64
+	bytes      uint64
+	latency    time.Duration
+}
+
+// hotKeyHeap is a min-heap of counters ordered by weight, so that the lightest key can be found without scanning
+// every counter.
+type hotKeyHeap []*hotKeyCounter
+
+func (h hotKeyHeap) Len() int {
+	return len(h)
+}
+
+func (h hotKeyHeap) Less(i, j int) bool {
+	return h[i].weight < h[j].weight
+}
+
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotKeyHeap) Push(x interface{}) {
+	counter := x.(*hotKeyCounter)
+	counter.index = len(*h)
+	*h = append(*h, counter)
+}
+
+func (h *hotKeyHeap) Pop() interface{} {
+	old := *h
+	counter := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return counter
+}
+
+// spaceSaving tracks the heaviest keys using the space-saving algorithm, which bounds memory to capacity keys.
+// When a key which is not tracked arrives and the summary is full, the lightest key is evicted and the new key
+// inherits its weight as the error.
+type spaceSaving struct {
+	capacity int
+	counters map[string]*hotKeyCounter
+	heap     hotKeyHeap
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{
+		capacity: capacity,
+		counters: make(map[string]*hotKeyCounter, capacity),
+		heap:     make(hotKeyHeap, 0, capacity),
+	}
+}
+
+func (s *spaceSaving) add(key string, weight, bytes uint64, latency time.Duration) {
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &hotKeyCounter{key: key}
+		if len(s.counters) >= s.capacity {
+			// The new key takes the place of the lightest key at the root of the heap.
+			min := s.heap[0]
+			delete(s.counters, min.key)
+
+			counter.weight = min.weight
+			counter.err = min.weight
+			counter.index = 0
+			s.heap[0] = counter
+		} else {
+			heap.Push(&s.heap, counter)
+		}
+		s.counters[key] = counter
+	}
+
+	counter.weight += weight
+	counter.operations++
+	counter.bytes += bytes
+	counter.latency += latency
+	heap.Fix(&s.heap, counter.index)
+}
+
+func (s *spaceSaving) top(k int) []KvKeyStats {
+	counters := make([]*hotKeyCounter, 0, len(s.counters))
+	for _, counter := range s.counters {
+		counters = append(counters, counter)
+	}
+
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].weight != counters[j].weight {
+			return counters[i].weight > counters[j].weight
+		}
+		return counters[i].key < counters[j].key
+	})
+
+	if len(counters) > k {
+		counters = counters[:k]
+	}
+
+	stats := make([]KvKeyStats, len(counters))
+	for i, counter := range counters {
+		stats[i] = KvKeyStats{
+			Key:        redactUserData(counter.key),
+			Operations: counter.operations,
+			Bytes:      counter.bytes,
+			Latency:    counter.latency,
+			Error:      counter.err,
+		}
+	}
+
+	return stats
+}
+
+type hotKeyWindow struct {
+	start      time.Time
+	operations uint64
+	bytes      uint64
+	byOps      *spaceSaving
+	byBytes    *spaceSaving
+	byLatency  *spaceSaving
+}
+
+// hotKeySampler is a KvInterceptor which records the operations made by a collection.
+type hotKeySampler struct {
+	bucketName     string
+	scopeName      string
+	collectionName string
+
+	opts     HotKeyOptions
+	topK     int
+	capacity int
+	window   time.Duration
+	now      func() time.Time
+
+	lock     sync.Mutex
+	current  *hotKeyWindow
+	previous *KvStatsWindow
+}
+
+// withDefaults returns the options with defaults applied to any which are unset.
+func (opts HotKeyOptions) withDefaults() HotKeyOptions {
+	if opts.TopK <= 0 {
+		opts.TopK = 10
+	}
+	if opts.Capacity < opts.TopK {
+		opts.Capacity = 10 * opts.TopK
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Minute
+	}
+
+	return opts
+}
+
+func newHotKeySampler(sb *stateBlock, opts HotKeyOptions) *hotKeySampler {
+	opts = opts.withDefaults()
+
+	sampler := &hotKeySampler{
+		bucketName:     sb.BucketName,
+		scopeName:      sb.ScopeName,
+		collectionName: sb.CollectionName,
+		opts:           opts,
+		topK:           opts.TopK,
+		capacity:       opts.Capacity,
+		window:         opts.Window,
+		now:            time.Now,
+	}
+	sampler.current = sampler.newWindow(sampler.now())
+
+	return sampler
+}
+
+func (s *hotKeySampler) newWindow(start time.Time) *hotKeyWindow {
+	return &hotKeyWindow{
+		start:     start,
+		byOps:     newSpaceSaving(s.capacity),
+		byBytes:   newSpaceSaving(s.capacity),
+		byLatency: newSpaceSaving(s.capacity),
+	}
+}
+
+func (s *hotKeySampler) snapshot(window *hotKeyWindow, end time.Time) KvStatsWindow {
+	return KvStatsWindow{
+		Start:           window.start,
+		End:             end,
+		Operations:      window.operations,
+		Bytes:           window.bytes,
+		TopByOperations: window.byOps.top(s.topK),
+		TopByBytes:      window.byBytes.top(s.topK),
+		TopByLatency:    window.byLatency.top(s.topK),
+	}
+}
+
+// rotate must be called with the lock held.
+func (s *hotKeySampler) rotate(now time.Time) {
+	end := s.current.start.Add(s.window)
+	if now.Before(end) {
+		return
+	}
+
+	previous := s.snapshot(s.current, end)
+	s.previous = &previous
+	s.current = s.newWindow(now)
+}
+
+func (s *hotKeySampler) record(key string, bytes uint64, latency time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.rotate(s.now())
+
+	window := s.current
+	window.operations++
+	window.bytes += bytes
+	window.byOps.add(key, 1, bytes, latency)
+	window.byBytes.add(key, bytes, bytes, latency)
+	window.byLatency.add(key, uint64(latency), bytes, latency)
+}
+
+func (s *hotKeySampler) stats() CollectionKvStats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.rotate(now)
+
+	return CollectionKvStats{
+		BucketName:     s.bucketName,
+		ScopeName:      s.scopeName,
+		CollectionName: s.collectionName,
+		Current:        s.snapshot(s.current, now),
+		Previous:       s.previous,
+	}
+}
+
+func (s *hotKeySampler) InterceptKv(req *KvRequest, next KvHandler) (*KvResponse, error) {
+	start := time.Now()
+	res, err := next(req)
+	s.completeKv(req, res, start)
+
+	return res, err
+}
+
+func (s *hotKeySampler) interceptKvAsync(req *KvRequest, next kvExecFunc, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+	start := time.Now()
+	op, err := next(req, func(res *KvResponse, err error) {
+		s.completeKv(req, res, start)
+		cb(res, err)
+	})
+	if err != nil {
+		s.completeKv(req, nil, start)
+		return nil, err
+	}
+
+	return kvCancelHook{PendingOp: op, onCancel: func() { s.completeKv(req, nil, start) }}, nil
+}
+
+// completeKv records the access made by req, which started at start, once it has completed.
+func (s *hotKeySampler) completeKv(req *KvRequest, res *KvResponse, start time.Time) {
+	latency := time.Since(start)
+
+	bytes := uint64(len(req.Value))
+	for _, op := range req.Ops {
+		bytes += uint64(len(op.Value))
+	}
+	if res != nil {
+		bytes += uint64(len(res.Value))
+		for _, op := range res.Ops {
+			bytes += uint64(len(op.Value))
+		}
+	}
+
+	s.record(req.Key, bytes, latency)
+}
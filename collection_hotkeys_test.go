@@ -0,0 +1,203 @@
+package gocb
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func TestSpaceSavingTopK(t *testing.T) {
+	summary := newSpaceSaving(10)
+	for i := 0; i < 100; i++ {
+		summary.add("hot", 1, 10, time.Millisecond)
+		summary.add(fmt.Sprintf("cold%d", i), 1, 10, time.Millisecond)
+		if i%2 == 0 {
+			summary.add("warm", 1, 10, time.Millisecond)
+		}
+	}
+
+	top := summary.top(2)
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 keys but was %d", len(top))
+	}
+
+	if top[0].Key != "hot" || top[0].Operations != 100 || top[0].Bytes != 1000 || top[0].Error != 0 {
+		t.Fatalf("Expected hot to be the top key with 100 operations but was %+v", top[0])
+	}
+
+	if top[1].Key != "warm" {
+		t.Fatalf("Expected warm to be the second key but was %+v", top[1])
+	}
+
+	if len(summary.counters) != 10 {
+		t.Fatalf("Expected the summary to be bounded to 10 keys but was %d", len(summary.counters))
+	}
+}
+
+func TestSpaceSavingEvictsLightest(t *testing.T) {
+	summary := newSpaceSaving(3)
+	summary.add("a", 5, 0, 0)
+	summary.add("b", 1, 0, 0)
+	summary.add("c", 3, 0, 0)
+	summary.add("b", 1, 0, 0)
+	summary.add("d", 1, 0, 0)
+
+	if _, ok := summary.counters["b"]; ok {
+		t.Fatalf("Expected the lightest key to be evicted")
+	}
+
+	counter := summary.counters["d"]
+	if counter == nil || counter.weight != 3 || counter.err != 2 {
+		t.Fatalf("Expected the new key to inherit the weight of the evicted key but was %+v", counter)
+	}
+
+	for i, counter := range summary.heap {
+		if counter.index != i || i > 0 && summary.heap[(i-1)/2].weight > counter.weight {
+			t.Fatalf("Expected counters to be ordered as a min-heap but were %+v", summary.heap)
+		}
+	}
+}
+
+func TestHotKeySamplerWindows(t *testing.T) {
+	sampler := newHotKeySampler(&stateBlock{CollectionName: "hot"}, HotKeyOptions{TopK: 1, Window: time.Minute})
+	now := time.Unix(1571057032, 0)
+	sampler.now = func() time.Time {
+		return now
+	}
+	sampler.current = sampler.newWindow(now)
+
+	sampler.record("a", 10, time.Millisecond)
+	sampler.record("b", 100, 5*time.Millisecond)
+	sampler.record("a", 10, time.Millisecond)
+
+	stats := sampler.stats()
+	if stats.Previous != nil {
+		t.Fatalf("Expected no previous window")
+	}
+
+	if stats.Current.Operations != 3 || stats.Current.Bytes != 120 {
+		t.Fatalf("Expected 3 operations and 120 bytes but was %d and %d", stats.Current.Operations, stats.Current.Bytes)
+	}
+
+	if stats.Current.TopByOperations[0].Key != "a" || stats.Current.TopByBytes[0].Key != "b" ||
+		stats.Current.TopByLatency[0].Key != "b" {
+		t.Fatalf("Top keys were not as expected: %+v", stats.Current)
+	}
+
+	now = now.Add(90 * time.Second)
+	sampler.record("c", 1, time.Millisecond)
+
+	stats = sampler.stats()
+	if stats.Previous == nil || stats.Previous.Operations != 3 {
+		t.Fatalf("Expected the previous window to contain 3 operations but was %+v", stats.Previous)
+	}
+
+	if !stats.Previous.End.Equal(time.Unix(1571057032, 0).Add(time.Minute)) {
+		t.Fatalf("Expected the previous window to end after a minute but was %v", stats.Previous.End)
+	}
+
+	if stats.Current.Operations != 1 || stats.Current.TopByOperations[0].Key != "c" {
+		t.Fatalf("Expected the current window to only contain c but was %+v", stats.Current)
+	}
+}
+
+func TestHotKeyRedaction(t *testing.T) {
+	defer SetLogRedactionLevel(RedactNone)
+
+	summary := newSpaceSaving(1)
+	summary.add("secret", 1, 0, 0)
+
+	SetLogRedactionLevel(RedactPartial)
+	if key := summary.top(1)[0].Key; key != "<ud>secret</ud>" {
+		t.Fatalf("Expected key to be tagged but was %s", key)
+	}
+
+	SetLogRedactionLevel(RedactFull)
+	if key := summary.top(1)[0].Key; key != "<ud>e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4</ud>" {
+		t.Fatalf("Expected key to be hashed but was %s", key)
+	}
+}
+
+func TestClusterKvStats(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`{"a":"b"}`),
+		flags: 0x02000000,
+	}
+	col := testGetCollection(t, provider)
+	registry := newKvStatsRegistry()
+	col.sb.KvStats = registry
+
+	s := &Scope{sb: col.sb}
+	hot := s.Collection("_default", &CollectionOptions{HotKeys: &HotKeyOptions{}})
+	for i := 0; i < 3; i++ {
+		_, err := hot.Get("hotKey", nil)
+		if err != nil {
+			t.Fatalf("Expected Get to not error %v", err)
+		}
+	}
+
+	cluster := testGetClusterForHTTP(&mockHTTPProvider{}, 0, 0, 0)
+	cluster.sb.KvStats = registry
+	cluster.connections["mock-false"].(*mockClient).mockDiagnosticsProvider = &mockDiagnosticsProvider{
+		info: &gocbcore.DiagnosticInfo{},
+	}
+
+	stats := cluster.KvStats()
+	if len(stats.Collections) != 1 {
+		t.Fatalf("Expected stats for 1 collection but was %d", len(stats.Collections))
+	}
+
+	window := stats.Collections[0].Current
+	if window.Operations != 3 || len(window.TopByOperations) != 1 || window.TopByOperations[0].Key != "hotKey" {
+		t.Fatalf("Expected hotKey to have 3 operations but was %+v", window)
+	}
+
+	report, err := cluster.Diagnostics(nil)
+	if err != nil {
+		t.Fatalf("Expected Diagnostics to not error %v", err)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Expected report to marshal %v", err)
+	}
+
+	var jsonReport jsonDiagnosticReport
+	err = json.Unmarshal(data, &jsonReport)
+	if err != nil {
+		t.Fatalf("Expected report to unmarshal %v", err)
+	}
+
+	if len(jsonReport.KvStats) != 1 || jsonReport.KvStats[0].Current.TopByOperations[0].Operations != 3 {
+		t.Fatalf("Expected report to contain kv stats but was %s", data)
+	}
+}
+
+func TestHotKeyOptionsMismatch(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`{"a":"b"}`),
+		flags: 0x02000000,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.KvStats = newKvStatsRegistry()
+
+	s := &Scope{sb: col.sb}
+	s.Collection("_default", &CollectionOptions{HotKeys: &HotKeyOptions{}})
+
+	same := s.Collection("_default", &CollectionOptions{HotKeys: &HotKeyOptions{TopK: 10, Window: time.Minute}})
+	_, err := same.Get("hotKey", nil)
+	if err != nil {
+		t.Fatalf("Expected Get with the default options to not error %v", err)
+	}
+
+	different := s.Collection("_default", &CollectionOptions{HotKeys: &HotKeyOptions{TopK: 5}})
+	_, err = different.Get("hotKey", nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected Get with different options to be invalid but was %v", err)
+	}
+}
@@ -0,0 +1,752 @@
+package gocb
+
+import (
+	"sync"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
+)
+
+// KvRequest is a key-value request as seen by a KvInterceptor. Interceptors may modify the request before
+// passing it on, fields which are not relevant to the operation are ignored.
+type KvRequest struct {
+	Operation      KvOperation
+	BucketName     string
+	ScopeName      string
+	CollectionName string
+	Key            string
+	// Value is the encoded document, or the encoded fragment for Append and Prepend.
+	Value           []byte
+	Flags           uint32
+	Datatype        uint8
+	Expiry          uint32
+	LockTime        uint32
+	Cas             Cas
+	Delta           uint64
+	Initial         uint64
+	ReplicaIdx      int
+	DurabilityLevel DurabilityLevel
+	DocFlags        SubdocDocFlag
+	// Ops are the sub-document operations of a LookupIn or MutateIn, ops can be modified but not added or removed.
+	Ops []KvSubdocOp
+}
+
+// KvSubdocOp is a single sub-document operation within a KvRequest.
+type KvSubdocOp struct {
+	op    gocbcore.SubDocOpType
+	Path  string
+	Flags SubdocFlag
+	Value []byte
+}
+
+// KvResponse is a key-value response as seen by a KvInterceptor. Interceptors may modify the response, or create
+// their own to respond to a request without it being sent to the server.
+type KvResponse struct {
+	Cas           Cas
+	Value         []byte
+	Flags         uint32
+	Datatype      uint8
+	Counter       uint64
+	MutationToken MutationToken
+	// Ops are the results of the sub-document operations of a LookupIn or MutateIn, in the same order as the
+	// request ops.
+	Ops []KvSubdocResult
+}
+
+// KvSubdocResult is the result of a single sub-document operation within a KvResponse.
+type KvSubdocResult struct {
+	Value []byte
+	Err   error
+}
+
+// KvHandler performs a key-value request, returning its response.
+type KvHandler func(req *KvRequest) (*KvResponse, error)
+
+// KvInterceptor can be used to observe, modify or short-circuit the key-value requests made by a Collection.
+// An interceptor is given the request and the next handler in the chain, which it may call any number of times,
+// including zero times in which case the interceptor must return a response or error itself. Some operations,
+// such as LookupIn, may return both a response and an error.
+//
+// Interceptors are invoked on their own goroutine, as they may block waiting for the request, and must be safe for
+// concurrent use. Interceptors are not invoked for the observe requests used by PersistTo and ReplicateTo
+// durability.
+type KvInterceptor interface {
+	InterceptKv(req *KvRequest, next KvHandler) (*KvResponse, error)
+}
+
+// KvInterceptorFunc allows an ordinary function to be used as a KvInterceptor.
+type KvInterceptorFunc func(req *KvRequest, next KvHandler) (*KvResponse, error)
+
+// InterceptKv calls f(req, next).
+func (f KvInterceptorFunc) InterceptKv(req *KvRequest, next KvHandler) (*KvResponse, error) {
+	return f(req, next)
+}
+
+type kvResponseCallback func(*KvResponse, error)
+
+type kvExecFunc func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error)
+
+// interceptedKvProvider runs each key-value request through the interceptors of a collection before passing it
+// to the underlying provider.
+type interceptedKvProvider struct {
+	kvProvider
+	bucketName   string
+	interceptors []KvInterceptor
+}
+
+// interceptedPendingOp tracks a request whilst it passes through the interceptor chain. Once cancelled the
+// callback for the request is never invoked, matching the behaviour of the underlying provider.
+type interceptedPendingOp struct {
+	lock     sync.Mutex
+	inner    gocbcore.PendingOp
+	done     bool
+	cancelCh chan struct{}
+}
+
+func (op *interceptedPendingOp) Cancel() bool {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+
+	if op.done {
+		return false
+	}
+	op.done = true
+	close(op.cancelCh)
+
+	if op.inner != nil {
+		op.inner.Cancel()
+	}
+
+	return true
+}
+
+func (op *interceptedPendingOp) setInner(inner gocbcore.PendingOp) bool {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+
+	if op.done {
+		return false
+	}
+	op.inner = inner
+
+	return true
+}
+
+func (op *interceptedPendingOp) finish() bool {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+
+	if op.done {
+		return false
+	}
+	op.done = true
+
+	return true
+}
+
+type kvOutcome struct {
+	res *KvResponse
+	err error
+}
+
+func (p *interceptedKvProvider) newRequest(operation KvOperation, key []byte, scopeName, collectionName string) *KvRequest {
+	return &KvRequest{
+		Operation:      operation,
+		BucketName:     p.bucketName,
+		ScopeName:      scopeName,
+		CollectionName: collectionName,
+		Key:            string(key),
+	}
+}
+
+func (p *interceptedKvProvider) mutationToken(token gocbcore.MutationToken) MutationToken {
+	return MutationToken{
+		token:      token,
+		bucketName: p.bucketName,
+	}
+}
+
+// asyncKvInterceptor is implemented by the interceptors built into the SDK. They never block, so they are run on
+// the goroutine making the request and pass the response on from the callback of the underlying request, rather
+// than each request needing a goroutine to run the interceptor chain on.
+type asyncKvInterceptor interface {
+	interceptKvAsync(req *KvRequest, next kvExecFunc, cb kvResponseCallback) (gocbcore.PendingOp, error)
+}
+
+// kvCancelHook calls onCancel when the request is cancelled before it completes, in which case the callback of the
+// request is never invoked.
+type kvCancelHook struct {
+	gocbcore.PendingOp
+	onCancel func()
+}
+
+func (op kvCancelHook) Cancel() bool {
+	if !op.PendingOp.Cancel() {
+		return false
+	}
+
+	op.onCancel()
+	return true
+}
+
+func (p *interceptedKvProvider) intercept(req *KvRequest, exec kvExecFunc, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+	// Interceptors at the end of the chain which never block are run without handing off to another goroutine.
+	numSync := len(p.interceptors)
+	for ; numSync > 0; numSync-- {
+		interceptor, ok := p.interceptors[numSync-1].(asyncKvInterceptor)
+		if !ok {
+			break
+		}
+
+		next := exec
+		exec = func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+			return interceptor.interceptKvAsync(req, next, cb)
+		}
+	}
+
+	if numSync == 0 {
+		return exec(req, cb)
+	}
+
+	op := &interceptedPendingOp{
+		cancelCh: make(chan struct{}),
+	}
+
+	handler := func(req *KvRequest) (*KvResponse, error) {
+		outcomeCh := make(chan kvOutcome, 1)
+		inner, err := exec(req, func(res *KvResponse, err error) {
+			outcomeCh <- kvOutcome{res: res, err: err}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if !op.setInner(inner) {
+			inner.Cancel()
+			return nil, gocbcore.ErrCancelled
+		}
+
+		select {
+		case outcome := <-outcomeCh:
+			return outcome.res, outcome.err
+		case <-op.cancelCh:
+			return nil, gocbcore.ErrCancelled
+		}
+	}
+
+	for i := numSync - 1; i >= 0; i-- {
+		interceptor := p.interceptors[i]
+		next := handler
+		handler = func(req *KvRequest) (*KvResponse, error) {
+			return interceptor.InterceptKv(req, next)
+		}
+	}
+
+	go func() {
+		res, err := handler(req)
+		if res == nil && err == nil {
+			err = errors.New("kv interceptor returned neither a response nor an error")
+		}
+
+		if op.finish() {
+			cb(res, err)
+		}
+	}()
+
+	return op, nil
+}
+
+func subdocOpsToRequest(ops []gocbcore.SubDocOp) []KvSubdocOp {
+	reqOps := make([]KvSubdocOp, len(ops))
+	for i, op := range ops {
+		reqOps[i] = KvSubdocOp{
+			op:    op.Op,
+			Path:  op.Path,
+			Flags: SubdocFlag(op.Flags),
+			Value: op.Value,
+		}
+	}
+
+	return reqOps
+}
+
+func subdocOpsFromRequest(req *KvRequest, numOps int) ([]gocbcore.SubDocOp, error) {
+	if len(req.Ops) != numOps {
+		return nil, invalidArgumentsError{message: "kv interceptors cannot add or remove sub-document operations"}
+	}
+
+	ops := make([]gocbcore.SubDocOp, len(req.Ops))
+	for i, op := range req.Ops {
+		ops[i] = gocbcore.SubDocOp{
+			Op:    op.op,
+			Path:  op.Path,
+			Flags: gocbcore.SubdocFlag(op.Flags),
+			Value: op.Value,
+		}
+	}
+
+	return ops, nil
+}
+
+func subdocResultsToResponse(results []gocbcore.SubDocResult) []KvSubdocResult {
+	resOps := make([]KvSubdocResult, len(results))
+	for i, result := range results {
+		resOps[i] = KvSubdocResult{
+			Value: result.Value,
+			Err:   result.Err,
+		}
+	}
+
+	return resOps
+}
+
+func subdocResultsFromResponse(results []KvSubdocResult) []gocbcore.SubDocResult {
+	resOps := make([]gocbcore.SubDocResult, len(results))
+	for i, result := range results {
+		resOps[i] = gocbcore.SubDocResult{
+			Value: result.Value,
+			Err:   result.Err,
+		}
+	}
+
+	return resOps
+}
+
+func (p *interceptedKvProvider) GetEx(opts gocbcore.GetOptions, cb gocbcore.GetExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationGet, opts.Key, opts.ScopeName, opts.CollectionName)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		return p.kvProvider.GetEx(reqOpts, func(res *gocbcore.GetResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.GetResult{Cas: gocbcore.Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+	})
+}
+
+func (p *interceptedKvProvider) GetAndTouchEx(opts gocbcore.GetAndTouchOptions, cb gocbcore.GetAndTouchExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationGetAndTouch, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Expiry = opts.Expiry
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Expiry = req.Expiry
+		return p.kvProvider.GetAndTouchEx(reqOpts, func(res *gocbcore.GetAndTouchResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.GetAndTouchResult{Cas: gocbcore.Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+	})
+}
+
+func (p *interceptedKvProvider) GetAndLockEx(opts gocbcore.GetAndLockOptions, cb gocbcore.GetAndLockExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationGetAndLock, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.LockTime = opts.LockTime
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.LockTime = req.LockTime
+		return p.kvProvider.GetAndLockEx(reqOpts, func(res *gocbcore.GetAndLockResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.GetAndLockResult{Cas: gocbcore.Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+	})
+}
+
+func (p *interceptedKvProvider) GetAnyReplicaEx(opts gocbcore.GetAnyReplicaOptions, cb gocbcore.GetReplicaExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationGetAnyReplica, opts.Key, opts.ScopeName, opts.CollectionName)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		return p.kvProvider.GetAnyReplicaEx(reqOpts, func(res *gocbcore.GetReplicaResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.GetReplicaResult{Cas: gocbcore.Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+	})
+}
+
+func (p *interceptedKvProvider) GetOneReplicaEx(opts gocbcore.GetOneReplicaOptions, cb gocbcore.GetReplicaExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationGetReplica, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.ReplicaIdx = opts.ReplicaIdx
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.ReplicaIdx = req.ReplicaIdx
+		return p.kvProvider.GetOneReplicaEx(reqOpts, func(res *gocbcore.GetReplicaResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.GetReplicaResult{Cas: gocbcore.Cas(res.Cas), Value: res.Value, Flags: res.Flags, Datatype: res.Datatype}, err)
+	})
+}
+
+func (p *interceptedKvProvider) TouchEx(opts gocbcore.TouchOptions, cb gocbcore.TouchExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationTouch, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Expiry = opts.Expiry
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Expiry = req.Expiry
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		return p.kvProvider.TouchEx(reqOpts, func(res *gocbcore.TouchResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), MutationToken: p.mutationToken(res.MutationToken)}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.TouchResult{Cas: gocbcore.Cas(res.Cas), MutationToken: res.MutationToken.token}, err)
+	})
+}
+
+func (p *interceptedKvProvider) UnlockEx(opts gocbcore.UnlockOptions, cb gocbcore.UnlockExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationUnlock, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Cas = Cas(opts.Cas)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Cas = gocbcore.Cas(req.Cas)
+		return p.kvProvider.UnlockEx(reqOpts, func(res *gocbcore.UnlockResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), MutationToken: p.mutationToken(res.MutationToken)}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.UnlockResult{Cas: gocbcore.Cas(res.Cas), MutationToken: res.MutationToken.token}, err)
+	})
+}
+
+func (p *interceptedKvProvider) DeleteEx(opts gocbcore.DeleteOptions, cb gocbcore.DeleteExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationRemove, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Cas = Cas(opts.Cas)
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Cas = gocbcore.Cas(req.Cas)
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		return p.kvProvider.DeleteEx(reqOpts, func(res *gocbcore.DeleteResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), MutationToken: p.mutationToken(res.MutationToken)}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.DeleteResult{Cas: gocbcore.Cas(res.Cas), MutationToken: res.MutationToken.token}, err)
+	})
+}
+
+func (p *interceptedKvProvider) storeCallback(cb gocbcore.StoreExCallback) kvResponseCallback {
+	return func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.StoreResult{Cas: gocbcore.Cas(res.Cas), MutationToken: res.MutationToken.token}, err)
+	}
+}
+
+func (p *interceptedKvProvider) storeResponse(cb kvResponseCallback) gocbcore.StoreExCallback {
+	return func(res *gocbcore.StoreResult, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&KvResponse{Cas: Cas(res.Cas), MutationToken: p.mutationToken(res.MutationToken)}, err)
+	}
+}
+
+func (p *interceptedKvProvider) AddEx(opts gocbcore.AddOptions, cb gocbcore.StoreExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationInsert, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Value = opts.Value
+	req.Flags = opts.Flags
+	req.Datatype = opts.Datatype
+	req.Expiry = opts.Expiry
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Value = req.Value
+		reqOpts.Flags = req.Flags
+		reqOpts.Datatype = req.Datatype
+		reqOpts.Expiry = req.Expiry
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		return p.kvProvider.AddEx(reqOpts, p.storeResponse(cb))
+	}, p.storeCallback(cb))
+}
+
+func (p *interceptedKvProvider) SetEx(opts gocbcore.SetOptions, cb gocbcore.StoreExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationUpsert, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Value = opts.Value
+	req.Flags = opts.Flags
+	req.Datatype = opts.Datatype
+	req.Expiry = opts.Expiry
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Value = req.Value
+		reqOpts.Flags = req.Flags
+		reqOpts.Datatype = req.Datatype
+		reqOpts.Expiry = req.Expiry
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		return p.kvProvider.SetEx(reqOpts, p.storeResponse(cb))
+	}, p.storeCallback(cb))
+}
+
+func (p *interceptedKvProvider) ReplaceEx(opts gocbcore.ReplaceOptions, cb gocbcore.StoreExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationReplace, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Value = opts.Value
+	req.Flags = opts.Flags
+	req.Datatype = opts.Datatype
+	req.Expiry = opts.Expiry
+	req.Cas = Cas(opts.Cas)
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Value = req.Value
+		reqOpts.Flags = req.Flags
+		reqOpts.Datatype = req.Datatype
+		reqOpts.Expiry = req.Expiry
+		reqOpts.Cas = gocbcore.Cas(req.Cas)
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		return p.kvProvider.ReplaceEx(reqOpts, p.storeResponse(cb))
+	}, p.storeCallback(cb))
+}
+
+func (p *interceptedKvProvider) adjoin(operation KvOperation, opts gocbcore.AdjoinOptions, cb gocbcore.AdjoinExCallback,
+	exec func(gocbcore.AdjoinOptions, gocbcore.AdjoinExCallback) (gocbcore.PendingOp, error)) (gocbcore.PendingOp, error) {
+	req := p.newRequest(operation, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Value = opts.Value
+	req.Cas = Cas(opts.Cas)
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Value = req.Value
+		reqOpts.Cas = gocbcore.Cas(req.Cas)
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		return exec(reqOpts, func(res *gocbcore.AdjoinResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), MutationToken: p.mutationToken(res.MutationToken)}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.AdjoinResult{Cas: gocbcore.Cas(res.Cas), MutationToken: res.MutationToken.token}, err)
+	})
+}
+
+func (p *interceptedKvProvider) AppendEx(opts gocbcore.AdjoinOptions, cb gocbcore.AdjoinExCallback) (gocbcore.PendingOp, error) {
+	return p.adjoin(KvOperationAppend, opts, cb, p.kvProvider.AppendEx)
+}
+
+func (p *interceptedKvProvider) PrependEx(opts gocbcore.AdjoinOptions, cb gocbcore.AdjoinExCallback) (gocbcore.PendingOp, error) {
+	return p.adjoin(KvOperationPrepend, opts, cb, p.kvProvider.PrependEx)
+}
+
+func (p *interceptedKvProvider) counter(operation KvOperation, opts gocbcore.CounterOptions, cb gocbcore.CounterExCallback,
+	exec func(gocbcore.CounterOptions, gocbcore.CounterExCallback) (gocbcore.PendingOp, error)) (gocbcore.PendingOp, error) {
+	req := p.newRequest(operation, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.Delta = opts.Delta
+	req.Initial = opts.Initial
+	req.Expiry = opts.Expiry
+	req.Cas = Cas(opts.Cas)
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Delta = req.Delta
+		reqOpts.Initial = req.Initial
+		reqOpts.Expiry = req.Expiry
+		reqOpts.Cas = gocbcore.Cas(req.Cas)
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		return exec(reqOpts, func(res *gocbcore.CounterResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), Counter: res.Value, MutationToken: p.mutationToken(res.MutationToken)}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.CounterResult{Cas: gocbcore.Cas(res.Cas), Value: res.Counter, MutationToken: res.MutationToken.token}, err)
+	})
+}
+
+func (p *interceptedKvProvider) IncrementEx(opts gocbcore.CounterOptions, cb gocbcore.CounterExCallback) (gocbcore.PendingOp, error) {
+	return p.counter(KvOperationIncrement, opts, cb, p.kvProvider.IncrementEx)
+}
+
+func (p *interceptedKvProvider) DecrementEx(opts gocbcore.CounterOptions, cb gocbcore.CounterExCallback) (gocbcore.PendingOp, error) {
+	return p.counter(KvOperationDecrement, opts, cb, p.kvProvider.DecrementEx)
+}
+
+func (p *interceptedKvProvider) LookupInEx(opts gocbcore.LookupInOptions, cb gocbcore.LookupInExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationLookupIn, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.DocFlags = SubdocDocFlag(opts.Flags)
+	req.Ops = subdocOpsToRequest(opts.Ops)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		ops, err := subdocOpsFromRequest(req, len(opts.Ops))
+		if err != nil {
+			return nil, err
+		}
+
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Flags = gocbcore.SubdocDocFlag(req.DocFlags)
+		reqOpts.Ops = ops
+		return p.kvProvider.LookupInEx(reqOpts, func(res *gocbcore.LookupInResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{Cas: Cas(res.Cas), Ops: subdocResultsToResponse(res.Ops)}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.LookupInResult{Cas: gocbcore.Cas(res.Cas), Ops: subdocResultsFromResponse(res.Ops)}, err)
+	})
+}
+
+func (p *interceptedKvProvider) MutateInEx(opts gocbcore.MutateInOptions, cb gocbcore.MutateInExCallback) (gocbcore.PendingOp, error) {
+	req := p.newRequest(KvOperationMutateIn, opts.Key, opts.ScopeName, opts.CollectionName)
+	req.DocFlags = SubdocDocFlag(opts.Flags)
+	req.Cas = Cas(opts.Cas)
+	req.Expiry = opts.Expiry
+	req.DurabilityLevel = DurabilityLevel(opts.DurabilityLevel)
+	req.Ops = subdocOpsToRequest(opts.Ops)
+	return p.intercept(req, func(req *KvRequest, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+		ops, err := subdocOpsFromRequest(req, len(opts.Ops))
+		if err != nil {
+			return nil, err
+		}
+
+		reqOpts := opts
+		reqOpts.Key = []byte(req.Key)
+		reqOpts.Flags = gocbcore.SubdocDocFlag(req.DocFlags)
+		reqOpts.Cas = gocbcore.Cas(req.Cas)
+		reqOpts.Expiry = req.Expiry
+		reqOpts.DurabilityLevel = gocbcore.DurabilityLevel(req.DurabilityLevel)
+		reqOpts.Ops = ops
+		return p.kvProvider.MutateInEx(reqOpts, func(res *gocbcore.MutateInResult, err error) {
+			if res == nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(&KvResponse{
+				Cas:           Cas(res.Cas),
+				MutationToken: p.mutationToken(res.MutationToken),
+				Ops:           subdocResultsToResponse(res.Ops),
+			}, err)
+		})
+	}, func(res *KvResponse, err error) {
+		if res == nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(&gocbcore.MutateInResult{
+			Cas:           gocbcore.Cas(res.Cas),
+			MutationToken: res.MutationToken.token,
+			Ops:           subdocResultsFromResponse(res.Ops),
+		}, err)
+	})
+}
@@ -0,0 +1,43 @@
+package gocb
+
+import (
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func benchmarkInterceptedGet(b *testing.B, interceptors ...KvInterceptor) {
+	b.ReportAllocs()
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`"test"`),
+	}
+	col := testGetCollection(nil, provider)
+	col.interceptors = interceptors
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, err := col.Get("intercepted", nil)
+			if err != nil {
+				b.Fatalf("failed to get %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetNoInterceptors(b *testing.B) {
+	benchmarkInterceptedGet(b)
+}
+
+func BenchmarkGetBuiltinInterceptor(b *testing.B) {
+	sampler := newHotKeySampler(&stateBlock{CollectionName: "bench"}, HotKeyOptions{Window: time.Minute})
+	benchmarkInterceptedGet(b, sampler)
+}
+
+func BenchmarkGetInterceptor(b *testing.B) {
+	benchmarkInterceptedGet(b, KvInterceptorFunc(func(req *KvRequest, next KvHandler) (*KvResponse, error) {
+		return next(req)
+	}))
+}
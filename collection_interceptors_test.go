@@ -0,0 +1,198 @@
+package gocb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+type testKvRecorder struct {
+	lock     sync.Mutex
+	requests []KvRequest
+	latency  []time.Duration
+}
+
+func (r *testKvRecorder) InterceptKv(req *KvRequest, next KvHandler) (*KvResponse, error) {
+	start := time.Now()
+	res, err := next(req)
+
+	r.lock.Lock()
+	r.requests = append(r.requests, *req)
+	r.latency = append(r.latency, time.Since(start))
+	r.lock.Unlock()
+
+	return res, err
+}
+
+func TestInterceptorObservesUpsert(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:    gocbcore.Cas(10),
+		opWait: 10 * time.Millisecond,
+	}
+	col := testGetCollection(t, provider)
+	recorder := &testKvRecorder{}
+	col.interceptors = []KvInterceptor{recorder}
+
+	res, err := col.Upsert("interceptUpsert", map[string]string{"a": "b"}, &UpsertOptions{Expiration: ExpiryIn(time.Minute)})
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	if res.Cas() != Cas(10) {
+		t.Fatalf("Expected cas to be 10 but was %d", res.Cas())
+	}
+
+	if len(recorder.requests) != 1 {
+		t.Fatalf("Expected 1 request to be intercepted but was %d", len(recorder.requests))
+	}
+
+	req := recorder.requests[0]
+	if req.Operation != KvOperationUpsert || req.Key != "interceptUpsert" || req.BucketName != "mock" {
+		t.Fatalf("Request was not as expected: %+v", req)
+	}
+
+	if string(req.Value) != `{"a":"b"}` || req.Expiry != 60 {
+		t.Fatalf("Expected encoded value and expiry to be intercepted but was %s and %d", req.Value, req.Expiry)
+	}
+
+	if recorder.latency[0] < 10*time.Millisecond {
+		t.Fatalf("Expected latency to be at least 10ms but was %v", recorder.latency[0])
+	}
+}
+
+func TestInterceptorModifiesRequest(t *testing.T) {
+	provider := &mockKvProvider{}
+	provider.lookupInFn = func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error) {
+		if string(opts.Key) != "tenant::interceptLookupIn" {
+			t.Errorf("Expected key to be prefixed but was %s", opts.Key)
+		}
+
+		return &gocbcore.LookupInResult{
+			Cas: gocbcore.Cas(5),
+			Ops: []gocbcore.SubDocResult{{Value: []byte(`"value"`)}},
+		}, nil
+	}
+	col := testGetCollection(t, provider)
+	col.interceptors = []KvInterceptor{KvInterceptorFunc(func(req *KvRequest, next KvHandler) (*KvResponse, error) {
+		req.Key = "tenant::" + req.Key
+		return next(req)
+	})}
+
+	res, err := col.LookupIn("interceptLookupIn", []LookupInOp{LookupInSpec{}.Get("field", nil)}, nil)
+	if err != nil {
+		t.Fatalf("Expected LookupIn to not error %v", err)
+	}
+
+	var val string
+	err = res.ContentAt(0, &val)
+	if err != nil {
+		t.Fatalf("Expected ContentAt to not error %v", err)
+	}
+
+	if val != "value" {
+		t.Fatalf("Expected value to be value but was %s", val)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	provider := &mockKvProvider{
+		err: gocbcore.ErrKeyNotFound,
+	}
+	col := testGetCollection(t, provider)
+	col.interceptors = []KvInterceptor{KvInterceptorFunc(func(req *KvRequest, next KvHandler) (*KvResponse, error) {
+		if req.Operation != KvOperationGet {
+			return next(req)
+		}
+
+		return &KvResponse{
+			Cas:   Cas(20),
+			Value: []byte(`{"synthetic":true}`),
+			Flags: 0x02000000,
+		}, nil
+	})}
+
+	res, err := col.Get("interceptGet", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	var doc map[string]bool
+	err = res.Content(&doc)
+	if err != nil {
+		t.Fatalf("Expected Content to not error %v", err)
+	}
+
+	if !doc["synthetic"] || res.Cas() != Cas(20) {
+		t.Fatalf("Expected synthetic result but was %v with cas %d", doc, res.Cas())
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: uint64(2),
+	}
+	col := testGetCollection(t, provider)
+
+	var lock sync.Mutex
+	var order []string
+	interceptor := func(name string) KvInterceptor {
+		return KvInterceptorFunc(func(req *KvRequest, next KvHandler) (*KvResponse, error) {
+			lock.Lock()
+			order = append(order, name)
+			lock.Unlock()
+			return next(req)
+		})
+	}
+	col.interceptors = []KvInterceptor{interceptor("first"), interceptor("second")}
+
+	_, err := col.Binary().Increment("interceptIncrement", &CounterOptions{Delta: 1})
+	if err != nil {
+		t.Fatalf("Expected Increment to not error %v", err)
+	}
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("Expected interceptors to run in order but was %v", order)
+	}
+}
+
+func TestInterceptorBulk(t *testing.T) {
+	provider := &mockKvProvider{
+		cas: gocbcore.Cas(1),
+	}
+	col := testGetCollection(t, provider)
+	recorder := &testKvRecorder{}
+	col.interceptors = []KvInterceptor{recorder}
+
+	ops := []BulkOp{
+		&UpsertOp{Key: "interceptBulk1", Value: "a"},
+		&UpsertOp{Key: "interceptBulk2", Value: "b"},
+		&RemoveOp{Key: "interceptBulk3"},
+	}
+	err := col.Do(ops, nil)
+	if err != nil {
+		t.Fatalf("Expected Do to not error %v", err)
+	}
+
+	if len(recorder.requests) != 3 {
+		t.Fatalf("Expected 3 requests to be intercepted but was %d", len(recorder.requests))
+	}
+}
+
+func TestInterceptorTimeout(t *testing.T) {
+	provider := &mockKvProvider{
+		opCancellationSuccess: true,
+	}
+	col := testGetCollection(t, provider)
+	col.interceptors = []KvInterceptor{KvInterceptorFunc(func(req *KvRequest, next KvHandler) (*KvResponse, error) {
+		time.Sleep(200 * time.Millisecond)
+		return next(req)
+	})}
+
+	_, err := col.Remove("interceptTimeout", &RemoveOptions{Timeout: 50 * time.Millisecond})
+	if !IsTimeoutError(err) {
+		t.Fatalf("Expected timeout error but was %v", err)
+	}
+}
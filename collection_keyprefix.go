@@ -0,0 +1,395 @@
+package gocb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/couchbase/gocb/v2/n1ql"
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+// KeyPrefixPredicate is a placeholder for the WHERE clause of statements executed by Collection.NamespacedQuery.
+// It is replaced with the filter passed to NamespacedQuery, combined with a predicate which restricts the query to
+// documents within the key namespace of the collection.
+const KeyPrefixPredicate = "${KeyPrefix.predicate}"
+
+// KeyPrefixKey is a placeholder for use within the projection of statements executed by
+// Collection.NamespacedQuery. It is replaced with the document key of the keyspace with the key prefix of the
+// collection removed, so that keys returned by the query can be used with the namespaced collection.
+const KeyPrefixKey = "${KeyPrefix.key}"
+
+// keyPrefixParameter is the named parameter which the key prefix pattern is bound to by NamespacedQuery.
+const keyPrefixParameter = "gocbKeyPrefix"
+
+// keyPrefixValueParameter is the named parameter which the key prefix itself is bound to by NamespacedQuery.
+const keyPrefixValueParameter = "gocbKeyPrefixValue"
+
+var keyPrefixWhereRegexp = regexp.MustCompile(`(?i)\bWHERE\s+` + regexp.QuoteMeta(KeyPrefixPredicate))
+
+// WithKeyPrefix returns a view of the collection in which every key is transparently prefixed with prefix. This
+// applies to all key-value, sub-document, bulk and data structure operations performed through the view, so that
+// the view cannot address keys outside of its namespace. Calling WithKeyPrefix on a view nests the prefixes.
+// Interceptors and hot key statistics see the full key, including the prefix.
+func (c *Collection) WithKeyPrefix(prefix string) *Collection {
+	n := c.clone()
+	n.keyPrefix = c.keyPrefix + prefix
+	return n
+}
+
+// KeyPrefix returns the prefix applied to keys by this collection, empty if the collection is not namespaced.
+func (c *Collection) KeyPrefix() string {
+	return c.keyPrefix
+}
+
+// StripKeyPrefix removes the key prefix of this collection from a full document key, such as one returned by
+// META().id within a query. It returns false if the key is not within the namespace of this collection.
+func (c *Collection) StripKeyPrefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, c.keyPrefix) {
+		return "", false
+	}
+
+	return key[len(c.keyPrefix):], true
+}
+
+// NamespacedQuery executes a N1QL query against cluster which is restricted to the key namespace of this
+// collection. The statement must be a SELECT, UPDATE or DELETE of a single keyspace aliased as alias, and its
+// WHERE clause must consist of only the KeyPrefixPredicate placeholder, such as
+// SELECT ${KeyPrefix.key} AS id, d.* FROM `default` AS d WHERE ${KeyPrefix.predicate} ORDER BY d.name. The
+// placeholder is replaced with (filter) AND META(alias).id LIKE $gocbKeyPrefix, so that the filter cannot widen
+// the query beyond the namespace. Statements which join, nest or query other keyspaces, or which contain
+// subqueries or comments, are rejected, as are filters which contain subqueries or unbalanced parentheses.
+// Positional parameters cannot be used as the key prefix is bound as a named parameter.
+func (c *Collection) NamespacedQuery(cluster *Cluster, alias, statement, filter string, opts *QueryOptions) (*QueryResults, error) {
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+
+	if c.keyPrefix == "" {
+		return nil, invalidArgumentsError{message: "collection does not have a key prefix"}
+	}
+
+	if alias == "" {
+		return nil, invalidArgumentsError{message: "alias cannot be empty"}
+	}
+
+	if strings.Count(statement, KeyPrefixPredicate) != 1 || !keyPrefixWhereRegexp.MatchString(statement) {
+		return nil, invalidArgumentsError{message: "statement must contain the key prefix predicate placeholder " +
+			"as its WHERE clause"}
+	}
+
+	err := checkNamespacedStatement(strings.Replace(strings.Replace(statement, KeyPrefixPredicate, "TRUE", -1),
+		KeyPrefixKey, "NULL", -1))
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkNamespacedFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.PositionalParameters) > 0 {
+		return nil, invalidArgumentsError{message: "positional parameters cannot be used with a namespaced query"}
+	}
+
+	userParams := opts.NamedParameters
+	if opts.Parameters != nil {
+		if opts.NamedParameters != nil {
+			return nil, invalidArgumentsError{message: "Parameters cannot be used with named parameters"}
+		}
+
+		serializer := opts.Serializer
+		if serializer == nil {
+			serializer = cluster.sb.Serializer
+		}
+
+		userParams, err = structParameters(opts.Parameters, serializer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	namedParams := make(map[string]interface{}, len(userParams)+2)
+	for key, value := range userParams {
+		if strings.HasPrefix(strings.TrimPrefix(key, "$"), keyPrefixParameter) {
+			return nil, invalidArgumentsError{message: fmt.Sprintf("named parameter %s is reserved", key)}
+		}
+		namedParams[key] = value
+	}
+	namedParams[keyPrefixParameter] = keyPrefixLikePattern(c.keyPrefix)
+
+	metaID := fmt.Sprintf("META(%s).id", n1ql.EscapeIdentifier(alias))
+	predicate := fmt.Sprintf("%s LIKE $%s", metaID, keyPrefixParameter)
+	if strings.TrimSpace(filter) != "" {
+		predicate = "(" + filter + ") AND " + predicate
+	}
+	statement = strings.Replace(statement, KeyPrefixPredicate, predicate, -1)
+
+	if strings.Contains(statement, KeyPrefixKey) {
+		namedParams[keyPrefixValueParameter] = c.keyPrefix
+		statement = strings.Replace(statement, KeyPrefixKey,
+			fmt.Sprintf("SUBSTR(%s, LENGTH($%s))", metaID, keyPrefixValueParameter), -1)
+	}
+
+	if opts.Parameters != nil {
+		err := checkNamedParameters(statement, namedParams)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	queryOpts := *opts
+	queryOpts.PositionalParameters = nil
+	queryOpts.NamedParameters = namedParams
+	queryOpts.Parameters = nil
+
+	return cluster.Query(statement, &queryOpts)
+}
+
+// namespacedStatementVerbs are the statements which can be executed by NamespacedQuery.
+var namespacedStatementVerbs = map[string]bool{"SELECT": true, "UPDATE": true, "DELETE": true}
+
+// namespacedForbiddenWords are the keywords which would allow a namespaced statement to read or write keyspaces
+// other than the one restricted by the key prefix predicate.
+var namespacedForbiddenWords = map[string]bool{
+	"JOIN": true, "NEST": true, "INSERT": true, "UPSERT": true, "MERGE": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true,
+}
+
+// namespacedFromEndWords are the keywords which end the FROM clause of a statement.
+var namespacedFromEndWords = map[string]bool{
+	"USE": true, "UNNEST": true, "LET": true, "WHERE": true, "GROUP": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "RETURNING": true,
+}
+
+// checkNamespacedStatement checks that statement only addresses a single keyspace.
+func checkNamespacedStatement(statement string) error {
+	tokens, err := scanN1qlTokens(statement)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 || !namespacedStatementVerbs[tokens[0].text] {
+		return invalidArgumentsError{message: "namespaced statements must be a SELECT, UPDATE or DELETE"}
+	}
+
+	selects, froms := 0, 0
+	inFrom := false
+	for _, token := range tokens {
+		if namespacedForbiddenWords[token.text] {
+			return invalidArgumentsError{message: fmt.Sprintf("namespaced statements cannot contain %s", token.text)}
+		}
+
+		switch {
+		case token.text == "SELECT":
+			selects++
+		case token.text == "FROM":
+			froms++
+			inFrom = true
+		case inFrom && token.text == "," && token.depth == 0:
+			return invalidArgumentsError{message: "namespaced statements cannot query more than one keyspace"}
+		case namespacedFromEndWords[token.text]:
+			inFrom = false
+		}
+	}
+
+	if selects > 1 || froms > 1 {
+		return invalidArgumentsError{message: "namespaced statements cannot contain subqueries"}
+	}
+
+	return nil
+}
+
+// checkNamespacedFilter checks that filter is a self-contained expression over the namespaced keyspace.
+func checkNamespacedFilter(filter string) error {
+	tokens, err := scanN1qlTokens(filter)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.text == "SELECT" || token.text == "FROM" {
+			return invalidArgumentsError{message: "namespaced filters cannot contain subqueries"}
+		}
+	}
+
+	return nil
+}
+
+// n1qlToken is a keyword, identifier or comma within a N1QL statement, along with its depth of parentheses.
+// Keywords and identifiers are upper cased, escaped identifiers and literals are skipped.
+type n1qlToken struct {
+	text  string
+	depth int
+}
+
+// scanN1qlTokens splits statement into tokens, failing if it contains comments, semicolons, unterminated
+// literals or unbalanced parentheses.
+func scanN1qlTokens(statement string) ([]n1qlToken, error) {
+	var tokens []n1qlToken
+	depth := 0
+	for i := 0; i < len(statement); i++ {
+		ch := statement[i]
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := i + 1
+			for ; end < len(statement); end++ {
+				if statement[end] == '\\' && ch != '`' {
+					end++
+				} else if statement[end] == ch {
+					if end+1 < len(statement) && statement[end+1] == ch {
+						end++
+						continue
+					}
+					break
+				}
+			}
+			if end >= len(statement) {
+				return nil, invalidArgumentsError{message: "statement contains an unterminated literal"}
+			}
+			i = end
+		case ch == '-' && i+1 < len(statement) && statement[i+1] == '-',
+			ch == '/' && i+1 < len(statement) && statement[i+1] == '*':
+			return nil, invalidArgumentsError{message: "statement cannot contain comments"}
+		case ch == ';':
+			return nil, invalidArgumentsError{message: "statement cannot contain semicolons"}
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+			if depth < 0 {
+				return nil, invalidArgumentsError{message: "statement contains unbalanced brackets"}
+			}
+		case ch == ',':
+			tokens = append(tokens, n1qlToken{text: ",", depth: depth})
+		case isN1qlWordByte(ch):
+			end := i
+			for end < len(statement) && isN1qlWordByte(statement[end]) {
+				end++
+			}
+			tokens = append(tokens, n1qlToken{text: strings.ToUpper(statement[i:end]), depth: depth})
+			i = end - 1
+		}
+	}
+
+	if depth != 0 {
+		return nil, invalidArgumentsError{message: "statement contains unbalanced brackets"}
+	}
+
+	return tokens, nil
+}
+
+func isN1qlWordByte(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// keyPrefixLikePattern creates a LIKE pattern which matches keys beginning with prefix.
+func keyPrefixLikePattern(prefix string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return escaper.Replace(prefix) + "%"
+}
+
+// prefixedKvProvider prefixes the key of every request made by a namespaced collection.
+type prefixedKvProvider struct {
+	kvProvider
+	prefix string
+}
+
+func (p *prefixedKvProvider) key(key []byte) []byte {
+	return append([]byte(p.prefix), key...)
+}
+
+func (p *prefixedKvProvider) AddEx(opts gocbcore.AddOptions, cb gocbcore.StoreExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.AddEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) SetEx(opts gocbcore.SetOptions, cb gocbcore.StoreExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.SetEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) ReplaceEx(opts gocbcore.ReplaceOptions, cb gocbcore.StoreExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.ReplaceEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) GetEx(opts gocbcore.GetOptions, cb gocbcore.GetExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.GetEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) GetAnyReplicaEx(opts gocbcore.GetAnyReplicaOptions, cb gocbcore.GetReplicaExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.GetAnyReplicaEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) GetOneReplicaEx(opts gocbcore.GetOneReplicaOptions, cb gocbcore.GetReplicaExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.GetOneReplicaEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) ObserveEx(opts gocbcore.ObserveOptions, cb gocbcore.ObserveExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.ObserveEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) DeleteEx(opts gocbcore.DeleteOptions, cb gocbcore.DeleteExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.DeleteEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) LookupInEx(opts gocbcore.LookupInOptions, cb gocbcore.LookupInExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.LookupInEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) MutateInEx(opts gocbcore.MutateInOptions, cb gocbcore.MutateInExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.MutateInEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) GetAndTouchEx(opts gocbcore.GetAndTouchOptions, cb gocbcore.GetAndTouchExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.GetAndTouchEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) GetAndLockEx(opts gocbcore.GetAndLockOptions, cb gocbcore.GetAndLockExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.GetAndLockEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) UnlockEx(opts gocbcore.UnlockOptions, cb gocbcore.UnlockExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.UnlockEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) TouchEx(opts gocbcore.TouchOptions, cb gocbcore.TouchExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.TouchEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) IncrementEx(opts gocbcore.CounterOptions, cb gocbcore.CounterExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.IncrementEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) DecrementEx(opts gocbcore.CounterOptions, cb gocbcore.CounterExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.DecrementEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) AppendEx(opts gocbcore.AdjoinOptions, cb gocbcore.AdjoinExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.AppendEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) PrependEx(opts gocbcore.AdjoinOptions, cb gocbcore.AdjoinExCallback) (gocbcore.PendingOp, error) {
+	opts.Key = p.key(opts.Key)
+	return p.kvProvider.PrependEx(opts, cb)
+}
+
+func (p *prefixedKvProvider) KeyToVbucket(key []byte) uint16 {
+	return p.kvProvider.KeyToVbucket(p.key(key))
+}
@@ -0,0 +1,171 @@
+package gocb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func TestKeyPrefixKv(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`"value"`),
+		flags: 0x02000000,
+	}
+	provider.mutateInFn = func(opts gocbcore.MutateInOptions) (*gocbcore.MutateInResult, error) {
+		if string(opts.Key) != "t123::list" {
+			t.Errorf("Expected key to be t123::list but was %s", opts.Key)
+		}
+
+		return &gocbcore.MutateInResult{Cas: gocbcore.Cas(1), Ops: []gocbcore.SubDocResult{{}}}, nil
+	}
+	col := testGetCollection(t, provider)
+	recorder := &testKvRecorder{}
+	col.interceptors = []KvInterceptor{recorder}
+
+	tenant := col.WithKeyPrefix("t123::")
+	_, err := tenant.Upsert("doc", "value", nil)
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	_, err = tenant.Get("doc", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	err = tenant.Do([]BulkOp{&RemoveOp{Key: "bulk"}}, nil)
+	if err != nil {
+		t.Fatalf("Expected Do to not error %v", err)
+	}
+
+	err = tenant.List("list").Append("item")
+	if err != nil {
+		t.Fatalf("Expected Append to not error %v", err)
+	}
+
+	expected := []string{"t123::doc", "t123::doc", "t123::bulk", "t123::list"}
+	if len(recorder.requests) != len(expected) {
+		t.Fatalf("Expected %d requests but was %d", len(expected), len(recorder.requests))
+	}
+	for i, req := range recorder.requests {
+		if req.Key != expected[i] {
+			t.Fatalf("Expected key to be %s but was %s", expected[i], req.Key)
+		}
+	}
+
+	_, err = col.Get("doc", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if recorder.requests[len(recorder.requests)-1].Key != "doc" {
+		t.Fatalf("Expected the original collection to not be prefixed")
+	}
+}
+
+func TestKeyPrefixNested(t *testing.T) {
+	col := testGetCollection(t, &mockKvProvider{})
+	tenant := col.WithKeyPrefix("t123::").WithKeyPrefix("orders::")
+
+	if tenant.KeyPrefix() != "t123::orders::" {
+		t.Fatalf("Expected key prefix to be t123::orders:: but was %s", tenant.KeyPrefix())
+	}
+
+	key, ok := tenant.StripKeyPrefix("t123::orders::1")
+	if !ok || key != "1" {
+		t.Fatalf("Expected stripped key to be 1 but was %s", key)
+	}
+
+	_, ok = tenant.StripKeyPrefix("t456::orders::1")
+	if ok {
+		t.Fatalf("Expected key from another namespace to not be stripped")
+	}
+}
+
+func TestKeyPrefixNamespacedQuery(t *testing.T) {
+	dataBytes, err := loadRawTestDataset("beer_sample_query_dataset")
+	if err != nil {
+		t.Fatalf("Could not read test dataset: %v", err)
+	}
+
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var opts map[string]interface{}
+		err := json.Unmarshal(req.Body, &opts)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+
+		expectedStatement := "SELECT SUBSTR(META(`d`).id, LENGTH($gocbKeyPrefixValue)) AS id, d.* FROM `default` AS d " +
+			"WHERE (d.type = $type OR d.type = \"invoice\") AND META(`d`).id LIKE $gocbKeyPrefix ORDER BY d.name"
+		if opts["statement"] != expectedStatement {
+			t.Fatalf("Expected statement to be %s but was %s", expectedStatement, opts["statement"])
+		}
+
+		if opts["$gocbKeyPrefix"] != `t\_123::%` {
+			t.Fatalf("Expected key prefix pattern to be t\\_123::%% but was %v", opts["$gocbKeyPrefix"])
+		}
+
+		if opts["$gocbKeyPrefixValue"] != "t_123::" {
+			t.Fatalf("Expected key prefix to be t_123:: but was %v", opts["$gocbKeyPrefixValue"])
+		}
+
+		if opts["$type"] != "order" {
+			t.Fatalf("Expected type parameter to be order but was %v", opts["$type"])
+		}
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(dataBytes), nil},
+		}, nil
+	}
+
+	cluster := testGetClusterForHTTP(&mockHTTPProvider{doFn: doHTTP}, 10*time.Second, 0, 0)
+	tenant := testGetCollection(t, &mockKvProvider{}).WithKeyPrefix("t_123::")
+
+	res, err := tenant.NamespacedQuery(cluster, "d", "SELECT "+KeyPrefixKey+" AS id, d.* FROM `default` AS d WHERE "+
+		KeyPrefixPredicate+" ORDER BY d.name", `d.type = $type OR d.type = "invoice"`,
+		&QueryOptions{NamedParameters: map[string]interface{}{"type": "order"}})
+	if err != nil {
+		t.Fatalf("Expected NamespacedQuery to not error %v", err)
+	}
+
+	err = res.Close()
+	if err != nil {
+		t.Fatalf("Expected Close to not error %v", err)
+	}
+
+	_, err = tenant.NamespacedQuery(cluster, "d", "SELECT d.* FROM `default` AS d WHERE "+KeyPrefixPredicate, "",
+		&QueryOptions{PositionalParameters: []interface{}{1}})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected positional parameters to be invalid but was %v", err)
+	}
+
+	invalid := []struct {
+		statement string
+		filter    string
+	}{
+		{"SELECT d.* FROM `default` AS d", ""},
+		{"SELECT d.* FROM `default` AS d WHERE d.type = 'order' AND " + KeyPrefixPredicate, ""},
+		{"SELECT d.* FROM `default` AS d JOIN `default` AS o ON KEYS d.orderId WHERE " + KeyPrefixPredicate, ""},
+		{"SELECT d.* FROM `default` AS d, `default` AS o WHERE " + KeyPrefixPredicate, ""},
+		{"SELECT d.* FROM `default` AS d WHERE " + KeyPrefixPredicate + " UNION SELECT o.* FROM `default` AS o", ""},
+		{"SELECT d.*, (SELECT o.* FROM `default` AS o) AS o FROM `default` AS d WHERE " + KeyPrefixPredicate, ""},
+		{"INSERT INTO `other` (KEY k, VALUE d) SELECT META(d).id AS k, d FROM `default` AS d WHERE " +
+			KeyPrefixPredicate, ""},
+		{"SELECT d.* FROM `default` AS d WHERE " + KeyPrefixPredicate + " -- comment", ""},
+		{"SELECT d.* FROM `default` AS d WHERE " + KeyPrefixPredicate, "d.type = 'order') OR (TRUE"},
+		{"SELECT d.* FROM `default` AS d WHERE " + KeyPrefixPredicate, "d.type = 'order' --"},
+		{"SELECT d.* FROM `default` AS d WHERE " + KeyPrefixPredicate, "d.id IN (SELECT RAW o.id FROM `default` AS o)"},
+	}
+	for _, tc := range invalid {
+		_, err = tenant.NamespacedQuery(cluster, "d", tc.statement, tc.filter, nil)
+		if !IsInvalidArgumentsError(err) {
+			t.Fatalf("Expected %s with filter %s to be invalid but was %v", tc.statement, tc.filter, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package gocb
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
+)
+
+// documentMetaPath is the virtual xattr which the server populates with the metadata of a document.
+const documentMetaPath = "$document"
+
+// documentMeta is the format of the $document virtual xattr.
+type documentMeta struct {
+	Cas          string   `json:"CAS"`
+	SeqNo        string   `json:"seqno"`
+	Expiration   uint32   `json:"exptime"`
+	ValueBytes   uint64   `json:"value_bytes"`
+	Datatype     []string `json:"datatype"`
+	Deleted      bool     `json:"deleted"`
+	LastModified string   `json:"last_modified"`
+	Flags        uint32   `json:"flags"`
+	RevID        string   `json:"revid"`
+}
+
+// GetMetaOptions are the options available to the GetMeta operation.
+type GetMetaOptions struct {
+	Timeout time.Duration
+	Context context.Context
+	// AccessDeleted allows the metadata of deleted documents (tombstones) to be fetched.
+	AccessDeleted bool
+}
+
+// GetMeta fetches the metadata of a document, as reported by the server, without fetching its value.
+// This requires the server to support xattrs.
+func (c *Collection) GetMeta(key string, opts *GetMetaOptions) (docOut *GetMetaResult, errOut error) {
+	if opts == nil {
+		opts = &GetMetaOptions{}
+	}
+
+	ctx, cancel := c.context(opts.Context, opts.Timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	res, err := c.getMeta(ctx, key, *opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Collection) getMeta(ctx context.Context, key string, opts GetMetaOptions) (docOut *GetMetaResult, errOut error) {
+	agent, err := c.getKvProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	_, contents, err := c.lookupInChunk(ctx, agent, key, []gocbcore.SubDocOp{documentMetaOp()}, documentMetaFlags(opts.AccessDeleted))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contents) != 1 {
+		return nil, errors.New("invalid response for document metadata")
+	}
+
+	if contents[0].err != nil {
+		return nil, contents[0].err
+	}
+
+	return newGetMetaResult(contents[0].data)
+}
+
+func documentMetaOp() gocbcore.SubDocOp {
+	return gocbcore.SubDocOp{
+		Op:    gocbcore.SubDocOpGet,
+		Path:  documentMetaPath,
+		Flags: gocbcore.SubdocFlag(SubdocFlagXattr),
+	}
+}
+
+func documentMetaFlags(accessDeleted bool) gocbcore.SubdocDocFlag {
+	if accessDeleted {
+		return gocbcore.SubdocDocFlagAccessDeleted
+	}
+
+	return gocbcore.SubdocDocFlagNone
+}
+
+// newGetMetaResult parses the value of the $document virtual xattr.
+func newGetMetaResult(data []byte) (*GetMetaResult, error) {
+	var meta documentMeta
+	err := json.Unmarshal(data, &meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse document metadata")
+	}
+
+	res := &GetMetaResult{
+		expiration: meta.Expiration,
+		valueSize:  meta.ValueBytes,
+		datatype:   meta.Datatype,
+		deleted:    meta.Deleted,
+		flags:      meta.Flags,
+	}
+
+	cas, err := parseDocumentMetaUint(meta.Cas)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse document metadata cas")
+	}
+	res.cas = Cas(cas)
+
+	res.seqNo, err = parseDocumentMetaUint(meta.SeqNo)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse document metadata seqno")
+	}
+
+	res.revID, err = parseDocumentMetaUint(meta.RevID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse document metadata revid")
+	}
+
+	lastModified, err := parseDocumentMetaUint(meta.LastModified)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse document metadata last modified")
+	}
+	if lastModified > 0 {
+		res.lastModified = time.Unix(int64(lastModified), 0)
+	}
+
+	return res, nil
+}
+
+// parseDocumentMetaUint parses a number which may be hex encoded, as the server reports some values as hex
+// strings and others as decimal strings. Older servers may not report every value so empty strings are 0.
+func parseDocumentMetaUint(val string) (uint64, error) {
+	if val == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(val, 0, 64)
+}
@@ -0,0 +1,111 @@
+package gocb
+
+import (
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func testDocumentMetaLookupIn(t *testing.T, deleted bool) func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error) {
+	return func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error) {
+		if len(opts.Ops) != 1 || opts.Ops[0].Path != "$document" {
+			t.Errorf("Expected a single lookup of $document but was %v", opts.Ops)
+		}
+
+		if deleted && opts.Flags&gocbcore.SubdocDocFlagAccessDeleted == 0 {
+			return nil, gocbcore.ErrKeyNotFound
+		}
+
+		meta := []byte(`{"CAS":"0x15a8e4e5d7ab0000","seqno":"0x0000000000000010","exptime":0,"value_bytes":14,` +
+			`"datatype":["json","xattr"],"last_modified":"1571057032","flags":33554432,"revid":"3","deleted":false}`)
+		if deleted {
+			meta = []byte(`{"CAS":"0x15a8e4e5d7ab0000","seqno":"0x0000000000000010","exptime":0,"value_bytes":0,` +
+				`"datatype":["raw"],"last_modified":"1571057032","flags":0,"revid":"4","deleted":true}`)
+		}
+
+		return &gocbcore.LookupInResult{
+			Cas: gocbcore.Cas(0x15a8e4e5d7ab0000),
+			Ops: []gocbcore.SubDocResult{{Value: meta}},
+		}, nil
+	}
+}
+
+func TestGetMeta(t *testing.T) {
+	provider := &mockKvProvider{}
+	provider.lookupInFn = testDocumentMetaLookupIn(t, false)
+	col := testGetCollection(t, provider)
+
+	res, err := col.GetMeta("getMeta", nil)
+	if err != nil {
+		t.Fatalf("Expected GetMeta to not error %v", err)
+	}
+
+	if res.Cas() != Cas(0x15a8e4e5d7ab0000) {
+		t.Fatalf("Expected cas to be 0x15a8e4e5d7ab0000 but was %x", res.Cas())
+	}
+
+	if res.SeqNo() != 16 || res.RevID() != 3 || res.ValueSize() != 14 || res.Flags() != 33554432 {
+		t.Fatalf("Metadata was not as expected: %+v", res)
+	}
+
+	if res.Deleted() || !res.Expiration().IsZero() {
+		t.Fatalf("Expected document to not be deleted or have an expiry")
+	}
+
+	if len(res.Datatype()) != 2 || res.Datatype()[0] != "json" || res.Datatype()[1] != "xattr" {
+		t.Fatalf("Expected datatype to be json and xattr but was %v", res.Datatype())
+	}
+
+	if !res.LastModified().Equal(time.Unix(1571057032, 0)) {
+		t.Fatalf("Expected last modified to be 1571057032 but was %v", res.LastModified())
+	}
+}
+
+func TestGetMetaAccessDeleted(t *testing.T) {
+	provider := &mockKvProvider{}
+	provider.lookupInFn = testDocumentMetaLookupIn(t, true)
+	col := testGetCollection(t, provider)
+
+	_, err := col.GetMeta("getMetaDeleted", nil)
+	if !IsKeyNotFoundError(err) {
+		t.Fatalf("Expected key not found error but was %v", err)
+	}
+
+	res, err := col.GetMeta("getMetaDeleted", &GetMetaOptions{AccessDeleted: true})
+	if err != nil {
+		t.Fatalf("Expected GetMeta to not error %v", err)
+	}
+
+	if !res.Deleted() || res.RevID() != 4 {
+		t.Fatalf("Expected a tombstone with revid 4 but was %+v", res)
+	}
+}
+
+func TestBulkGetMeta(t *testing.T) {
+	provider := &mockKvProvider{}
+	provider.lookupInFn = testDocumentMetaLookupIn(t, true)
+	col := testGetCollection(t, provider)
+
+	ops := []BulkOp{
+		&GetMetaOp{Key: "bulkGetMeta1"},
+		&GetMetaOp{Key: "bulkGetMeta2", AccessDeleted: true},
+	}
+	err := col.Do(ops, nil)
+	if err != nil {
+		t.Fatalf("Expected Do to not error %v", err)
+	}
+
+	if !IsKeyNotFoundError(ops[0].(*GetMetaOp).Err) {
+		t.Fatalf("Expected key not found error but was %v", ops[0].(*GetMetaOp).Err)
+	}
+
+	op := ops[1].(*GetMetaOp)
+	if op.Err != nil {
+		t.Fatalf("Expected op to not error %v", op.Err)
+	}
+
+	if !op.Result.Deleted() {
+		t.Fatalf("Expected document to be deleted")
+	}
+}
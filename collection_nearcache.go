@@ -0,0 +1,445 @@
+package gocb
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+// NearCacheOptions enables an in-process cache in front of Collection.Get, intended for read heavy data which
+// changes rarely. Entries are invalidated when the document is written through the same collection, and
+// otherwise live until they expire or are evicted.
+type NearCacheOptions struct {
+	// MaxEntries is the maximum number of documents held in the cache, defaults to 10000.
+	MaxEntries int
+	// MaxBytes is the maximum total size of the documents held in the cache, defaults to 64MiB. Documents larger
+	// than this are never cached.
+	MaxBytes int
+	// TTL is the maximum amount of time that a document is served from the cache, defaults to 1 minute.
+	TTL time.Duration
+	// ValidateCas confirms that the CAS of a cached document matches the server before it is served. This
+	// requires a round trip to the active node but avoids transferring the document body.
+	ValidateCas bool
+	// ChangeStream opens a DCP stream for the collection so that entries are also invalidated when the document
+	// is changed by other clients. Documents are only cached within vbuckets which have a stream open. Streams
+	// which fail to open or which end are reopened with backoff, and the cache is cleared whenever a stream ends.
+	ChangeStream bool
+}
+
+// NearCacheStats are the statistics recorded by the near cache of a collection.
+type NearCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Expirations   uint64
+	Invalidations uint64
+	Entries       int
+	Bytes         int
+	// StreamedVbuckets is the number of vbuckets which currently have a change stream open.
+	StreamedVbuckets int
+}
+
+// HitRatio returns the fraction of reads which were served from the cache.
+func (s NearCacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(total)
+}
+
+// nearCacheRegistry holds the near cache of every collection opened from a cluster.
+type nearCacheRegistry struct {
+	lock   sync.Mutex
+	caches map[string]*nearCache
+}
+
+func newNearCacheRegistry() *nearCacheRegistry {
+	return &nearCacheRegistry{
+		caches: make(map[string]*nearCache),
+	}
+}
+
+// cacheFor returns the cache for a collection, collections opened more than once share a cache so that writes
+// through either invalidate it. An error is returned if the collection was already opened with different options.
+func (r *nearCacheRegistry) cacheFor(sb *stateBlock, opts NearCacheOptions) (*nearCache, error) {
+	if r == nil {
+		return newNearCache(sb, opts), nil
+	}
+
+	id := sb.BucketName + "/" + sb.ScopeName + "/" + sb.CollectionName
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	cache, ok := r.caches[id]
+	if !ok {
+		cache = newNearCache(sb, opts)
+		r.caches[id] = cache
+	} else if cache.opts != opts.withDefaults() {
+		return nil, invalidArgumentsError{message: "collection " + id + " is already cached with different near cache options"}
+	}
+
+	return cache, nil
+}
+
+type nearCacheEntry struct {
+	key      string
+	contents []byte
+	flags    uint32
+	cas      Cas
+	expires  time.Time
+}
+
+func (e *nearCacheEntry) size() int {
+	return len(e.key) + len(e.contents)
+}
+
+// nearCache is an LRU cache of documents keyed by their full key, including any key prefix. It is also a
+// KvInterceptor which invalidates entries when they are written.
+type nearCache struct {
+	opts        NearCacheOptions
+	maxEntries  int
+	maxBytes    int
+	ttl         time.Duration
+	validateCas bool
+	now         func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	bytes   int
+	// epoch is incremented on every invalidation, reads which were in flight when it changed do not populate the
+	// cache as they may have fetched the document before it was written.
+	epoch    uint64
+	stats    NearCacheStats
+	streamed map[uint16]struct{}
+}
+
+// withDefaults returns the options with defaults applied to any which are unset.
+func (opts NearCacheOptions) withDefaults() NearCacheOptions {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10000
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 64 * 1024 * 1024
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+
+	return opts
+}
+
+func newNearCache(sb *stateBlock, opts NearCacheOptions) *nearCache {
+	opts = opts.withDefaults()
+
+	cache := &nearCache{
+		opts:        opts,
+		maxEntries:  opts.MaxEntries,
+		maxBytes:    opts.MaxBytes,
+		ttl:         opts.TTL,
+		validateCas: opts.ValidateCas,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+		streamed:    make(map[uint16]struct{}),
+	}
+
+	if opts.ChangeStream {
+		stream := &nearCacheStream{
+			cache:          cache,
+			client:         sb.getCachedClient(),
+			scopeName:      sb.ScopeName,
+			collectionName: sb.CollectionName,
+			timeout:        sb.KvTimeout,
+		}
+		stream.start()
+	}
+
+	return cache
+}
+
+// get returns the entry for key, the entry must not be modified.
+func (c *nearCache) get(key string) (*nearCacheEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*nearCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.removeElement(elem)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+
+	return entry, true
+}
+
+// currentEpoch must be read before fetching a document which is to be passed to put.
+func (c *nearCache) currentEpoch() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.epoch
+}
+
+// put caches doc as key, which belongs to vbID. Nothing is cached if the cache was invalidated since epoch was read,
+// or if the cache uses a change stream which is not currently open for vbID. The contents of doc are copied, so
+// the caller remains free to modify doc.
+func (c *nearCache) put(key string, vbID uint16, doc *GetResult, epoch uint64) {
+	entry := &nearCacheEntry{
+		key:      key,
+		contents: append([]byte(nil), doc.contents...),
+		flags:    doc.flags,
+		cas:      doc.Cas(),
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if epoch != c.epoch || entry.size() > c.maxBytes {
+		return
+	}
+
+	if _, ok := c.streamed[vbID]; c.opts.ChangeStream && !ok {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+
+	entry.expires = c.now().Add(c.ttl)
+	c.entries[key] = c.lru.PushFront(entry)
+	c.bytes += entry.size()
+
+	for len(c.entries) > c.maxEntries || c.bytes > c.maxBytes {
+		c.removeElement(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// removeElement must be called with the lock held.
+func (c *nearCache) removeElement(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*nearCacheEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size()
+}
+
+func (c *nearCache) invalidate(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.epoch++
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+		c.stats.Invalidations++
+	}
+}
+
+// invalidateChange invalidates key following a change seen on the change stream. Entries which already hold
+// the changed revision of the document are kept.
+func (c *nearCache) invalidateChange(key string, cas Cas) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.epoch++
+	if elem, ok := c.entries[key]; ok && elem.Value.(*nearCacheEntry).cas != cas {
+		c.removeElement(elem)
+		c.stats.Invalidations++
+	}
+}
+
+func (c *nearCache) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.epoch++
+	c.stats.Invalidations += uint64(len(c.entries))
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+// discardPending prevents documents which are currently being fetched from being cached.
+func (c *nearCache) discardPending() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.epoch++
+}
+
+func (c *nearCache) isStreamed(vbID uint16) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, ok := c.streamed[vbID]
+	return ok
+}
+
+func (c *nearCache) setStreamed(vbID uint16, streamed bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if streamed {
+		c.streamed[vbID] = struct{}{}
+	} else {
+		delete(c.streamed, vbID)
+	}
+}
+
+func (c *nearCache) snapshot() NearCacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Bytes = c.bytes
+	stats.StreamedVbuckets = len(c.streamed)
+
+	return stats
+}
+
+// completeKv invalidates the key of req once it has completed, if it is a write. Failed and cancelled writes may
+// still have been applied, so every write invalidates.
+func (c *nearCache) completeKv(req *KvRequest) {
+	switch req.Operation {
+	case KvOperationGet, KvOperationGetReplica, KvOperationGetAnyReplica, KvOperationLookupIn:
+	default:
+		c.invalidate(req.Key)
+	}
+}
+
+func (c *nearCache) InterceptKv(req *KvRequest, next KvHandler) (*KvResponse, error) {
+	res, err := next(req)
+	c.completeKv(req)
+
+	return res, err
+}
+
+func (c *nearCache) interceptKvAsync(req *KvRequest, next kvExecFunc, cb kvResponseCallback) (gocbcore.PendingOp, error) {
+	op, err := next(req, func(res *KvResponse, err error) {
+		c.completeKv(req)
+		cb(res, err)
+	})
+	if err != nil {
+		c.completeKv(req)
+		return nil, err
+	}
+
+	return kvCancelHook{PendingOp: op, onCancel: func() { c.completeKv(req) }}, nil
+}
+
+// getNearCached performs a standard full document fetch, serving it from the near cache where possible.
+func (c *Collection) getNearCached(ctx context.Context, key string, opts *GetOptions) (*GetResult, error) {
+	cacheKey := c.keyPrefix + key
+
+	if !opts.BypassNearCache {
+		entry, ok := c.nearCache.get(cacheKey)
+		if ok && (!c.nearCache.validateCas || c.nearCacheEntryCurrent(ctx, key, entry)) {
+			// Each reader gets its own copy of the contents, as callers may modify them in place.
+			return &GetResult{
+				Result: Result{
+					cas: entry.cas,
+				},
+				transcoder: opts.Transcoder,
+				contents:   append([]byte(nil), entry.contents...),
+				flags:      entry.flags,
+			}, nil
+		}
+	}
+
+	agent, err := c.getKvProvider()
+	if err != nil {
+		return nil, err
+	}
+	vbID := agent.KeyToVbucket([]byte(key))
+
+	epoch := c.nearCache.currentEpoch()
+	doc, err := c.get(ctx, key, opts)
+	if err != nil {
+		if IsKeyNotFoundError(err) {
+			c.nearCache.invalidate(cacheKey)
+		}
+		return nil, err
+	}
+
+	c.nearCache.put(cacheKey, vbID, doc, epoch)
+
+	return doc, nil
+}
+
+// nearCacheEntryCurrent observes the active copy of the document to check that entry holds its latest revision.
+// Entries which cannot be confirmed are invalidated so that they are fetched again.
+func (c *Collection) nearCacheEntryCurrent(ctx context.Context, key string, entry *nearCacheEntry) (current bool) {
+	defer func() {
+		if !current {
+			c.nearCache.invalidate(entry.key)
+		}
+	}()
+
+	agent, err := c.getKvProvider()
+	if err != nil {
+		return false
+	}
+
+	ctrl := c.newOpManager(ctx)
+	err = ctrl.wait(agent.ObserveEx(gocbcore.ObserveOptions{
+		Key:            []byte(key),
+		ReplicaIdx:     0,
+		CollectionName: c.name(),
+		ScopeName:      c.scopeName(),
+	}, func(res *gocbcore.ObserveResult, err error) {
+		current = err == nil && res != nil && Cas(res.Cas) == entry.cas &&
+			(res.KeyState == gocbcore.KeyStateNotPersisted || res.KeyState == gocbcore.KeyStatePersisted)
+		ctrl.resolve()
+	}))
+	if err != nil {
+		return false
+	}
+
+	return current
+}
+
+// NearCacheStats returns the statistics of the near cache of the collection, or nil if it is not enabled.
+func (c *Collection) NearCacheStats() *NearCacheStats {
+	if c.nearCache == nil {
+		return nil
+	}
+
+	stats := c.nearCache.snapshot()
+	return &stats
+}
+
+// InvalidateNearCache removes key from the near cache of the collection, if it is enabled.
+func (c *Collection) InvalidateNearCache(key string) {
+	if c.nearCache == nil {
+		return
+	}
+
+	c.nearCache.invalidate(c.keyPrefix + key)
+}
+
+// ClearNearCache removes every entry from the near cache of the collection, if it is enabled. Collections
+// created with WithKeyPrefix share the cache of their parent, so are also cleared.
+func (c *Collection) ClearNearCache() {
+	if c.nearCache == nil {
+		return
+	}
+
+	c.nearCache.clear()
+}
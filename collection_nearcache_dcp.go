@@ -0,0 +1,298 @@
+package gocb
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
+)
+
+type dcpProvider interface {
+	OpenStream(vbID uint16, flags gocbcore.DcpStreamAddFlag, vbUUID gocbcore.VbUuid, startSeqNo, endSeqNo,
+		snapStartSeqNo, snapEndSeqNo gocbcore.SeqNo, evtHandler gocbcore.StreamObserver, filter *gocbcore.StreamFilter,
+		cb gocbcore.OpenStreamCallback) (gocbcore.PendingOp, error)
+	GetFailoverLog(vbID uint16, cb gocbcore.GetFailoverLogCallback) (gocbcore.PendingOp, error)
+	GetVbucketSeqnos(serverIdx int, state gocbcore.VbucketState, cb gocbcore.GetVBucketSeqnosCallback) (gocbcore.PendingOp, error)
+	GetCollectionID(scopeName string, collectionName string, opts gocbcore.GetCollectionIDOptions,
+		cb gocbcore.CollectionIdCallback) (gocbcore.PendingOp, error)
+	HasCollectionsSupport() bool
+	NumServers() int
+}
+
+// nearCacheStreamRetryBehavior is used to wait between attempts to open change streams which failed to open or
+// have ended. Attempts continue until every stream is open or the client is shut down.
+var nearCacheStreamRetryBehavior RetryBehavior = StandardDelayRetryBehavior(math.MaxUint32, 2, 30*time.Second,
+	ExponentialDelayFunction)
+
+var errNearCacheCollectionsUnsupported = errors.New("change streams require collections support")
+
+// nearCacheStream invalidates a near cache using a DCP stream of the changes made to its collection. The
+// stream starts from the current sequence number of each vbucket and carries no document bodies. Documents are
+// only cached within vbuckets which currently have a stream open.
+type nearCacheStream struct {
+	cache          *nearCache
+	client         client
+	scopeName      string
+	collectionName string
+	timeout        time.Duration
+
+	lock    sync.Mutex
+	running bool
+	// ended is set when a stream ends whilst streams are being opened, so that it is reopened by the next attempt.
+	ended bool
+}
+
+// start opens a stream for every vbucket which does not have one, unless an attempt to do so is already running.
+func (s *nearCacheStream) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.running {
+		s.ended = true
+		return
+	}
+	s.running = true
+
+	go s.run()
+}
+
+func (s *nearCacheStream) run() {
+	var retries uint
+	for {
+		err := s.open()
+		if err != nil && !isNearCacheStreamRetryable(err) {
+			if gocbcore.ErrorCause(err) != gocbcore.ErrShutdown {
+				logWarnf("Failed to open near cache change stream for %s.%s, documents will not be cached: %v",
+					s.scopeName, s.collectionName, err)
+			}
+
+			s.lock.Lock()
+			s.running = false
+			s.lock.Unlock()
+			return
+		}
+
+		s.lock.Lock()
+		if err == nil && !s.ended {
+			s.running = false
+			s.lock.Unlock()
+			return
+		}
+		s.ended = false
+		s.lock.Unlock()
+
+		if err != nil {
+			retries++
+			logWarnf("Failed to open near cache change stream for %s.%s, documents will not be cached within "+
+				"unstreamed vbuckets until it is reopened: %v", s.scopeName, s.collectionName, err)
+		} else {
+			retries = 0
+		}
+
+		time.Sleep(nearCacheStreamRetryBehavior.NextInterval(retries))
+	}
+}
+
+func isNearCacheStreamRetryable(err error) bool {
+	return err != errNearCacheCollectionsUnsupported && gocbcore.ErrorCause(err) != gocbcore.ErrShutdown
+}
+
+func (s *nearCacheStream) wait(op func(resolve func()) (gocbcore.PendingOp, error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	ctrl := &opManager{
+		signal: make(chan struct{}, 1),
+		ctx:    ctx,
+	}
+	return ctrl.wait(op(ctrl.resolve))
+}
+
+// open opens a stream for every vbucket which does not have one. Vbuckets which fail to open do not stop the
+// remaining vbuckets from being opened, but cause an error to be returned so that they are retried.
+func (s *nearCacheStream) open() error {
+	provider, err := s.client.getDcpProvider()
+	if err != nil {
+		return err
+	}
+
+	if !provider.HasCollectionsSupport() {
+		return errNearCacheCollectionsUnsupported
+	}
+
+	var collectionID uint32
+	if s.scopeName != "_default" || s.collectionName != "_default" {
+		var cidErr error
+		err = s.wait(func(resolve func()) (gocbcore.PendingOp, error) {
+			return provider.GetCollectionID(s.scopeName, s.collectionName, gocbcore.GetCollectionIDOptions{},
+				func(manifestID uint64, cid uint32, err error) {
+					collectionID, cidErr = cid, err
+					resolve()
+				})
+		})
+		if err == nil {
+			err = cidErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Documents being fetched before the sequence numbers are read may have been changed before the streams start.
+	s.cache.discardPending()
+
+	seqNos := make(map[uint16]gocbcore.SeqNo)
+	for serverIdx := 0; serverIdx < provider.NumServers(); serverIdx++ {
+		var entries []gocbcore.VbSeqNoEntry
+		var seqErr error
+		err = s.wait(func(resolve func()) (gocbcore.PendingOp, error) {
+			return provider.GetVbucketSeqnos(serverIdx, gocbcore.VbucketStateActive, func(e []gocbcore.VbSeqNoEntry, err error) {
+				entries, seqErr = e, err
+				resolve()
+			})
+		})
+		if err == nil {
+			err = seqErr
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			seqNos[entry.VbId] = entry.SeqNo
+		}
+	}
+
+	filter := gocbcore.NewStreamFilter()
+	filter.Collections = []uint32{collectionID}
+
+	var failed int
+	var firstErr error
+	for vbID, seqNo := range seqNos {
+		if s.cache.isStreamed(vbID) {
+			continue
+		}
+
+		err = s.openVbucket(provider, vbID, seqNo, filter)
+		if err != nil {
+			if gocbcore.ErrorCause(err) == gocbcore.ErrShutdown {
+				return err
+			}
+
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return errors.Wrapf(firstErr, "failed to open streams for %d vbuckets", failed)
+	}
+
+	return nil
+}
+
+func (s *nearCacheStream) openVbucket(provider dcpProvider, vbID uint16, seqNo gocbcore.SeqNo, filter *gocbcore.StreamFilter) error {
+	var failoverLog []gocbcore.FailoverEntry
+	var logErr error
+	err := s.wait(func(resolve func()) (gocbcore.PendingOp, error) {
+		return provider.GetFailoverLog(vbID, func(entries []gocbcore.FailoverEntry, err error) {
+			failoverLog, logErr = entries, err
+			resolve()
+		})
+	})
+	if err == nil {
+		err = logErr
+	}
+	if err != nil {
+		return err
+	}
+	if len(failoverLog) == 0 {
+		return errors.Errorf("no failover log for vbucket %d", vbID)
+	}
+
+	var openErr error
+	err = s.wait(func(resolve func()) (gocbcore.PendingOp, error) {
+		return provider.OpenStream(vbID, 0, failoverLog[0].VbUuid, seqNo, gocbcore.SeqNo(math.MaxUint64), seqNo, seqNo,
+			s, filter, func(entries []gocbcore.FailoverEntry, err error) {
+				openErr = err
+				resolve()
+			})
+	})
+	if err == nil {
+		err = openErr
+	}
+	if err != nil {
+		return err
+	}
+
+	s.cache.setStreamed(vbID, true)
+
+	return nil
+}
+
+func (s *nearCacheStream) SnapshotMarker(startSeqNo, endSeqNo uint64, vbID uint16, streamID uint16,
+	snapshotType gocbcore.SnapshotState) {
+}
+
+func (s *nearCacheStream) Mutation(seqNo, revNo uint64, flags, expiry, lockTime uint32, cas uint64, datatype uint8,
+	vbID uint16, collectionID uint32, streamID uint16, key, value []byte) {
+	s.cache.invalidateChange(string(key), Cas(cas))
+}
+
+func (s *nearCacheStream) Deletion(seqNo, revNo, cas uint64, datatype uint8, vbID uint16, collectionID uint32,
+	streamID uint16, key, value []byte) {
+	s.cache.invalidateChange(string(key), Cas(cas))
+}
+
+func (s *nearCacheStream) Expiration(seqNo, revNo, cas uint64, vbID uint16, collectionID uint32, streamID uint16,
+	key []byte) {
+	s.cache.invalidateChange(string(key), Cas(cas))
+}
+
+// End is called when the stream for a vbucket is closed. Changes to the vbucket can no longer be seen so the
+// cache is cleared, rather than risk serving stale entries until they expire, and the stream is reopened.
+func (s *nearCacheStream) End(vbID uint16, streamID uint16, err error) {
+	s.cache.setStreamed(vbID, false)
+	s.cache.clear()
+
+	if err != nil && gocbcore.ErrorCause(err) == gocbcore.ErrShutdown {
+		return
+	}
+
+	if err != nil {
+		logWarnf("Near cache change stream for vbucket %d of %s.%s ended: %v", vbID, s.scopeName, s.collectionName, err)
+	}
+
+	s.start()
+}
+
+func (s *nearCacheStream) CreateCollection(seqNo uint64, version uint8, vbID uint16, manifestUID uint64,
+	scopeID uint32, collectionID uint32, ttl uint32, streamID uint16, key []byte) {
+}
+
+func (s *nearCacheStream) DeleteCollection(seqNo uint64, version uint8, vbID uint16, manifestUID uint64,
+	scopeID uint32, collectionID uint32, streamID uint16) {
+	s.cache.clear()
+}
+
+func (s *nearCacheStream) FlushCollection(seqNo uint64, version uint8, vbID uint16, manifestUID uint64,
+	collectionID uint32) {
+	s.cache.clear()
+}
+
+func (s *nearCacheStream) CreateScope(seqNo uint64, version uint8, vbID uint16, manifestUID uint64, scopeID uint32,
+	streamID uint16, key []byte) {
+}
+
+func (s *nearCacheStream) DeleteScope(seqNo uint64, version uint8, vbID uint16, manifestUID uint64, scopeID uint32,
+	streamID uint16) {
+}
+
+func (s *nearCacheStream) ModifyCollection(seqNo uint64, version uint8, vbID uint16, manifestUID uint64,
+	collectionID uint32, ttl uint32, streamID uint16) {
+}
@@ -0,0 +1,373 @@
+package gocb
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+type mockDcpProvider struct {
+	lock      sync.Mutex
+	observers map[uint16]gocbcore.StreamObserver
+	// openFailures is the number of times that opening the stream for each vbucket fails before it succeeds.
+	openFailures map[uint16]int
+}
+
+func (mdp *mockDcpProvider) OpenStream(vbID uint16, flags gocbcore.DcpStreamAddFlag, vbUUID gocbcore.VbUuid, startSeqNo,
+	endSeqNo, snapStartSeqNo, snapEndSeqNo gocbcore.SeqNo, evtHandler gocbcore.StreamObserver, filter *gocbcore.StreamFilter,
+	cb gocbcore.OpenStreamCallback) (gocbcore.PendingOp, error) {
+	mdp.lock.Lock()
+	if mdp.openFailures[vbID] > 0 {
+		mdp.openFailures[vbID]--
+		mdp.lock.Unlock()
+		cb(nil, gocbcore.ErrTmpFail)
+		return &mockPendingOp{}, nil
+	}
+	mdp.observers[vbID] = evtHandler
+	mdp.lock.Unlock()
+
+	cb([]gocbcore.FailoverEntry{{VbUuid: vbUUID, SeqNo: startSeqNo}}, nil)
+	return &mockPendingOp{}, nil
+}
+
+func (mdp *mockDcpProvider) GetFailoverLog(vbID uint16, cb gocbcore.GetFailoverLogCallback) (gocbcore.PendingOp, error) {
+	cb([]gocbcore.FailoverEntry{{VbUuid: gocbcore.VbUuid(vbID + 100), SeqNo: 0}}, nil)
+	return &mockPendingOp{}, nil
+}
+
+func (mdp *mockDcpProvider) GetVbucketSeqnos(serverIdx int, state gocbcore.VbucketState, cb gocbcore.GetVBucketSeqnosCallback) (gocbcore.PendingOp, error) {
+	cb([]gocbcore.VbSeqNoEntry{{VbId: 0, SeqNo: 10}, {VbId: 1, SeqNo: 20}}, nil)
+	return &mockPendingOp{}, nil
+}
+
+func (mdp *mockDcpProvider) GetCollectionID(scopeName string, collectionName string, opts gocbcore.GetCollectionIDOptions,
+	cb gocbcore.CollectionIdCallback) (gocbcore.PendingOp, error) {
+	cb(1, 8, nil)
+	return &mockPendingOp{}, nil
+}
+
+func (mdp *mockDcpProvider) HasCollectionsSupport() bool {
+	return true
+}
+
+func (mdp *mockDcpProvider) NumServers() int {
+	return 1
+}
+
+func (mdp *mockDcpProvider) observer(vbID uint16) gocbcore.StreamObserver {
+	mdp.lock.Lock()
+	defer mdp.lock.Unlock()
+
+	return mdp.observers[vbID]
+}
+
+func testGetNearCachedCollection(t *testing.T, provider *mockKvProvider, opts *NearCacheOptions) (*Collection, *testKvRecorder) {
+	col := testGetCollection(t, provider)
+	recorder := &testKvRecorder{}
+	s := &Scope{sb: col.sb}
+
+	return s.Collection("_default", &CollectionOptions{
+		Interceptors: []KvInterceptor{recorder},
+		NearCache:    opts,
+	}), recorder
+}
+
+func TestNearCacheGet(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`{"name":"ref"}`),
+		flags: 0x02000000,
+	}
+	col, recorder := testGetNearCachedCollection(t, provider, &NearCacheOptions{})
+
+	for i := 0; i < 3; i++ {
+		res, err := col.Get("ref", nil)
+		if err != nil {
+			t.Fatalf("Expected Get to not error %v", err)
+		}
+
+		var doc map[string]string
+		err = res.Content(&doc)
+		if err != nil {
+			t.Fatalf("Expected Content to not error %v", err)
+		}
+
+		if doc["name"] != "ref" || res.Cas() != Cas(1) {
+			t.Fatalf("Expected cached document to match but was %v with cas %d", doc, res.Cas())
+		}
+	}
+
+	if len(recorder.requests) != 1 {
+		t.Fatalf("Expected 1 request to reach the server but was %d", len(recorder.requests))
+	}
+
+	_, err := col.Get("ref", &GetOptions{BypassNearCache: true})
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	_, err = col.Upsert("ref", "new", nil)
+	if err != nil {
+		t.Fatalf("Expected Upsert to not error %v", err)
+	}
+
+	_, err = col.Get("ref", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	expected := []KvOperation{KvOperationGet, KvOperationGet, KvOperationUpsert, KvOperationGet}
+	if len(recorder.requests) != len(expected) {
+		t.Fatalf("Expected %d requests but was %d", len(expected), len(recorder.requests))
+	}
+	for i, req := range recorder.requests {
+		if req.Operation != expected[i] {
+			t.Fatalf("Expected request %d to be %s but was %s", i, expected[i], req.Operation)
+		}
+	}
+
+	stats := col.NearCacheStats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Invalidations != 1 || stats.Entries != 1 {
+		t.Fatalf("Stats were not as expected: %+v", stats)
+	}
+
+	if stats.HitRatio() != 0.5 {
+		t.Fatalf("Expected hit ratio to be 0.5 but was %f", stats.HitRatio())
+	}
+}
+
+func TestNearCacheGetCopiesContents(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`{"name":"ref"}`),
+		flags: 0x02000000,
+	}
+	col, _ := testGetNearCachedCollection(t, provider, &NearCacheOptions{})
+
+	for i := 0; i < 3; i++ {
+		res, err := col.Get("ref", nil)
+		if err != nil {
+			t.Fatalf("Expected Get to not error %v", err)
+		}
+
+		if string(res.contents) != `{"name":"ref"}` {
+			t.Fatalf("Expected cached document to be unchanged but was %s", res.contents)
+		}
+
+		// Modifying the contents in place must not affect later readers.
+		for j := range res.contents {
+			res.contents[j] = ' '
+		}
+	}
+}
+
+func TestNearCacheKeyPrefix(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`"value"`),
+		flags: 0x02000000,
+	}
+	col, recorder := testGetNearCachedCollection(t, provider, &NearCacheOptions{})
+	tenant := col.WithKeyPrefix("t1::")
+
+	_, err := col.Get("doc", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	_, err = tenant.Get("doc", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if len(recorder.requests) != 2 {
+		t.Fatalf("Expected prefixed keys to be cached separately but saw %d requests", len(recorder.requests))
+	}
+
+	_, err = tenant.Remove("doc", nil)
+	if err != nil {
+		t.Fatalf("Expected Remove to not error %v", err)
+	}
+
+	stats := col.NearCacheStats()
+	if stats.Entries != 1 || stats.Invalidations != 1 {
+		t.Fatalf("Expected only the prefixed key to be invalidated but was %+v", stats)
+	}
+}
+
+func TestNearCacheBounds(t *testing.T) {
+	cache := newNearCache(&stateBlock{}, NearCacheOptions{MaxEntries: 2, MaxBytes: 20, TTL: time.Minute})
+	now := time.Unix(1571057032, 0)
+	cache.now = func() time.Time {
+		return now
+	}
+
+	doc := func(contents string) *GetResult {
+		return &GetResult{Result: Result{cas: Cas(1)}, contents: []byte(contents)}
+	}
+
+	cache.put("a", 0, doc("1"), cache.currentEpoch())
+	cache.put("b", 0, doc("2"), cache.currentEpoch())
+	cache.get("a")
+	cache.put("c", 0, doc("3"), cache.currentEpoch())
+
+	if _, ok := cache.get("b"); ok {
+		t.Fatalf("Expected the least recently used entry to be evicted")
+	}
+
+	cache.put("big", 0, doc("0123456789abcdefghij"), cache.currentEpoch())
+	if _, ok := cache.get("big"); ok {
+		t.Fatalf("Expected an entry larger than MaxBytes to not be cached")
+	}
+
+	cache.put("d", 0, doc("0123456789abcdefgh"), cache.currentEpoch())
+	stats := cache.snapshot()
+	if stats.Entries != 1 || stats.Bytes != 19 || stats.Evictions != 3 {
+		t.Fatalf("Expected entries to be evicted to fit MaxBytes but was %+v", stats)
+	}
+
+	epoch := cache.currentEpoch()
+	cache.invalidate("e")
+	cache.put("e", 0, doc("5"), epoch)
+	if _, ok := cache.get("e"); ok {
+		t.Fatalf("Expected a read which raced with a write to not be cached")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.get("d"); ok {
+		t.Fatalf("Expected the entry to have expired")
+	}
+
+	if stats := cache.snapshot(); stats.Expirations != 1 || stats.Entries != 0 {
+		t.Fatalf("Expected the expired entry to be removed but was %+v", stats)
+	}
+}
+
+func TestNearCacheChangeStream(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(5),
+		value: []byte(`"value"`),
+		flags: 0x02000000,
+	}
+	dcp := &mockDcpProvider{observers: make(map[uint16]gocbcore.StreamObserver)}
+	col := testGetCollection(t, provider)
+	col.sb.getCachedClient().(*mockClient).mockDcpProvider = dcp
+
+	s := &Scope{sb: col.sb}
+	col = s.Collection("_default", &CollectionOptions{NearCache: &NearCacheOptions{ChangeStream: true}})
+
+	testWaitForStreamedVbuckets(t, col, 2)
+
+	for _, key := range []string{"same", "changed"} {
+		_, err := col.Get(key, nil)
+		if err != nil {
+			t.Fatalf("Expected Get to not error %v", err)
+		}
+	}
+
+	dcp.observer(0).Mutation(11, 1, 0, 0, 0, 5, 0, 0, 0, 0, []byte("same"), nil)
+	dcp.observer(0).Deletion(12, 1, 6, 0, 0, 0, 0, []byte("changed"), nil)
+
+	stats := col.NearCacheStats()
+	if stats.Entries != 1 || stats.Invalidations != 1 {
+		t.Fatalf("Expected only the changed key to be invalidated but was %+v", stats)
+	}
+
+	dcp.observer(1).End(1, 0, gocbcore.ErrStreamStateChanged)
+
+	stats = col.NearCacheStats()
+	if stats.Entries != 0 {
+		t.Fatalf("Expected the cache to be cleared when a stream ends but was %+v", stats)
+	}
+
+	testWaitForStreamedVbuckets(t, col, 2)
+}
+
+func testWaitForStreamedVbuckets(t *testing.T, col *Collection, expected int) {
+	deadline := time.Now().Add(time.Second)
+	for col.NearCacheStats().StreamedVbuckets != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d change streams to open, %d were open", expected,
+				col.NearCacheStats().StreamedVbuckets)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNearCacheChangeStreamReopen(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(5),
+		value: []byte(`"value"`),
+		flags: 0x02000000,
+		mt:    gocbcore.MutationToken{VbId: 1},
+	}
+	dcp := &mockDcpProvider{
+		observers:    make(map[uint16]gocbcore.StreamObserver),
+		openFailures: map[uint16]int{1: math.MaxInt32},
+	}
+	col := testGetCollection(t, provider)
+	col.sb.getCachedClient().(*mockClient).mockDcpProvider = dcp
+
+	s := &Scope{sb: col.sb}
+	col = s.Collection("_default", &CollectionOptions{NearCache: &NearCacheOptions{ChangeStream: true}})
+
+	testWaitForStreamedVbuckets(t, col, 1)
+
+	dcp.lock.Lock()
+	_, ok := dcp.observers[0]
+	dcp.lock.Unlock()
+	if !ok {
+		t.Fatalf("Expected the stream for vbucket 0 to open whilst vbucket 1 was failing")
+	}
+
+	_, err := col.Get("unstreamed", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if entries := col.NearCacheStats().Entries; entries != 0 {
+		t.Fatalf("Expected documents in unstreamed vbuckets to not be cached but had %d entries", entries)
+	}
+
+	dcp.lock.Lock()
+	dcp.openFailures[1] = 0
+	dcp.lock.Unlock()
+
+	testWaitForStreamedVbuckets(t, col, 2)
+
+	_, err = col.Get("streamed", nil)
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if entries := col.NearCacheStats().Entries; entries != 1 {
+		t.Fatalf("Expected documents to be cached once the vbucket was streamed but had %d entries", entries)
+	}
+}
+
+func TestNearCacheOptionsMismatch(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(5),
+		value: []byte(`"value"`),
+		flags: 0x02000000,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.NearCaches = newNearCacheRegistry()
+	s := &Scope{sb: col.sb}
+	s.Collection("_default", &CollectionOptions{NearCache: &NearCacheOptions{}})
+
+	same := s.Collection("_default", &CollectionOptions{NearCache: &NearCacheOptions{MaxEntries: 10000}})
+	_, err := same.Get("key", nil)
+	if err != nil {
+		t.Fatalf("Expected Get with the default options to not error %v", err)
+	}
+
+	different := s.Collection("_default", &CollectionOptions{NearCache: &NearCacheOptions{TTL: time.Second}})
+	_, err = different.Get("key", nil)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected Get with different options to be invalid but was %v", err)
+	}
+}
@@ -0,0 +1,515 @@
+package gocb
+
+import (
+	"context"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
+)
+
+// StreamReplicasOptions are the options available to the StreamReplicas command.
+type StreamReplicasOptions struct {
+	Timeout    time.Duration
+	Context    context.Context
+	Transcoder Transcoder
+}
+
+// StreamReplicas reads a document from the active and every replica server in parallel, streaming each copy as it
+// is read. Unlike GetAllReplicas a failure to read any single copy does not end the stream, see
+// GetReplicaResult.Err. The stream must be closed once finished with.
+func (c *Collection) StreamReplicas(key string, opts *StreamReplicasOptions) (*ReplicaStream, error) {
+	if opts == nil {
+		opts = &StreamReplicasOptions{}
+	}
+
+	agent, err := c.getKvProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Transcoder == nil {
+		opts.Transcoder = c.sb.Transcoder
+	}
+
+	ctx, cancel := c.context(opts.Context, opts.Timeout)
+	numCopies := agent.NumReplicas() + 1
+	addresses := c.kvServerAddresses()
+	vbID := agent.KeyToVbucket([]byte(key))
+
+	stream := &ReplicaStream{
+		ctx:       ctx,
+		cancel:    cancel,
+		resultCh:  make(chan GetReplicaResult, numCopies),
+		numCopies: numCopies,
+	}
+
+	for replicaIdx := 0; replicaIdx < numCopies; replicaIdx++ {
+		var node string
+		if serverIdx := agent.VbucketToServer(vbID, uint32(replicaIdx)); serverIdx >= 0 && serverIdx < len(addresses) {
+			node = addresses[serverIdx]
+		}
+
+		go func(replicaIdx int, node string) {
+			res := GetReplicaResult{}
+			doc, err := c.getReplica(ctx, agent, key, replicaIdx, opts.Transcoder)
+			if err != nil {
+				res.err = err
+				res.isMaster = replicaIdx == 0
+			} else {
+				res = *doc
+			}
+			res.replicaIdx = replicaIdx
+			res.node = node
+
+			stream.resultCh <- res
+		}(replicaIdx, node)
+	}
+
+	return stream, nil
+}
+
+// ReplicaFallbackOptions are the options for falling back to a replica read as a part of a Get request.
+type ReplicaFallbackOptions struct {
+	Policy ReplicaFallbackPolicy
+	// After is how long the active copy is given to respond before falling back. When Policy is
+	// ReplicaFallbackHedged the active read is left outstanding, otherwise it is cancelled. Defaults to half of
+	// the operation timeout.
+	After time.Duration
+}
+
+// ReplicaFallbackEvent describes a Get operation which fell back to reading from a replica.
+type ReplicaFallbackEvent struct {
+	BucketName     string
+	ScopeName      string
+	CollectionName string
+	Key            string
+	Policy         ReplicaFallbackPolicy
+	// ActiveError is the error which caused the fallback, this is a timeout error if the active copy did not
+	// respond in time.
+	ActiveError error
+	// ReplicaError is the error returned by the replica read, if any.
+	ReplicaError error
+	// FromReplica indicates whether or not the document returned to the caller was read from a replica.
+	FromReplica bool
+	Elapsed     time.Duration
+}
+
+// replicaConsistency is the point within a vbucket's history that a replica must have reached for reads from
+// it to be consistent with a MutationState.
+type replicaConsistency struct {
+	vbID   uint16
+	vbUUID gocbcore.VbUuid
+	seqNo  gocbcore.SeqNo
+}
+
+// replicaConsistencyFor returns the consistency requirements for reading key from a replica, or nil if state
+// contains no token for the vbucket that key belongs to.
+func (c *Collection) replicaConsistencyFor(agent kvProvider, key string, state *MutationState) *replicaConsistency {
+	if state == nil {
+		return nil
+	}
+
+	vbID := agent.KeyToVbucket([]byte(key))
+	vbUUID, seqNo, ok := state.vbucketToken(c.sb.BucketName, vbID)
+	if !ok {
+		return nil
+	}
+
+	return &replicaConsistency{
+		vbID:   vbID,
+		vbUUID: vbUUID,
+		seqNo:  seqNo,
+	}
+}
+
+// observe sends an ObserveVbEx request to the node holding the copy of the vbucket at replicaIdx, invoking cb
+// with whether or not that copy has seen the sequence number.
+func (rc *replicaConsistency) observe(provider kvProvider, replicaIdx int,
+	cb func(consistent bool, err error)) (gocbcore.PendingOp, error) {
+	return provider.ObserveVbEx(gocbcore.ObserveVbOptions{
+		VbId:       rc.vbID,
+		VbUuid:     rc.vbUUID,
+		ReplicaIdx: replicaIdx,
+	}, func(res *gocbcore.ObserveVbResult, err error) {
+		if err != nil {
+			cb(false, err)
+			return
+		}
+
+		// If the vbucket has failed over since the mutation then the mutation only survived if the new
+		// history branched after it.
+		if res.DidFailover && res.LastSeqNo < rc.seqNo {
+			cb(false, nil)
+			return
+		}
+
+		cb(res.CurrentSeqNo >= rc.seqNo, nil)
+	})
+}
+
+// check verifies whether or not the copy of the vbucket at replicaIdx has seen the sequence number.
+func (rc *replicaConsistency) check(ctx context.Context, provider kvProvider, replicaIdx int) (bool, error) {
+	type checkResult struct {
+		consistent bool
+		err        error
+	}
+	resultCh := make(chan checkResult, 1)
+	op, err := rc.observe(provider, replicaIdx, func(consistent bool, err error) {
+		resultCh <- checkResult{consistent: consistent, err: err}
+	})
+	if err != nil {
+		return false, err
+	}
+
+	select {
+	case <-ctx.Done():
+		if op.Cancel() {
+			if ctx.Err() == context.DeadlineExceeded {
+				return false, timeoutError{}
+			}
+			return false, ctx.Err()
+		}
+		res := <-resultCh
+		return res.consistent, res.err
+	case res := <-resultCh:
+		return res.consistent, res.err
+	}
+}
+
+// getReplica fetches key from the copy at replicaIdx, where 0 is the active copy.
+func (c *Collection) getReplica(ctx context.Context, agent kvProvider, key string, replicaIdx int,
+	transcoder Transcoder) (docOut *GetReplicaResult, errOut error) {
+	ctrl := c.newOpManager(ctx)
+	var err error
+	if replicaIdx == 0 {
+		err = ctrl.wait(agent.GetEx(gocbcore.GetOptions{
+			Key:            []byte(key),
+			CollectionName: c.name(),
+			ScopeName:      c.scopeName(),
+		}, func(res *gocbcore.GetResult, err error) {
+			if err != nil {
+				errOut = maybeEnhanceKVErr(err, key, false)
+				ctrl.resolve()
+				return
+			}
+
+			docOut = &GetReplicaResult{
+				GetResult: GetResult{
+					Result: Result{
+						cas: Cas(res.Cas),
+					},
+					transcoder: transcoder,
+					contents:   res.Value,
+					flags:      res.Flags,
+				},
+				isMaster: true,
+			}
+
+			ctrl.resolve()
+		}))
+	} else {
+		err = ctrl.wait(agent.GetOneReplicaEx(gocbcore.GetOneReplicaOptions{
+			Key:            []byte(key),
+			ReplicaIdx:     replicaIdx,
+			CollectionName: c.name(),
+			ScopeName:      c.scopeName(),
+		}, func(res *gocbcore.GetReplicaResult, err error) {
+			if err != nil {
+				errOut = maybeEnhanceKVErr(err, key, false)
+				ctrl.resolve()
+				return
+			}
+
+			docOut = &GetReplicaResult{
+				GetResult: GetResult{
+					Result: Result{
+						cas: Cas(res.Cas),
+					},
+					transcoder: transcoder,
+					contents:   res.Value,
+					flags:      res.Flags,
+				},
+			}
+
+			ctrl.resolve()
+		}))
+	}
+	if err != nil {
+		errOut = err
+	}
+
+	return
+}
+
+// defaultReplicaConsistencyPollInterval is how often copies are observed whilst waiting for one to catch up.
+const defaultReplicaConsistencyPollInterval = 50 * time.Millisecond
+
+// getAnyConsistentReplica observes every copy of the vbucket and reads key from the first copy which has seen
+// the sequence number required by consistency. If no copy is consistent then it polls again until either the
+// consistency timeout passes or ctx is done.
+func (c *Collection) getAnyConsistentReplica(ctx context.Context, agent kvProvider, key string,
+	consistency *replicaConsistency, opts GetFromReplicaOptions) (*GetReplicaResult, error) {
+	type observeResponse struct {
+		replicaIdx int
+		consistent bool
+		err        error
+	}
+
+	interval := opts.ConsistencyPollInterval
+	if interval <= 0 {
+		interval = defaultReplicaConsistencyPollInterval
+	}
+
+	if opts.ConsistencyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.ConsistencyTimeout)
+		defer cancel()
+	}
+
+	numServers := agent.NumReplicas() + 1
+	behind := make(map[int]error, numServers)
+	done := func() error {
+		if ctx.Err() != context.DeadlineExceeded {
+			return ctx.Err()
+		}
+
+		return newReplicaConsistencyError(key, consistency, behind)
+	}
+
+	for {
+		respCh := make(chan observeResponse, numServers)
+		var ops []gocbcore.PendingOp
+		for replicaIdx := 0; replicaIdx < numServers; replicaIdx++ {
+			replicaIdx := replicaIdx
+			op, err := consistency.observe(agent, replicaIdx, func(consistent bool, err error) {
+				respCh <- observeResponse{replicaIdx: replicaIdx, consistent: consistent, err: err}
+			})
+			if err != nil {
+				behind[replicaIdx] = err
+				continue
+			}
+			ops = append(ops, op)
+		}
+
+		cancelOps := func() {
+			for _, op := range ops {
+				op.Cancel()
+			}
+		}
+
+		for range ops {
+			select {
+			case resp := <-respCh:
+				if resp.err != nil || !resp.consistent {
+					behind[resp.replicaIdx] = resp.err
+					continue
+				}
+
+				doc, err := c.getReplica(ctx, agent, key, resp.replicaIdx, opts.Transcoder)
+				if err == nil || IsKeyNotFoundError(err) || ctx.Err() != nil {
+					cancelOps()
+					return doc, err
+				}
+				behind[resp.replicaIdx] = err
+				logDebugf("Failed to read consistent replica %d, trying next: %v", resp.replicaIdx, err)
+			case <-ctx.Done():
+				cancelOps()
+				return nil, done()
+			}
+		}
+
+		waitTmr := gocbcore.AcquireTimer(interval)
+		select {
+		case <-waitTmr.C:
+			gocbcore.ReleaseTimer(waitTmr, true)
+		case <-ctx.Done():
+			gocbcore.ReleaseTimer(waitTmr, false)
+			return nil, done()
+		}
+	}
+}
+
+// shouldFallbackToReplica returns whether or not err from the active read should trigger a replica read. The agent
+// transparently retries not my vbucket responses so a storm of them usually surfaces as a timeout.
+func shouldFallbackToReplica(policy ReplicaFallbackPolicy, err error) bool {
+	if IsTimeoutError(err) {
+		return true
+	}
+
+	if policy != ReplicaFallbackOnNotMyVbucket {
+		return false
+	}
+
+	cause := errors.Cause(err)
+	if kvErr, ok := cause.(KeyValueError); ok {
+		return kvErr.StatusCode() == int(gocbcore.StatusNotMyVBucket)
+	}
+
+	return cause == gocbcore.ErrNoServer || cause == gocbcore.ErrNetwork
+}
+
+// replicaFallbackWindow returns how long the active copy is given to respond before falling back.
+func replicaFallbackWindow(ctx context.Context, opts *ReplicaFallbackOptions) time.Duration {
+	if opts.After > 0 {
+		return opts.After
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+
+	return time.Until(deadline) / 2
+}
+
+// notifyReplicaFallback reports a Get which read from a replica, unless the replica read was a hedge which went
+// unused because the active copy responded successfully.
+func (c *Collection) notifyReplicaFallback(event ReplicaFallbackEvent) {
+	if !event.FromReplica && event.ActiveError == nil {
+		logDebugf("Hedged replica read for %s went unused after %s", event.Key, event.Elapsed)
+		return
+	}
+
+	logWarnf("Get fell back to a replica read (policy %d, from replica %t) after %s: %v",
+		event.Policy, event.FromReplica, event.Elapsed, event.ActiveError)
+
+	if c.sb.ReplicaFallbackHandler != nil {
+		c.sb.ReplicaFallbackHandler(event)
+	}
+}
+
+// getWithReplicaFallback performs a full document fetch against the active copy, reading from any replica
+// instead when the active copy fails to respond as described by opts.ReplicaFallback.
+func (c *Collection) getWithReplicaFallback(ctx context.Context, key string, opts *GetOptions) (*GetResult, error) {
+	policy := opts.ReplicaFallback.Policy
+	window := replicaFallbackWindow(ctx, opts.ReplicaFallback)
+	start := time.Now()
+	event := ReplicaFallbackEvent{
+		BucketName:     c.sb.BucketName,
+		ScopeName:      c.scopeName(),
+		CollectionName: c.name(),
+		Key:            key,
+		Policy:         policy,
+	}
+
+	getReplica := func(ctx context.Context) (*GetResult, error) {
+		doc, err := c.getAnyReplica(ctx, key, GetFromReplicaOptions{Transcoder: opts.Transcoder})
+		if err != nil {
+			return nil, err
+		}
+
+		doc.GetResult.fromReplica = true
+		return &doc.GetResult, nil
+	}
+
+	if policy == ReplicaFallbackHedged {
+		return c.getHedged(ctx, key, opts, window, getReplica, event)
+	}
+
+	activeCtx, activeCancel := context.WithTimeout(ctx, window)
+	doc, err := c.get(activeCtx, key, opts)
+	activeCancel()
+	if err == nil || ctx.Err() != nil || !shouldFallbackToReplica(policy, err) {
+		return doc, err
+	}
+
+	event.ActiveError = err
+	replicaDoc, replicaErr := getReplica(ctx)
+	event.ReplicaError = replicaErr
+	event.FromReplica = replicaErr == nil
+	event.Elapsed = time.Since(start)
+	c.notifyReplicaFallback(event)
+
+	if replicaErr != nil {
+		if IsKeyNotFoundError(replicaErr) {
+			return nil, replicaErr
+		}
+		return nil, err
+	}
+
+	return replicaDoc, nil
+}
+
+// getHedged reads from the active copy and, if it has not responded within window, from any replica as well. The
+// first successful response is returned and the other read is cancelled.
+func (c *Collection) getHedged(ctx context.Context, key string, opts *GetOptions, window time.Duration,
+	getReplica func(context.Context) (*GetResult, error), event ReplicaFallbackEvent) (*GetResult, error) {
+	type getResponse struct {
+		doc       *GetResult
+		err       error
+		isReplica bool
+	}
+
+	start := time.Now()
+	hedgeCtx, hedgeCancel := context.WithCancel(ctx)
+	defer hedgeCancel()
+
+	respCh := make(chan getResponse, 2)
+	go func() {
+		doc, err := c.get(hedgeCtx, key, opts)
+		respCh <- getResponse{doc: doc, err: err}
+	}()
+
+	hedgeTmr := gocbcore.AcquireTimer(window)
+	select {
+	case resp := <-respCh:
+		gocbcore.ReleaseTimer(hedgeTmr, false)
+		if resp.err == nil || ctx.Err() != nil || !shouldFallbackToReplica(ReplicaFallbackOnNotMyVbucket, resp.err) {
+			return resp.doc, resp.err
+		}
+
+		// The active copy is unreachable so there's no point in waiting to hedge.
+		event.ActiveError = resp.err
+		doc, err := getReplica(ctx)
+		event.ReplicaError = err
+		event.FromReplica = err == nil
+		event.Elapsed = time.Since(start)
+		c.notifyReplicaFallback(event)
+		if err != nil && !IsKeyNotFoundError(err) {
+			return nil, resp.err
+		}
+		return doc, err
+	case <-hedgeTmr.C:
+		gocbcore.ReleaseTimer(hedgeTmr, true)
+	case <-ctx.Done():
+		gocbcore.ReleaseTimer(hedgeTmr, false)
+		resp := <-respCh
+		return resp.doc, resp.err
+	}
+
+	go func() {
+		doc, err := getReplica(hedgeCtx)
+		respCh <- getResponse{doc: doc, err: err, isReplica: true}
+	}()
+
+	var firstErr error
+	for i := 0; i < 2; i++ {
+		resp := <-respCh
+		if resp.isReplica {
+			event.ReplicaError = resp.err
+		} else {
+			event.ActiveError = resp.err
+		}
+
+		// A response from the active copy, such as the document not existing, is always authoritative.
+		if resp.err == nil || (!resp.isReplica && !shouldFallbackToReplica(ReplicaFallbackOnNotMyVbucket, resp.err)) {
+			hedgeCancel()
+			event.FromReplica = resp.isReplica && resp.err == nil
+			if event.FromReplica && event.ActiveError == nil {
+				event.ActiveError = timeoutError{}
+			}
+			event.Elapsed = time.Since(start)
+			c.notifyReplicaFallback(event)
+			return resp.doc, resp.err
+		}
+
+		if firstErr == nil || (!resp.isReplica && !IsKeyNotFoundError(firstErr)) {
+			firstErr = resp.err
+		}
+	}
+
+	event.Elapsed = time.Since(start)
+	c.notifyReplicaFallback(event)
+	return nil, firstErr
+}
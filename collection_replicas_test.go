@@ -0,0 +1,457 @@
+package gocb
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
+)
+
+func testReplicaMutationState(seqNo gocbcore.SeqNo) *MutationState {
+	return NewMutationState(MutationToken{
+		token: gocbcore.MutationToken{
+			VbId:   12,
+			VbUuid: 1234,
+			SeqNo:  seqNo,
+		},
+		bucketName: "mock",
+	})
+}
+
+func TestGetAnyReplicaConsistentWith(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`"test"`),
+		mt: gocbcore.MutationToken{
+			VbId:  12,
+			SeqNo: 10,
+		},
+		numReplicas: 1,
+	}
+	col := testGetCollection(t, provider)
+
+	res, err := col.GetAnyReplica("consistentReplica", &GetFromReplicaOptions{
+		ConsistentWith: testReplicaMutationState(10),
+	})
+	if err != nil {
+		t.Fatalf("Expected GetAnyReplica to not error %v", err)
+	}
+
+	var val string
+	err = res.Content(&val)
+	if err != nil {
+		t.Fatalf("Failed to get content %v", err)
+	}
+
+	if val != "test" {
+		t.Fatalf("Expected value to be test but was %s", val)
+	}
+}
+
+func TestGetAnyReplicaConsistentWithBehind(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`"test"`),
+		mt: gocbcore.MutationToken{
+			VbId:  12,
+			SeqNo: 5,
+		},
+		numReplicas: 1,
+	}
+	col := testGetCollection(t, provider)
+	col.sb.DuraPollTimeout = time.Second
+
+	_, err := col.GetAnyReplica("consistentReplicaBehind", &GetFromReplicaOptions{
+		ConsistentWith:          testReplicaMutationState(10),
+		ConsistencyPollInterval: 5 * time.Millisecond,
+		Timeout:                 50 * time.Millisecond,
+	})
+	if !IsTimeoutError(err) || !IsReplicaConsistencyError(err) {
+		t.Fatalf("Expected GetAnyReplica to timeout but was %v", err)
+	}
+
+	behind := errors.Cause(err).(ReplicaConsistencyError).BehindReplicas()
+	if _, ok := behind[0]; !ok || len(behind) != 2 {
+		t.Fatalf("Expected error to name the active and replica as behind but was %v", err)
+	}
+
+	count := atomic.LoadUint32(&provider.observeVbCount)
+	if count < 4 {
+		t.Fatalf("Expected replicas to be observed more than once but was observed %d times", count)
+	}
+
+	start := time.Now()
+	_, err = col.GetAnyReplica("consistentReplicaBehind", &GetFromReplicaOptions{
+		ConsistentWith:     testReplicaMutationState(10),
+		ConsistencyTimeout: 20 * time.Millisecond,
+		Timeout:            time.Second,
+	})
+	if !IsReplicaConsistencyError(err) || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("Expected GetAnyReplica to give up after the consistency timeout but was %v", err)
+	}
+}
+
+func TestGetAllReplicasConsistentWithBehind(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:   gocbcore.Cas(1),
+		value: []byte(`"test"`),
+		mt: gocbcore.MutationToken{
+			VbId:  12,
+			SeqNo: 5,
+		},
+		numReplicas: 2,
+	}
+	col := testGetCollection(t, provider)
+
+	results, err := col.GetAllReplicas("consistentAllReplicas", &GetFromReplicaOptions{
+		ConsistentWith: testReplicaMutationState(10),
+	})
+	if err != nil {
+		t.Fatalf("Expected GetAllReplicas to not error %v", err)
+	}
+
+	var res GetReplicaResult
+	if results.Next(&res) {
+		t.Fatalf("Expected all replicas to be filtered out")
+	}
+
+	err = results.Close()
+	if err != nil {
+		t.Fatalf("Expected Close to not error %v", err)
+	}
+
+	results, err = col.GetAllReplicas("consistentAllReplicas", &GetFromReplicaOptions{
+		ConsistentWith: testReplicaMutationState(5),
+	})
+	if err != nil {
+		t.Fatalf("Expected GetAllReplicas to not error %v", err)
+	}
+
+	count := 0
+	for results.Next(&res) {
+		count++
+	}
+
+	err = results.Close()
+	if err != nil {
+		t.Fatalf("Expected Close to not error %v", err)
+	}
+
+	if count != 3 {
+		t.Fatalf("Expected 3 replicas but was %d", count)
+	}
+}
+
+func TestGetReplicaFallbackOnTimeout(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:                   gocbcore.Cas(1),
+		value:                 []byte(`"replica"`),
+		numReplicas:           1,
+		getWait:               500 * time.Millisecond,
+		opCancellationSuccess: true,
+	}
+	col := testGetCollection(t, provider)
+
+	var events []ReplicaFallbackEvent
+	col.sb.ReplicaFallbackHandler = func(event ReplicaFallbackEvent) {
+		events = append(events, event)
+	}
+
+	res, err := col.Get("fallbackTimeout", &GetOptions{
+		ReplicaFallback: &ReplicaFallbackOptions{
+			Policy: ReplicaFallbackOnTimeout,
+			After:  20 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if !res.FromReplica() {
+		t.Fatalf("Expected result to be from a replica")
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 fallback event but was %d", len(events))
+	}
+
+	if !events[0].FromReplica || !IsTimeoutError(events[0].ActiveError) {
+		t.Fatalf("Expected fallback event to be a timeout served by a replica but was %+v", events[0])
+	}
+}
+
+func TestGetReplicaFallbackOnNotMyVbucket(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:         gocbcore.Cas(1),
+		value:       []byte(`"replica"`),
+		numReplicas: 1,
+		getErr:      gocbcore.ErrNotMyVBucket,
+	}
+	col := testGetCollection(t, provider)
+
+	_, err := col.Get("fallbackNmv", &GetOptions{
+		ReplicaFallback: &ReplicaFallbackOptions{
+			Policy: ReplicaFallbackOnTimeout,
+		},
+	})
+	if err == nil {
+		t.Fatalf("Expected Get with timeout fallback to not fall back on not my vbucket")
+	}
+
+	res, err := col.Get("fallbackNmv", &GetOptions{
+		ReplicaFallback: &ReplicaFallbackOptions{
+			Policy: ReplicaFallbackOnNotMyVbucket,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if !res.FromReplica() {
+		t.Fatalf("Expected result to be from a replica")
+	}
+}
+
+func TestGetReplicaFallbackHedged(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:                   gocbcore.Cas(1),
+		value:                 []byte(`"replica"`),
+		numReplicas:           1,
+		getWait:               500 * time.Millisecond,
+		opCancellationSuccess: true,
+	}
+	col := testGetCollection(t, provider)
+
+	var events []ReplicaFallbackEvent
+	col.sb.ReplicaFallbackHandler = func(event ReplicaFallbackEvent) {
+		events = append(events, event)
+	}
+
+	res, err := col.Get("fallbackHedged", &GetOptions{
+		ReplicaFallback: &ReplicaFallbackOptions{
+			Policy: ReplicaFallbackHedged,
+			After:  20 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if !res.FromReplica() {
+		t.Fatalf("Expected result to be from a replica")
+	}
+
+	if len(events) != 1 || !events[0].FromReplica {
+		t.Fatalf("Expected 1 fallback event served by a replica but was %+v", events)
+	}
+
+	provider.getWait = 0
+	events = nil
+	res, err = col.Get("fallbackHedged", &GetOptions{
+		ReplicaFallback: &ReplicaFallbackOptions{
+			Policy: ReplicaFallbackHedged,
+			After:  200 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if res.FromReplica() {
+		t.Fatalf("Expected result to be from the active")
+	}
+
+	if len(events) != 0 {
+		t.Fatalf("Expected no fallback events but was %+v", events)
+	}
+
+	// The hedge is sent but the active copy still responds first.
+	provider.getWait = 50 * time.Millisecond
+	provider.replicaWait = 500 * time.Millisecond
+	res, err = col.Get("fallbackHedged", &GetOptions{
+		ReplicaFallback: &ReplicaFallbackOptions{
+			Policy: ReplicaFallbackHedged,
+			After:  10 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected Get to not error %v", err)
+	}
+
+	if res.FromReplica() {
+		t.Fatalf("Expected result to be from the active")
+	}
+
+	if len(events) != 0 {
+		t.Fatalf("Expected an unused hedge not to be reported but was %+v", events)
+	}
+}
+
+func TestGetReplicaFallbackWithProjection(t *testing.T) {
+	provider := &mockKvProvider{}
+	col := testGetCollection(t, provider)
+
+	_, err := col.Get("fallbackProjection", &GetOptions{
+		Project: &ProjectOptions{Fields: []string{"field"}},
+		ReplicaFallback: &ReplicaFallbackOptions{
+			Policy: ReplicaFallbackOnTimeout,
+		},
+	})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected invalid arguments error but was %v", err)
+	}
+}
+
+func TestStreamReplicasPerReplicaErrors(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:         gocbcore.Cas(1),
+		value:       []byte(`"test"`),
+		numReplicas: 2,
+		replicaErr: map[int]error{
+			2: gocbcore.ErrTimeout,
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	stream, err := col.StreamReplicas("streamReplicas", nil)
+	if err != nil {
+		t.Fatalf("Expected StreamReplicas to not error %v", err)
+	}
+
+	seen := make(map[int]*GetReplicaResult)
+	for {
+		var res GetReplicaResult
+		if !stream.Next(&res) {
+			break
+		}
+		seen[res.ReplicaIdx()] = &res
+	}
+
+	err = stream.Close()
+	if err != nil {
+		t.Fatalf("Expected Close to not error %v", err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("Expected 3 copies but was %d", len(seen))
+	}
+
+	if !seen[0].IsMaster() || seen[0].Err() != nil {
+		t.Fatalf("Expected copy 0 to be a successful read from the active")
+	}
+
+	if seen[1].Err() != nil {
+		t.Fatalf("Expected copy 1 to not error %v", seen[1].Err())
+	}
+
+	if seen[2].Err() == nil {
+		t.Fatalf("Expected copy 2 to have an error")
+	}
+}
+
+func TestStreamReplicasContextCancelled(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:                   gocbcore.Cas(1),
+		value:                 []byte(`"test"`),
+		numReplicas:           1,
+		opWait:                500 * time.Millisecond,
+		opCancellationSuccess: true,
+	}
+	col := testGetCollection(t, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := col.StreamReplicas("streamReplicasCancel", &StreamReplicasOptions{Context: ctx})
+	if err != nil {
+		t.Fatalf("Expected StreamReplicas to not error %v", err)
+	}
+	cancel()
+
+	var res GetReplicaResult
+	if stream.Next(&res) {
+		t.Fatalf("Expected Next to return false once cancelled")
+	}
+
+	err = stream.Close()
+	if err != context.Canceled {
+		t.Fatalf("Expected Close to return context cancelled but was %v", err)
+	}
+}
+
+func TestStreamReplicasConsensus(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:         gocbcore.Cas(1),
+		value:       []byte(`"test"`),
+		numReplicas: 2,
+		replicaCas: map[int]gocbcore.Cas{
+			2: gocbcore.Cas(2),
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	stream, err := col.StreamReplicas("consensus", nil)
+	if err != nil {
+		t.Fatalf("Expected StreamReplicas to not error %v", err)
+	}
+
+	consensus, err := stream.Consensus()
+	if err != nil {
+		t.Fatalf("Expected Consensus to not error %v", err)
+	}
+
+	if !consensus.HasMajority() || consensus.Agreed != 2 || consensus.Copies != 3 {
+		t.Fatalf("Expected 2 of 3 copies to agree but was %d of %d", consensus.Agreed, consensus.Copies)
+	}
+
+	if !consensus.Diverged {
+		t.Fatalf("Expected copies to have diverged")
+	}
+
+	if consensus.Result == nil || consensus.Result.Cas() != Cas(1) {
+		t.Fatalf("Expected consensus result to have cas 1")
+	}
+
+	provider.replicaCas[1] = gocbcore.Cas(3)
+	stream, err = col.StreamReplicas("consensus", nil)
+	if err != nil {
+		t.Fatalf("Expected StreamReplicas to not error %v", err)
+	}
+
+	consensus, err = stream.Consensus()
+	if err != nil {
+		t.Fatalf("Expected Consensus to not error %v", err)
+	}
+
+	if consensus.HasMajority() || consensus.Result != nil {
+		t.Fatalf("Expected no majority")
+	}
+}
+
+func TestStreamReplicasConsensusNotFound(t *testing.T) {
+	provider := &mockKvProvider{
+		cas:         gocbcore.Cas(1),
+		value:       []byte(`"test"`),
+		numReplicas: 2,
+		replicaErr: map[int]error{
+			1: gocbcore.ErrKeyNotFound,
+			2: gocbcore.ErrKeyNotFound,
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	stream, err := col.StreamReplicas("consensusNotFound", nil)
+	if err != nil {
+		t.Fatalf("Expected StreamReplicas to not error %v", err)
+	}
+
+	consensus, err := stream.Consensus()
+	if !IsKeyNotFoundError(err) {
+		t.Fatalf("Expected key not found error but was %v", err)
+	}
+
+	if !consensus.Diverged || consensus.Result != nil {
+		t.Fatalf("Expected copies to have diverged with no result")
+	}
+}
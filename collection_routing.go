@@ -0,0 +1,66 @@
+package gocb
+
+import (
+	"context"
+	"time"
+)
+
+// KeyLocation describes where a document is stored within the cluster.
+type KeyLocation struct {
+	Key       string
+	VbucketID uint16
+	// Active is the node holding the active copy of the document.
+	Active NodeLocation
+	// Replicas are the nodes holding each replica of the document, in replica order.
+	Replicas []NodeLocation
+}
+
+// KeyLocationOptions are the options available to the KeyLocation operation.
+type KeyLocationOptions struct {
+	Timeout time.Duration
+	Context context.Context
+	// ServerGroups fetches the server group of each node from the management service. This requires permission
+	// to read the cluster configuration.
+	ServerGroups bool
+}
+
+// KeyLocation returns the vbucket and nodes which serve key, according to the current cluster configuration.
+// The location is a point in time snapshot which may change during a rebalance or failover.
+func (c *Collection) KeyLocation(key string, opts *KeyLocationOptions) (*KeyLocation, error) {
+	if opts == nil {
+		opts = &KeyLocationOptions{}
+	}
+
+	ctx, cancel := c.context(opts.Context, opts.Timeout)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	return c.keyLocation(ctx, key, *opts)
+}
+
+func (c *Collection) keyLocation(ctx context.Context, key string, opts KeyLocationOptions) (*KeyLocation, error) {
+	agent, err := c.getKvProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := routingNodes(ctx, c.sb.getCachedClient(), agent, opts.ServerGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	vbID := agent.KeyToVbucket([]byte(key))
+	location := &KeyLocation{
+		Key:       key,
+		VbucketID: vbID,
+		Active:    nodeLocation(nodes, agent.VbucketToServer(vbID, 0)),
+	}
+
+	numReplicas := agent.NumReplicas()
+	for replicaIdx := 1; replicaIdx <= numReplicas; replicaIdx++ {
+		location.Replicas = append(location.Replicas, nodeLocation(nodes, agent.VbucketToServer(vbID, uint32(replicaIdx))))
+	}
+
+	return location, nil
+}
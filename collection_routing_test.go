@@ -0,0 +1,86 @@
+package gocb
+
+import (
+	"bytes"
+	"testing"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func testRoutingCollection(t *testing.T, provider *mockKvProvider) *Collection {
+	col := testGetCollection(t, provider)
+	cli := col.sb.getCachedClient().(*mockClient)
+	cli.kvServers = []string{"10.0.0.1:11210", "10.0.0.2:11210"}
+	cli.mockHTTPProvider = &mockHTTPProvider{
+		doFn: func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+			if req.Path != "/pools/default/serverGroups" {
+				t.Fatalf("Expected request to server groups but was %s", req.Path)
+			}
+
+			body := `{"groups":[` +
+				`{"name":"Group 1","nodes":[{"hostname":"10.0.0.1:8091","ports":{"direct":11210,"sslDirect":11207}}]},` +
+				`{"name":"Group 2","nodes":[{"hostname":"10.0.0.2:8091","ports":{"direct":11210,"sslDirect":11207}}]}]}`
+			return &gocbcore.HttpResponse{
+				Endpoint:   "http://10.0.0.1:8091",
+				StatusCode: 200,
+				Body:       &testReadCloser{bytes.NewBufferString(body), nil},
+			}, nil
+		},
+	}
+
+	return col
+}
+
+func TestKeyLocation(t *testing.T) {
+	provider := &mockKvProvider{
+		numReplicas: 2,
+		numServers:  2,
+		mt:          gocbcore.MutationToken{VbId: 12},
+	}
+	col := testRoutingCollection(t, provider)
+
+	location, err := col.KeyLocation("keyLocation", &KeyLocationOptions{ServerGroups: true})
+	if err != nil {
+		t.Fatalf("Expected KeyLocation to not error %v", err)
+	}
+
+	if location.Key != "keyLocation" || location.VbucketID != 12 {
+		t.Fatalf("Expected vbucket 12 but was %d", location.VbucketID)
+	}
+
+	expectedActive := NodeLocation{Index: 0, Address: "10.0.0.1:11210", ServerGroup: "Group 1"}
+	if location.Active != expectedActive {
+		t.Fatalf("Expected active to be %v but was %v", expectedActive, location.Active)
+	}
+
+	if len(location.Replicas) != 2 {
+		t.Fatalf("Expected 2 replicas but was %d", len(location.Replicas))
+	}
+
+	expectedReplica := NodeLocation{Index: 1, Address: "10.0.0.2:11210", ServerGroup: "Group 2"}
+	if location.Replicas[0] != expectedReplica {
+		t.Fatalf("Expected replica to be %v but was %v", expectedReplica, location.Replicas[0])
+	}
+
+	// There are fewer nodes than copies so the second replica is unassigned.
+	if location.Replicas[1].Index != -1 {
+		t.Fatalf("Expected second replica to be unassigned but was %v", location.Replicas[1])
+	}
+}
+
+func TestKeyLocationWithoutServerGroups(t *testing.T) {
+	provider := &mockKvProvider{
+		numServers: 2,
+	}
+	col := testRoutingCollection(t, provider)
+	col.sb.getCachedClient().(*mockClient).mockHTTPProvider = nil
+
+	location, err := col.KeyLocation("keyLocation", nil)
+	if err != nil {
+		t.Fatalf("Expected KeyLocation to not error %v", err)
+	}
+
+	if location.Active.Address != "10.0.0.1:11210" || location.Active.ServerGroup != "" {
+		t.Fatalf("Expected active to have an address but no server group but was %v", location.Active)
+	}
+}
@@ -8,6 +8,14 @@ import (
 	"github.com/couchbase/gocbcore/v8"
 )
 
+// maxLookupInOps is the maximum number of ops that the server accepts within a single LookupIn request.
+const maxLookupInOps = 16
+
+// lookupInChunkRetryBehavior controls how often a chunked lookup is retried when the document changes between its
+// requests, a document which is constantly changing fails rather than being retried until the timeout.
+var lookupInChunkRetryBehavior RetryBehavior = StandardDelayRetryBehavior(5, 2, 100*time.Millisecond,
+	ExponentialDelayFunction)
+
 // LookupInSpec provides a way to create LookupInOps.
 type LookupInSpec struct {
 }
@@ -23,6 +31,9 @@ type LookupInOptions struct {
 	Timeout    time.Duration
 	WithExpiry bool
 	Serializer JSONSerializer
+	// AccessDeleted allows the lookup to be performed against a deleted document (tombstone), only xattrs are
+	// retained by tombstones.
+	AccessDeleted bool
 }
 
 // LookupInSpecGetOptions are the options available to LookupIn subdoc Get operations.
@@ -125,7 +136,8 @@ func (spec LookupInSpec) Count(path string, opts *LookupInSpecCountOptions) Look
 	return LookupInOp{op: op}
 }
 
-// LookupIn performs a set of subdocument lookup operations on the document identified by key.
+// LookupIn performs a set of subdocument lookup operations on the document identified by key. If more ops are
+// specified than the server supports in a single request then they are split across several requests.
 func (c *Collection) LookupIn(key string, ops []LookupInOp, opts *LookupInOptions) (docOut *LookupInResult, errOut error) {
 	if opts == nil {
 		opts = &LookupInOptions{}
@@ -167,55 +179,125 @@ func (c *Collection) lookupIn(ctx context.Context, key string, ops []LookupInOp,
 		subdocs = append([]gocbcore.SubDocOp{op}, subdocs...)
 	}
 
-	if len(ops) > 16 {
-		return nil, errors.New("too many lookupIn ops specified, maximum 16")
-	}
-
 	serializer := opts.Serializer
 	if serializer == nil {
 		serializer = &DefaultJSONSerializer{}
 	}
 
+	var flags gocbcore.SubdocDocFlag
+	if opts.AccessDeleted {
+		flags |= gocbcore.SubdocDocFlagAccessDeleted
+	}
+
+	cas, contents, err := c.lookupInChunks(ctx, agent, key, subdocs, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	resSet := &LookupInResult{}
+	resSet.serializer = serializer
+	resSet.cas = cas
+	resSet.contents = contents
+
+	if opts.WithExpiry {
+		// if expiry was requested then extract and remove it from the results
+		resSet.withExpiration = true
+		err = resSet.ContentAt(0, &resSet.expiration)
+		if err != nil {
+			return nil, err
+		}
+		resSet.contents = resSet.contents[1:]
+	}
+
+	resSet.pathMap = make(map[string]int)
+	for i, op := range ops {
+		if _, ok := resSet.pathMap[op.op.Path]; !ok {
+			resSet.pathMap[op.op.Path] = i
+		}
+	}
+
+	return resSet, nil
+}
+
+// lookupInChunks performs the subdoc ops using as many LookupInEx requests as are needed to stay within the
+// server's limit on ops per request. If the document changes between requests then all of them are retried, with
+// a backoff, so that the results are always from a single version of the document.
+func (c *Collection) lookupInChunks(ctx context.Context, agent kvProvider, key string,
+	subdocs []gocbcore.SubDocOp, flags gocbcore.SubdocDocFlag) (Cas, []lookupInPartial, error) {
+	for retries := uint(0); ; retries++ {
+		var cas Cas
+		var contents []lookupInPartial
+		consistent := true
+		for start := 0; start < len(subdocs); start += maxLookupInOps {
+			end := start + maxLookupInOps
+			if end > len(subdocs) {
+				end = len(subdocs)
+			}
+
+			chunkCas, chunkContents, err := c.lookupInChunk(ctx, agent, key, subdocs[start:end], flags)
+			if err != nil {
+				return 0, nil, err
+			}
+
+			if start > 0 && chunkCas != cas {
+				consistent = false
+				break
+			}
+
+			cas = chunkCas
+			contents = append(contents, chunkContents...)
+		}
+
+		if consistent {
+			return cas, contents, nil
+		}
+
+		if !lookupInChunkRetryBehavior.CanRetry(retries) {
+			return 0, nil, documentChangedError{key: key, attempts: retries + 1}
+		}
+
+		logDebugf("Document changed during a chunked lookup, retrying")
+
+		waitTmr := gocbcore.AcquireTimer(lookupInChunkRetryBehavior.NextInterval(retries + 1))
+		select {
+		case <-waitTmr.C:
+			gocbcore.ReleaseTimer(waitTmr, true)
+		case <-ctx.Done():
+			gocbcore.ReleaseTimer(waitTmr, false)
+			if ctx.Err() == context.DeadlineExceeded {
+				return 0, nil, timeoutError{}
+			}
+			return 0, nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Collection) lookupInChunk(ctx context.Context, agent kvProvider, key string, subdocs []gocbcore.SubDocOp,
+	flags gocbcore.SubdocDocFlag) (casOut Cas, contentsOut []lookupInPartial, errOut error) {
 	ctrl := c.newOpManager(ctx)
-	err = ctrl.wait(agent.LookupInEx(gocbcore.LookupInOptions{
+	err := ctrl.wait(agent.LookupInEx(gocbcore.LookupInOptions{
 		Key:            []byte(key),
+		Flags:          flags,
 		Ops:            subdocs,
 		CollectionName: c.name(),
 		ScopeName:      c.scopeName(),
 	}, func(res *gocbcore.LookupInResult, err error) {
-		if err != nil && !gocbcore.IsErrorStatus(err, gocbcore.StatusSubDocBadMulti) {
+		if err != nil && !isLookupInPartialSuccess(err) {
 			errOut = maybeEnhanceKVErr(err, key, false)
 			ctrl.resolve()
 			return
 		}
 
 		if res != nil {
-			resSet := &LookupInResult{}
-			resSet.serializer = serializer
-			resSet.cas = Cas(res.Cas)
-			resSet.contents = make([]lookupInPartial, len(subdocs))
+			casOut = Cas(res.Cas)
+			contentsOut = make([]lookupInPartial, len(subdocs))
 
 			for i, opRes := range res.Ops {
-				// resSet.contents[i].path = opts.spec.ops[i].Path
-				resSet.contents[i].err = maybeEnhanceKVErr(opRes.Err, key, false)
+				contentsOut[i].err = maybeEnhanceKVErr(opRes.Err, key, false)
 				if opRes.Value != nil {
-					resSet.contents[i].data = append([]byte(nil), opRes.Value...)
-				}
-			}
-
-			if opts.WithExpiry {
-				// if expiry was requested then extract and remove it from the results
-				resSet.withExpiration = true
-				err = resSet.ContentAt(0, &resSet.expiration)
-				if err != nil {
-					errOut = err
-					ctrl.resolve()
-					return
+					contentsOut[i].data = append([]byte(nil), opRes.Value...)
 				}
-				resSet.contents = resSet.contents[1:]
 			}
-
-			docOut = resSet
 		}
 
 		ctrl.resolve()
@@ -227,6 +309,13 @@ func (c *Collection) lookupIn(ctx context.Context, key string, ops []LookupInOp,
 	return
 }
 
+// isLookupInPartialSuccess returns whether or not err still carries per op results.
+func isLookupInPartialSuccess(err error) bool {
+	return gocbcore.IsErrorStatus(err, gocbcore.StatusSubDocBadMulti) ||
+		gocbcore.IsErrorStatus(err, gocbcore.StatusSubDocSuccessDeleted) ||
+		gocbcore.IsErrorStatus(err, gocbcore.StatusSubDocMultiPathFailureDeleted)
+}
+
 // MutateInSpec provides a way to create MutateInOps.
 type MutateInSpec struct {
 }
@@ -248,15 +337,21 @@ type MutateInOp struct {
 type MutateInOptions struct {
 	Timeout         time.Duration
 	Context         context.Context
-	Expiration      uint32
+	Expiration      Expiry
 	Cas             Cas
 	PersistTo       uint
 	ReplicateTo     uint
 	DurabilityLevel DurabilityLevel
 	UpsertDocument  bool
 	Serializer      JSONSerializer
-	// Internal: This should never be used and is not supported.
+	// AccessDeleted allows the mutations to be performed against a deleted document (tombstone), only xattrs can
+	// be mutated on tombstones and the document remains deleted.
 	AccessDeleted bool
+	// CreateAsDeleted causes the document to be created as a tombstone, allowing xattrs to be written without
+	// creating a live document. This must be used alongside UpsertDocument and requires server support.
+	CreateAsDeleted bool
+
+	reviveDocument bool
 }
 
 func (c *Collection) encodeMultiArray(in interface{}, serializer JSONSerializer) ([]byte, error) {
@@ -674,25 +769,32 @@ func (c *Collection) MutateIn(key string, ops []MutateInOp, opts *MutateInOption
 		return nil, err
 	}
 
-	res, err := c.mutate(ctx, key, ops, *opts)
+	durableOpts := *opts
+	durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo =
+		c.fallbackDurability(opts.DurabilityLevel, opts.PersistTo, opts.ReplicateTo)
+
+	res, err := c.mutate(ctx, key, ops, durableOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.PersistTo == 0 && opts.ReplicateTo == 0 {
+	res.durabilityMode = durabilityModeFor(durableOpts.DurabilityLevel, durableOpts.PersistTo, durableOpts.ReplicateTo)
+	if durableOpts.PersistTo == 0 && durableOpts.ReplicateTo == 0 {
 		return res, nil
 	}
-	return res, c.durability(durabilitySettings{
+	durabilityRes, err := c.durability(durabilitySettings{
 		ctx:            opts.Context,
 		key:            key,
 		cas:            res.Cas(),
 		mt:             res.MutationToken(),
-		replicaTo:      opts.ReplicateTo,
-		persistTo:      opts.PersistTo,
+		replicaTo:      durableOpts.ReplicateTo,
+		persistTo:      durableOpts.PersistTo,
 		forDelete:      false,
 		scopeName:      c.scopeName(),
 		collectionName: c.name(),
 	})
+	res.durability = durabilityRes
+	return res, err
 }
 
 func (c *Collection) mutate(ctx context.Context, key string, ops []MutateInOp, opts MutateInOptions) (mutOut *MutateInResult, errOut error) {
@@ -705,14 +807,24 @@ func (c *Collection) mutate(ctx context.Context, key string, ops []MutateInOp, o
 		return nil, configurationError{"cannot use observe based durability without mutation tokens"}
 	}
 
+	if opts.CreateAsDeleted && !opts.UpsertDocument {
+		return nil, invalidArgumentsError{message: "CreateAsDeleted must be used with UpsertDocument"}
+	}
+
 	var isInsertDocument bool
 	var flags SubdocDocFlag
 	if opts.UpsertDocument {
 		flags |= SubdocDocFlagMkDoc
 	}
-	if opts.AccessDeleted {
+	if opts.AccessDeleted || opts.CreateAsDeleted || opts.reviveDocument {
 		flags |= SubdocDocFlagAccessDeleted
 	}
+	if opts.CreateAsDeleted {
+		flags |= SubdocDocFlagCreateAsDeleted
+	}
+	if opts.reviveDocument {
+		flags |= SubdocDocFlagReviveDocument
+	}
 
 	serializer := opts.Serializer
 	if serializer == nil {
@@ -750,6 +862,11 @@ func (c *Collection) mutate(ctx context.Context, key string, ops []MutateInOp, o
 		})
 	}
 
+	expiry, err := opts.Expiration.wire(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	coerced, durabilityTimeout := c.durabilityTimeout(ctx, opts.DurabilityLevel)
 	if coerced {
 		var cancel context.CancelFunc
@@ -763,7 +880,7 @@ func (c *Collection) mutate(ctx context.Context, key string, ops []MutateInOp, o
 		Flags:                  gocbcore.SubdocDocFlag(flags),
 		Cas:                    gocbcore.Cas(opts.Cas),
 		Ops:                    subdocs,
-		Expiry:                 opts.Expiration,
+		Expiry:                 expiry,
 		CollectionName:         c.name(),
 		ScopeName:              c.scopeName(),
 		DurabilityLevel:        gocbcore.DurabilityLevel(opts.DurabilityLevel),
@@ -803,3 +920,41 @@ func (c *Collection) mutate(ctx context.Context, key string, ops []MutateInOp, o
 
 	return
 }
+
+// ReviveOptions are the set of options available to Revive.
+type ReviveOptions struct {
+	Timeout         time.Duration
+	Context         context.Context
+	Expiration      Expiry
+	Cas             Cas
+	PersistTo       uint
+	ReplicateTo     uint
+	DurabilityLevel DurabilityLevel
+	Serializer      JSONSerializer
+}
+
+// Revive atomically turns a deleted document (tombstone) back into a live document with the value val, keeping
+// any xattrs which survived the delete. This will fail if the document is not deleted and requires server support.
+func (c *Collection) Revive(key string, val interface{}, opts *ReviveOptions) (mutOut *MutationResult, errOut error) {
+	if opts == nil {
+		opts = &ReviveOptions{}
+	}
+
+	spec := MutateInSpec{}
+	res, err := c.MutateIn(key, []MutateInOp{spec.UpsertFull(val, nil)}, &MutateInOptions{
+		Timeout:         opts.Timeout,
+		Context:         opts.Context,
+		Expiration:      opts.Expiration,
+		Cas:             opts.Cas,
+		PersistTo:       opts.PersistTo,
+		ReplicateTo:     opts.ReplicateTo,
+		DurabilityLevel: opts.DurabilityLevel,
+		Serializer:      opts.Serializer,
+		reviveDocument:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.MutationResult, nil
+}
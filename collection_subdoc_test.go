@@ -1,8 +1,13 @@
 package gocb
 
 import (
+	"fmt"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
 )
 
 func TestInsertLookupIn(t *testing.T) {
@@ -295,7 +300,7 @@ func TestInsertLookupInInsertGetFull(t *testing.T) {
 	subRes, err := globalCollection.MutateIn("lookupDocGetFull", []MutateInOp{
 		mutSpec.Insert("xattrpath", "xattrvalue", &MutateInSpecInsertOptions{IsXattr: true}),
 		mutSpec.UpsertFull(doc, nil),
-	}, &MutateInOptions{UpsertDocument: true, Expiration: 20})
+	}, &MutateInOptions{UpsertDocument: true, Expiration: ExpiryIn(20 * time.Second)})
 	if err != nil {
 		t.Fatalf("MutateIn failed, error was %v", err)
 	}
@@ -433,3 +438,290 @@ func TestMutateInLookupInMacro(t *testing.T) {
 		t.Fatalf("Expected caspath to start with 0x but was %s", caspath)
 	}
 }
+
+func TestLookupInByPath(t *testing.T) {
+	provider := &mockKvProvider{
+		cas: gocbcore.Cas(1),
+		value: []gocbcore.SubDocResult{
+			{Value: []byte(`"Jo"`)},
+			{Value: []byte(`{"city":"London"}`)},
+			{},
+			{Err: gocbcore.ErrSubDocPathNotFound},
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	spec := LookupInSpec{}
+	res, err := col.LookupIn("lookupByPath", []LookupInOp{
+		spec.Get("name", nil),
+		spec.Get("address", nil),
+		spec.Exists("email", nil),
+		spec.Get("phone", nil),
+	}, nil)
+	if err != nil {
+		t.Fatalf("Expected LookupIn to not error %v", err)
+	}
+
+	var name string
+	err = res.ContentByPath("name", &name)
+	if err != nil {
+		t.Fatalf("Expected ContentByPath to not error %v", err)
+	}
+
+	if name != "Jo" {
+		t.Fatalf("Expected name to be Jo but was %s", name)
+	}
+
+	if !res.ExistsPath("email") {
+		t.Fatalf("Expected email to exist")
+	}
+
+	if res.ExistsPath("phone") || res.ExistsPath("unknown") {
+		t.Fatalf("Expected phone and unknown to not exist")
+	}
+
+	err = res.ContentByPath("unknown", &name)
+	if err == nil {
+		t.Fatalf("Expected ContentByPath with an unknown path to error")
+	}
+
+	var doc struct {
+		Name     string            `subdoc:"name"`
+		Address  map[string]string `subdoc:"address"`
+		HasEmail bool              `subdoc:"email"`
+		Phone    string            `subdoc:"phone"`
+		Ignored  string
+	}
+	err = res.Decode(&doc)
+	if err != nil {
+		t.Fatalf("Expected Decode to not error %v", err)
+	}
+
+	if doc.Name != "Jo" || doc.Address["city"] != "London" || !doc.HasEmail || doc.Phone != "" {
+		t.Fatalf("Decoded document was not as expected: %+v", doc)
+	}
+}
+
+func TestLookupInChunked(t *testing.T) {
+	var requests uint32
+	provider := &mockKvProvider{
+		lookupInFn: func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error) {
+			atomic.AddUint32(&requests, 1)
+			if len(opts.Ops) > 16 {
+				return nil, gocbcore.ErrSubDocBadCombo
+			}
+
+			res := &gocbcore.LookupInResult{Cas: gocbcore.Cas(1)}
+			for _, op := range opts.Ops {
+				res.Ops = append(res.Ops, gocbcore.SubDocResult{Value: []byte(`"` + op.Path + `"`)})
+			}
+			return res, nil
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	spec := LookupInSpec{}
+	var ops []LookupInOp
+	for i := 0; i < 20; i++ {
+		ops = append(ops, spec.Get(fmt.Sprintf("field%d", i), nil))
+	}
+
+	res, err := col.LookupIn("lookupChunked", ops, nil)
+	if err != nil {
+		t.Fatalf("Expected LookupIn to not error %v", err)
+	}
+
+	if atomic.LoadUint32(&requests) != 2 {
+		t.Fatalf("Expected 2 requests but was %d", requests)
+	}
+
+	for i := 0; i < 20; i++ {
+		var val string
+		err = res.ContentAt(i, &val)
+		if err != nil {
+			t.Fatalf("Expected ContentAt to not error %v", err)
+		}
+
+		if val != fmt.Sprintf("field%d", i) {
+			t.Fatalf("Expected value at %d to be field%d but was %s", i, i, val)
+		}
+	}
+}
+
+func TestLookupInChunkedCasChanged(t *testing.T) {
+	var requests uint32
+	provider := &mockKvProvider{
+		lookupInFn: func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error) {
+			// The document changes between the first and second requests.
+			count := atomic.AddUint32(&requests, 1)
+			cas := gocbcore.Cas(1)
+			if count > 1 {
+				cas = gocbcore.Cas(2)
+			}
+
+			res := &gocbcore.LookupInResult{Cas: cas}
+			for range opts.Ops {
+				res.Ops = append(res.Ops, gocbcore.SubDocResult{Value: []byte(`1`)})
+			}
+			return res, nil
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	spec := LookupInSpec{}
+	var ops []LookupInOp
+	for i := 0; i < 17; i++ {
+		ops = append(ops, spec.Get(fmt.Sprintf("field%d", i), nil))
+	}
+
+	res, err := col.LookupIn("lookupChunkedCas", ops, nil)
+	if err != nil {
+		t.Fatalf("Expected LookupIn to not error %v", err)
+	}
+
+	if atomic.LoadUint32(&requests) != 4 {
+		t.Fatalf("Expected lookup to be retried once but there were %d requests", requests)
+	}
+
+	if res.Cas() != Cas(2) {
+		t.Fatalf("Expected cas to be 2 but was %d", res.Cas())
+	}
+}
+
+func TestLookupInChunkedDocumentChanging(t *testing.T) {
+	var requests uint32
+	provider := &mockKvProvider{
+		lookupInFn: func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error) {
+			// The document changes between every request.
+			res := &gocbcore.LookupInResult{Cas: gocbcore.Cas(atomic.AddUint32(&requests, 1))}
+			for range opts.Ops {
+				res.Ops = append(res.Ops, gocbcore.SubDocResult{Value: []byte(`1`)})
+			}
+			return res, nil
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	spec := LookupInSpec{}
+	var ops []LookupInOp
+	for i := 0; i < 17; i++ {
+		ops = append(ops, spec.Get(fmt.Sprintf("field%d", i), nil))
+	}
+
+	_, err := col.LookupIn("lookupChunkedChanging", ops, nil)
+	if !IsDocumentChangedError(err) {
+		t.Fatalf("Expected LookupIn to fail with document changed but was %v", err)
+	}
+
+	if atomic.LoadUint32(&requests) != 12 {
+		t.Fatalf("Expected lookup to be attempted 6 times but there were %d requests", requests)
+	}
+}
+
+func TestLookupInAccessDeleted(t *testing.T) {
+	provider := &mockKvProvider{
+		lookupInFn: func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error) {
+			if opts.Flags&gocbcore.SubdocDocFlagAccessDeleted == 0 {
+				return nil, gocbcore.ErrKeyNotFound
+			}
+
+			return &gocbcore.LookupInResult{
+				Cas: gocbcore.Cas(1),
+				Ops: []gocbcore.SubDocResult{{Value: []byte(`"rev1"`)}},
+			}, gocbcore.ErrSubDocSuccessDeleted
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	spec := LookupInSpec{}
+	ops := []LookupInOp{spec.Get("sync.rev", &LookupInSpecGetOptions{IsXattr: true})}
+	_, err := col.LookupIn("lookupInDeleted", ops, nil)
+	if !IsKeyNotFoundError(err) {
+		t.Fatalf("Expected key not found error but was %v", err)
+	}
+
+	res, err := col.LookupIn("lookupInDeleted", ops, &LookupInOptions{AccessDeleted: true})
+	if err != nil {
+		t.Fatalf("Expected LookupIn to not error %v", err)
+	}
+
+	var rev string
+	err = res.ContentByPath("sync.rev", &rev)
+	if err != nil {
+		t.Fatalf("Expected ContentByPath to not error %v", err)
+	}
+
+	if rev != "rev1" {
+		t.Fatalf("Expected rev to be rev1 but was %s", rev)
+	}
+}
+
+func TestMutateInCreateAsDeleted(t *testing.T) {
+	var flags gocbcore.SubdocDocFlag
+	provider := &mockKvProvider{
+		mutateInFn: func(opts gocbcore.MutateInOptions) (*gocbcore.MutateInResult, error) {
+			flags = opts.Flags
+			return &gocbcore.MutateInResult{
+				Cas: gocbcore.Cas(1),
+				Ops: make([]gocbcore.SubDocResult, len(opts.Ops)),
+			}, nil
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	spec := MutateInSpec{}
+	ops := []MutateInOp{spec.Upsert("sync.rev", "rev1", &MutateInSpecUpsertOptions{IsXattr: true})}
+	_, err := col.MutateIn("mutateInCreateAsDeleted", ops, &MutateInOptions{CreateAsDeleted: true})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected invalid arguments error but was %v", err)
+	}
+
+	_, err = col.MutateIn("mutateInCreateAsDeleted", ops, &MutateInOptions{
+		CreateAsDeleted: true,
+		UpsertDocument:  true,
+	})
+	if err != nil {
+		t.Fatalf("Expected MutateIn to not error %v", err)
+	}
+
+	expected := gocbcore.SubdocDocFlagMkDoc | gocbcore.SubdocDocFlagAccessDeleted | gocbcore.SubdocDocFlag(SubdocDocFlagCreateAsDeleted)
+	if flags != expected {
+		t.Fatalf("Expected flags to be %x but was %x", expected, flags)
+	}
+}
+
+func TestRevive(t *testing.T) {
+	var req gocbcore.MutateInOptions
+	provider := &mockKvProvider{
+		mutateInFn: func(opts gocbcore.MutateInOptions) (*gocbcore.MutateInResult, error) {
+			req = opts
+			return &gocbcore.MutateInResult{
+				Cas: gocbcore.Cas(2),
+				Ops: make([]gocbcore.SubDocResult, len(opts.Ops)),
+			}, nil
+		},
+	}
+	col := testGetCollection(t, provider)
+
+	res, err := col.Revive("revive", map[string]string{"name": "revived"}, &ReviveOptions{Cas: Cas(1)})
+	if err != nil {
+		t.Fatalf("Expected Revive to not error %v", err)
+	}
+
+	if res.Cas() != Cas(2) {
+		t.Fatalf("Expected cas to be 2 but was %d", res.Cas())
+	}
+
+	expected := gocbcore.SubdocDocFlagAccessDeleted | gocbcore.SubdocDocFlag(SubdocDocFlagReviveDocument)
+	if req.Flags != expected {
+		t.Fatalf("Expected flags to be %x but was %x", expected, req.Flags)
+	}
+
+	if req.Cas != gocbcore.Cas(1) {
+		t.Fatalf("Expected request cas to be 1 but was %d", req.Cas)
+	}
+
+	if len(req.Ops) != 1 || req.Ops[0].Op != gocbcore.SubDocOpSetDoc || string(req.Ops[0].Value) != `{"name":"revived"}` {
+		t.Fatalf("Expected a single full document set but was %+v", req.Ops)
+	}
+}
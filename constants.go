@@ -89,6 +89,14 @@ const (
 
 	// SubdocDocFlagAccessDeleted indicates that you wish to receive soft-deleted documents.
 	SubdocDocFlagAccessDeleted = SubdocDocFlag(gocbcore.SubdocDocFlagAccessDeleted)
+
+	// SubdocDocFlagCreateAsDeleted indicates that the document should be created as a tombstone, this must be used
+	// alongside SubdocDocFlagMkDoc and SubdocDocFlagAccessDeleted.
+	SubdocDocFlagCreateAsDeleted = SubdocDocFlag(0x08)
+
+	// SubdocDocFlagReviveDocument indicates that a tombstone should be turned back into a live document, this must
+	// be used alongside SubdocDocFlagAccessDeleted.
+	SubdocDocFlagReviveDocument = SubdocDocFlag(0x10)
 )
 
 // DurabilityLevel specifies the level of synchronous replication to use.
@@ -107,6 +115,41 @@ const (
 	DurabilityLevelPersistToMajority = DurabilityLevel(3)
 )
 
+// DurabilityMode specifies the mechanism that was used to meet the durability requirements of a mutation.
+type DurabilityMode uint8
+
+const (
+	// DurabilityModeNone indicates that no durability requirements were applied.
+	DurabilityModeNone = DurabilityMode(0)
+
+	// DurabilityModeEnhanced indicates that synchronous durability, as specified by DurabilityLevel, was used.
+	DurabilityModeEnhanced = DurabilityMode(1)
+
+	// DurabilityModeObserve indicates that observe based durability, as specified by PersistTo and ReplicateTo,
+	// was used.
+	DurabilityModeObserve = DurabilityMode(2)
+)
+
+// ReplicaFallbackPolicy specifies when a Get should fall back to reading the document from a replica.
+type ReplicaFallbackPolicy uint8
+
+const (
+	// ReplicaFallbackNone indicates that a Get should never read from a replica.
+	ReplicaFallbackNone = ReplicaFallbackPolicy(0)
+
+	// ReplicaFallbackOnTimeout indicates that a Get should read from a replica if the active copy has not
+	// responded in time.
+	ReplicaFallbackOnTimeout = ReplicaFallbackPolicy(1)
+
+	// ReplicaFallbackOnNotMyVbucket indicates that a Get should read from a replica if the active copy could not
+	// be reached, such as during a not my vbucket storm whilst a rebalance or failover is in progress.
+	ReplicaFallbackOnNotMyVbucket = ReplicaFallbackPolicy(2)
+
+	// ReplicaFallbackHedged indicates that a Get should also read from a replica if the active copy has not
+	// responded in time, using whichever result is returned first.
+	ReplicaFallbackHedged = ReplicaFallbackPolicy(3)
+)
+
 // MutationMacro can be supplied to MutateIn operations to perform ExpandMacros operations.
 type MutationMacro string
 
@@ -120,3 +163,59 @@ const (
 	// MutationMacroValueCRC32c can be used to tell the server to use the value_crc32c macro.
 	MutationMacroValueCRC32c = MutationMacro("${Mutation.value_crc32c}")
 )
+
+// KvOperation specifies the type of a key-value operation seen by a KvInterceptor.
+type KvOperation string
+
+const (
+	// KvOperationGet indicates a Get operation.
+	KvOperationGet = KvOperation("get")
+
+	// KvOperationGetAndTouch indicates a GetAndTouch operation.
+	KvOperationGetAndTouch = KvOperation("get_and_touch")
+
+	// KvOperationGetAndLock indicates a GetAndLock operation.
+	KvOperationGetAndLock = KvOperation("get_and_lock")
+
+	// KvOperationGetAnyReplica indicates a read of whichever copy of a document responds first.
+	KvOperationGetAnyReplica = KvOperation("get_any_replica")
+
+	// KvOperationGetReplica indicates a read of a specific copy of a document.
+	KvOperationGetReplica = KvOperation("get_replica")
+
+	// KvOperationTouch indicates a Touch operation.
+	KvOperationTouch = KvOperation("touch")
+
+	// KvOperationUnlock indicates an Unlock operation.
+	KvOperationUnlock = KvOperation("unlock")
+
+	// KvOperationRemove indicates a Remove operation.
+	KvOperationRemove = KvOperation("remove")
+
+	// KvOperationInsert indicates an Insert operation.
+	KvOperationInsert = KvOperation("insert")
+
+	// KvOperationUpsert indicates an Upsert operation.
+	KvOperationUpsert = KvOperation("upsert")
+
+	// KvOperationReplace indicates a Replace operation.
+	KvOperationReplace = KvOperation("replace")
+
+	// KvOperationAppend indicates a binary Append operation.
+	KvOperationAppend = KvOperation("append")
+
+	// KvOperationPrepend indicates a binary Prepend operation.
+	KvOperationPrepend = KvOperation("prepend")
+
+	// KvOperationIncrement indicates a binary Increment operation.
+	KvOperationIncrement = KvOperation("increment")
+
+	// KvOperationDecrement indicates a binary Decrement operation.
+	KvOperationDecrement = KvOperation("decrement")
+
+	// KvOperationLookupIn indicates a LookupIn operation.
+	KvOperationLookupIn = KvOperation("lookup_in")
+
+	// KvOperationMutateIn indicates a MutateIn operation.
+	KvOperationMutateIn = KvOperation("mutate_in")
+)
@@ -2,6 +2,7 @@ package gocb
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	gocbcore "github.com/couchbase/gocbcore/v8"
@@ -126,6 +127,109 @@ func (err durabilityError) DurabilityError() bool {
 	return true
 }
 
+// DocumentChangedError occurs when a LookupIn which needed several requests could not read a single version of the
+// document, because the document kept changing between the requests.
+type DocumentChangedError interface {
+	DocumentChanged() bool
+}
+
+type documentChangedError struct {
+	key      string
+	attempts uint
+}
+
+func (err documentChangedError) Error() string {
+	return fmt.Sprintf("document %s changed during each of %d attempts to read it across multiple lookups", err.key,
+		err.attempts)
+}
+
+func (err documentChangedError) DocumentChanged() bool {
+	return true
+}
+
+// ReplicaConsistencyError occurs when no copy of a document caught up with the mutations that a replica read was
+// required to be consistent with in time. It is also a timeout error.
+type ReplicaConsistencyError interface {
+	ReplicaConsistencyError() bool
+	// BehindReplicas returns the copies which had not seen the mutations, where 0 is the active copy. Each maps
+	// to the error which occurred observing or reading that copy, or nil if it was behind.
+	BehindReplicas() map[int]error
+}
+
+type replicaConsistencyError struct {
+	key    string
+	seqNo  uint64
+	behind map[int]error
+}
+
+func newReplicaConsistencyError(key string, consistency *replicaConsistency, behind map[int]error) error {
+	copied := make(map[int]error, len(behind))
+	for replicaIdx, err := range behind {
+		copied[replicaIdx] = err
+	}
+
+	return replicaConsistencyError{key: key, seqNo: uint64(consistency.seqNo), behind: copied}
+}
+
+func (err replicaConsistencyError) Error() string {
+	replicaIdxs := make([]int, 0, len(err.behind))
+	for replicaIdx := range err.behind {
+		replicaIdxs = append(replicaIdxs, replicaIdx)
+	}
+	sort.Ints(replicaIdxs)
+
+	copies := make([]string, len(replicaIdxs))
+	for i, replicaIdx := range replicaIdxs {
+		name := fmt.Sprintf("replica %d", replicaIdx)
+		if replicaIdx == 0 {
+			name = "active"
+		}
+
+		if cause := err.behind[replicaIdx]; cause != nil {
+			copies[i] = fmt.Sprintf("%s (%s)", name, cause)
+		} else {
+			copies[i] = name
+		}
+	}
+
+	return fmt.Sprintf("no copy of %s reached sequence number %d in time, copies behind: %s", err.key, err.seqNo,
+		strings.Join(copies, ", "))
+}
+
+func (err replicaConsistencyError) ReplicaConsistencyError() bool {
+	return true
+}
+
+func (err replicaConsistencyError) BehindReplicas() map[int]error {
+	return err.behind
+}
+
+func (err replicaConsistencyError) Timeout() bool {
+	return true
+}
+
+// DurabilityAmbiguousError occurs when the durability requirements of a mutation could not be confirmed. The
+// mutation has been applied on the active node but may or may not survive a failover.
+type DurabilityAmbiguousError interface {
+	DurabilityAmbiguous() bool
+}
+
+type durabilityAmbiguousError struct {
+	reason string
+}
+
+func (err durabilityAmbiguousError) Error() string {
+	return err.reason
+}
+
+func (err durabilityAmbiguousError) DurabilityError() bool {
+	return true
+}
+
+func (err durabilityAmbiguousError) DurabilityAmbiguous() bool {
+	return true
+}
+
 // TimeoutError occurs when an operation times out.
 type TimeoutError interface {
 	Timeout() bool
@@ -512,6 +616,28 @@ func IsDurabilityError(err error) bool {
 	}
 }
 
+// IsDocumentChangedError verifies whether or not the cause for an error is that a document kept changing whilst it
+// was read across multiple lookups.
+func IsDocumentChangedError(err error) bool {
+	switch errType := errors.Cause(err).(type) {
+	case DocumentChangedError:
+		return errType.DocumentChanged()
+	default:
+		return false
+	}
+}
+
+// IsReplicaConsistencyError verifies whether or not the cause for an error is that no copy of a document caught up
+// with the mutations that a replica read was required to be consistent with.
+func IsReplicaConsistencyError(err error) bool {
+	switch errType := errors.Cause(err).(type) {
+	case ReplicaConsistencyError:
+		return errType.ReplicaConsistencyError()
+	default:
+		return false
+	}
+}
+
 // IsDurabilityLevelInvalidError verifies whether or not the cause for an error is because
 // the requested durability level is invalid.
 func IsDurabilityLevelInvalidError(err error) bool {
@@ -557,6 +683,18 @@ func IsSyncWriteAmbiguousError(err error) bool {
 	return false
 }
 
+// IsDurabilityAmbiguousError verifies whether or not the cause for an error is because the durability
+// requirements of a mutation could not be confirmed, either by the server for synchronous durability or
+// by observe based durability when AmbiguousDurabilityErrors is enabled.
+func IsDurabilityAmbiguousError(err error) bool {
+	switch errType := errors.Cause(err).(type) {
+	case DurabilityAmbiguousError:
+		return errType.DurabilityAmbiguous()
+	default:
+		return IsSyncWriteAmbiguousError(err)
+	}
+}
+
 // IsNoReplicasError verifies whether or not the cause for an error is because of an
 // the client could not locate a replica within the cluster map or replica read. The Bucket may not be configured
 // to have replicas, which should be checked to ensure replica reads.
@@ -656,6 +794,115 @@ func IsQueryIndexNotFoundError(err error) bool {
 	}
 }
 
+// IsPlanningFailure verifies that a N1QL query failed because the server could not plan the statement, such as when
+// there is no index which can be used for it.
+func IsPlanningFailure(err error) bool {
+	return queryErrorHas(err, QueryErrorEntry.planningFailure)
+}
+
+// IsIndexFailure verifies that a N1QL query failed because of an error in the indexer or the datastore, such as an
+// index not being found.
+func IsIndexFailure(err error) bool {
+	return queryErrorHas(err, QueryErrorEntry.indexFailure)
+}
+
+// IsPreparedStatementFailure verifies that a N1QL query failed because its prepared statement could not be found
+// or was no longer valid.
+func IsPreparedStatementFailure(err error) bool {
+	return queryErrorHas(err, QueryErrorEntry.preparedStatementFailure)
+}
+
+// IsDMLCasMismatch verifies that a N1QL DML statement failed because a document was modified concurrently.
+func IsDMLCasMismatch(err error) bool {
+	return queryErrorHas(err, QueryErrorEntry.dmlCasMismatch)
+}
+
+func queryErrorHas(err error, fn func(QueryErrorEntry) bool) bool {
+	qErr, ok := errors.Cause(err).(QueryError)
+	if !ok {
+		return false
+	}
+
+	for _, entry := range qErr.Errors() {
+		if fn(entry) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAnalyticsCompilationFailure verifies that an analytics query failed because the server could not compile the
+// statement.
+func IsAnalyticsCompilationFailure(err error) bool {
+	return analyticsErrorHas(err, func(entry AnalyticsErrorEntry) bool {
+		return entry.Code >= 24000 && entry.Code < 25000
+	})
+}
+
+// IsAnalyticsJobQueueFull verifies that an analytics query was rejected because the server has too many queued
+// requests.
+func IsAnalyticsJobQueueFull(err error) bool {
+	return analyticsErrorHas(err, func(entry AnalyticsErrorEntry) bool {
+		return entry.Code == 23007
+	})
+}
+
+func analyticsErrorHas(err error, fn func(AnalyticsErrorEntry) bool) bool {
+	aErr, ok := errors.Cause(err).(AnalyticsQueryError)
+	if !ok {
+		return false
+	}
+
+	for _, entry := range aErr.Errors() {
+		if fn(entry) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsSearchInvalidQuery verifies that a search query failed because the server rejected the query as malformed.
+func IsSearchInvalidQuery(err error) bool {
+	return searchErrorHas(err, SearchErrorInvalidQuery)
+}
+
+// IsSearchIndexNotFound verifies that a search query failed because the index does not exist.
+func IsSearchIndexNotFound(err error) bool {
+	return searchErrorHas(err, SearchErrorIndexNotFound)
+}
+
+// IsSearchIndexNotReady verifies that a search query failed because the index is not yet ready to be queried.
+func IsSearchIndexNotReady(err error) bool {
+	return searchErrorHas(err, SearchErrorIndexNotReady)
+}
+
+// IsSearchTooManyRequests verifies that a search query was rejected because the server is under too much load.
+func IsSearchTooManyRequests(err error) bool {
+	return searchErrorHas(err, SearchErrorTooManyRequests)
+}
+
+// IsSearchPartitionFailure verifies that one or more partitions of the index failed to answer a search query.
+func IsSearchPartitionFailure(err error) bool {
+	return searchErrorHas(err, SearchErrorPartitionFailure)
+}
+
+func searchErrorHas(err error, code SearchErrorCode) bool {
+	sErr, ok := errors.Cause(err).(SearchErrors)
+	if !ok {
+		return false
+	}
+
+	for _, entry := range sErr.Errors() {
+		if entry.Code() == code {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsAnalyticsIndexAlreadyExistsError verifies that an analytics index already exists.
 func IsAnalyticsIndexAlreadyExistsError(err error) bool {
 	switch errType := errors.Cause(err).(type) {
@@ -897,11 +1144,23 @@ func (e viewMultiError) Errors() []ViewQueryError {
 	return e.errors
 }
 
+// AnalyticsErrorEntry is a single error returned by Couchbase Server during Analytics query execution.
+type AnalyticsErrorEntry struct {
+	Code    uint32 `json:"code"`
+	Message string `json:"msg"`
+	// Retry is set when the server has indicated that the request can be retried.
+	Retry bool `json:"retriable,omitempty"`
+}
+
 // AnalyticsQueryError occurs for errors created by Couchbase Server during Analytics query execution.
 type AnalyticsQueryError interface {
 	error
+	// Code and Message return the details of the first error returned by the server.
 	Code() uint32
 	Message() string
+	// Errors returns every error returned by the server.
+	Errors() []AnalyticsErrorEntry
+	Statement() string
 	HTTPStatus() int
 	Endpoint() string
 	ContextID() string
@@ -910,13 +1169,31 @@ type AnalyticsQueryError interface {
 type analyticsQueryError struct {
 	ErrorCode    uint32 `json:"code"`
 	ErrorMessage string `json:"msg"`
+	entries      []AnalyticsErrorEntry
+	statement    string
 	httpStatus   int
 	endpoint     string
 	contextID    string
 }
 
+func newAnalyticsQueryError(entries []AnalyticsErrorEntry) analyticsQueryError {
+	return analyticsQueryError{
+		ErrorCode:    entries[0].Code,
+		ErrorMessage: entries[0].Message,
+		entries:      entries,
+	}
+}
+
 func (e analyticsQueryError) Error() string {
-	return fmt.Sprintf("[%d] %s", e.ErrorCode, e.ErrorMessage)
+	if len(e.entries) <= 1 {
+		return fmt.Sprintf("[%d] %s", e.ErrorCode, e.ErrorMessage)
+	}
+
+	var errs []string
+	for _, entry := range e.entries {
+		errs = append(errs, fmt.Sprintf("[%d] %s", entry.Code, entry.Message))
+	}
+	return strings.Join(errs, ", ")
 }
 
 // Code returns the error code for this error.
@@ -929,12 +1206,28 @@ func (e analyticsQueryError) Message() string {
 	return e.ErrorMessage
 }
 
+// Errors returns every error returned by the server.
+func (e analyticsQueryError) Errors() []AnalyticsErrorEntry {
+	if e.entries == nil {
+		return []AnalyticsErrorEntry{{Code: e.ErrorCode, Message: e.ErrorMessage}}
+	}
+	return e.entries
+}
+
+// Statement returns the statement which was being executed.
+func (e analyticsQueryError) Statement() string {
+	return e.statement
+}
+
 func (e analyticsQueryError) retryable() bool {
-	if e.Code() == 21002 || e.Code() == 23000 || e.Code() == 23003 || e.Code() == 23007 {
-		return true
+	// Every error must be retryable, otherwise retrying would just fail again.
+	for _, entry := range e.Errors() {
+		if !entry.Retry && entry.Code != 21002 && entry.Code != 23000 && entry.Code != 23003 && entry.Code != 23007 {
+			return false
+		}
 	}
 
-	return false
+	return true
 }
 
 // Timeout indicates whether or not this error is a timeout.
@@ -960,11 +1253,55 @@ func (e analyticsQueryError) ContextID() string {
 	return e.contextID
 }
 
+// QueryErrorEntry is a single error returned by Couchbase Server during N1QL query execution.
+type QueryErrorEntry struct {
+	Code    uint32 `json:"code"`
+	Message string `json:"msg"`
+	// Reason contains any further details which the server provided about the error.
+	Reason map[string]interface{} `json:"reason,omitempty"`
+	// Retry is set when the server has indicated that the request can be retried.
+	Retry bool `json:"retry,omitempty"`
+}
+
+func (e QueryErrorEntry) preparedStatementFailure() bool {
+	switch e.Code {
+	case 4040, 4050, 4060, 4070, 4080, 4090:
+		return true
+	}
+	return false
+}
+
+func (e QueryErrorEntry) planningFailure() bool {
+	return e.Code >= 4000 && e.Code < 5000 && !e.preparedStatementFailure()
+}
+
+func (e QueryErrorEntry) dmlCasMismatch() bool {
+	if e.Code != 12009 {
+		return false
+	}
+
+	if code, ok := e.Reason["code"].(float64); ok && code == 12033 {
+		return true
+	}
+	return strings.Contains(strings.ToLower(e.Message), "cas mismatch")
+}
+
+func (e QueryErrorEntry) indexFailure() bool {
+	if e.Code >= 12000 && e.Code < 13000 {
+		return e.Code != 12009
+	}
+	return e.Code >= 14000 && e.Code < 15000
+}
+
 // QueryError occurs for errors created by Couchbase Server during N1ql query execution.
 type QueryError interface {
 	error
+	// Code and Message return the details of the first error returned by the server.
 	Code() uint32
 	Message() string
+	// Errors returns every error returned by the server.
+	Errors() []QueryErrorEntry
+	Statement() string
 	HTTPStatus() int
 	Endpoint() string
 	ContextID() string
@@ -973,14 +1310,32 @@ type QueryError interface {
 type queryError struct {
 	ErrorCode             uint32 `json:"code"`
 	ErrorMessage          string `json:"msg"`
+	entries               []QueryErrorEntry
+	statement             string
 	httpStatus            int
 	endpoint              string
 	contextID             string
 	enhancedStmtSupported bool
 }
 
+func newQueryError(entries []QueryErrorEntry) queryError {
+	return queryError{
+		ErrorCode:    entries[0].Code,
+		ErrorMessage: entries[0].Message,
+		entries:      entries,
+	}
+}
+
 func (e queryError) Error() string {
-	return fmt.Sprintf("[%d] %s", e.ErrorCode, e.ErrorMessage)
+	if len(e.entries) <= 1 {
+		return fmt.Sprintf("[%d] %s", e.ErrorCode, e.ErrorMessage)
+	}
+
+	var errs []string
+	for _, entry := range e.entries {
+		errs = append(errs, fmt.Sprintf("[%d] %s", entry.Code, entry.Message))
+	}
+	return strings.Join(errs, ", ")
 }
 
 // Code returns the error code for this error.
@@ -993,16 +1348,45 @@ func (e queryError) Message() string {
 	return e.ErrorMessage
 }
 
+// Errors returns every error returned by the server.
+func (e queryError) Errors() []QueryErrorEntry {
+	if e.entries == nil {
+		return []QueryErrorEntry{{Code: e.ErrorCode, Message: e.ErrorMessage}}
+	}
+	return e.entries
+}
+
+// Statement returns the statement which was being executed.
+func (e queryError) Statement() string {
+	return e.statement
+}
+
 func (e queryError) retryable() bool {
-	if e.enhancedStmtSupported {
-		if e.ErrorCode == 4040 {
-			return true
+	// Every error must be retryable, otherwise retrying would just fail again.
+	for _, entry := range e.Errors() {
+		if !e.entryRetryable(entry) {
+			return false
 		}
+	}
 
-		return false
+	return true
+}
+
+func (e queryError) entryRetryable(entry QueryErrorEntry) bool {
+	if entry.Retry {
+		return true
 	}
-	if e.ErrorCode == 4050 || e.ErrorCode == 4070 || e.ErrorCode == 5000 {
+
+	switch entry.Code {
+	case 4040:
+		// The prepared statement no longer exists on the node, it needs preparing again.
 		return true
+	case 4050, 4070:
+		// The encoded plan is no longer valid, enhanced prepared statements do not use encoded plans.
+		return !e.enhancedStmtSupported
+	case 5000:
+		// An index used by the plan has been dropped and recreated, so the plan needs preparing again.
+		return !e.enhancedStmtSupported && strings.Contains(entry.Message, "queryport.indexNotFound")
 	}
 
 	return false
@@ -1031,17 +1415,83 @@ func (e queryError) ContextID() string {
 	return e.contextID
 }
 
+// withQueryStatement sets the statement of err if it is a query error which does not already have one.
+func withQueryStatement(err error, statement string) error {
+	if qErr, ok := err.(queryError); ok && qErr.statement == "" {
+		qErr.statement = statement
+		return qErr
+	}
+
+	return err
+}
+
+// SearchErrorCode classifies an error returned by Couchbase Server during Search query execution. The search
+// service does not return error codes, so they are derived from the HTTP status and message of each error.
+type SearchErrorCode uint32
+
+const (
+	// SearchErrorUnknown indicates that the error could not be classified.
+	SearchErrorUnknown = SearchErrorCode(iota)
+	// SearchErrorInvalidQuery indicates that the server rejected the query as malformed.
+	SearchErrorInvalidQuery
+	// SearchErrorIndexNotFound indicates that the index being queried does not exist.
+	SearchErrorIndexNotFound
+	// SearchErrorIndexNotReady indicates that the index is not yet ready to be queried.
+	SearchErrorIndexNotReady
+	// SearchErrorTooManyRequests indicates that the server is rejecting requests due to load.
+	SearchErrorTooManyRequests
+	// SearchErrorConsistencyTimeout indicates that the requested consistency could not be met in time.
+	SearchErrorConsistencyTimeout
+	// SearchErrorPartitionFailure indicates that a single partition of the index failed to answer the query, the
+	// results of the other partitions may still have been returned.
+	SearchErrorPartitionFailure
+)
+
 // SearchError occurs for errors created by Couchbase Server during Search query execution.
 type SearchError interface {
 	error
 	Message() string
+	Code() SearchErrorCode
+	// Partition returns the index partition which failed, empty if the error applies to the whole query.
+	Partition() string
 }
 
 type searchError struct {
-	message string
+	code      SearchErrorCode
+	partition string
+	message   string
+}
+
+// newSearchError classifies the message of an error returned with httpStatus for partition.
+func newSearchError(httpStatus int, partition, message string) searchError {
+	e := searchError{
+		partition: partition,
+		message:   message,
+	}
+
+	lowerMessage := strings.ToLower(message)
+	switch {
+	case strings.Contains(lowerMessage, "index not found"):
+		e.code = SearchErrorIndexNotFound
+	case httpStatus == 419:
+		e.code = SearchErrorIndexNotReady
+	case httpStatus == 429:
+		e.code = SearchErrorTooManyRequests
+	case httpStatus == 401:
+		e.code = SearchErrorConsistencyTimeout
+	case partition != "":
+		e.code = SearchErrorPartitionFailure
+	case httpStatus == 400:
+		e.code = SearchErrorInvalidQuery
+	}
+
+	return e
 }
 
 func (e searchError) Error() string {
+	if e.partition != "" {
+		return fmt.Sprintf("%s-%s", e.partition, e.message)
+	}
 	return e.message
 }
 
@@ -1050,11 +1500,22 @@ func (e searchError) Message() string {
 	return e.message
 }
 
+// Code returns the classification of this error.
+func (e searchError) Code() SearchErrorCode {
+	return e.code
+}
+
+// Partition returns the index partition which failed, empty if the error applies to the whole query.
+func (e searchError) Partition() string {
+	return e.partition
+}
+
 // SearchErrors is a collection of one or more SearchError that occurs for errors created by Couchbase Server
 // during Search query execution.
 type SearchErrors interface {
 	error
 	Errors() []SearchError
+	IndexName() string
 	HTTPStatus() int
 	Endpoint() string
 	ContextID() string
@@ -1062,6 +1523,7 @@ type SearchErrors interface {
 
 type searchMultiError struct {
 	errors     []SearchError
+	indexName  string
 	httpStatus int
 	endpoint   string
 	contextID  string
@@ -1075,6 +1537,11 @@ func (e searchMultiError) Error() string {
 	return strings.Join(errs, ", ")
 }
 
+// IndexName returns the name of the index which was being queried.
+func (e searchMultiError) IndexName() string {
+	return e.indexName
+}
+
 // HTTPStatus returns the HTTP status code for the operation.
 func (e searchMultiError) HTTPStatus() int {
 	return e.httpStatus
@@ -1095,9 +1562,19 @@ func (e searchMultiError) Errors() []SearchError {
 	return e.errors
 }
 
-// PartialResults indicates whether or not the operation also yielded results.
 func (e searchMultiError) retryable() bool {
-	return e.httpStatus == 419
+	if len(e.errors) == 0 {
+		return false
+	}
+
+	// Every error must be retryable, otherwise retrying would just fail again.
+	for _, err := range e.errors {
+		if code := err.Code(); code != SearchErrorIndexNotReady && code != SearchErrorTooManyRequests {
+			return false
+		}
+	}
+
+	return true
 }
 
 // ConfigurationError occurs when the client is configured incorrectly.
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
 )
 
 func TestIsCasMismatchError(t *testing.T) {
@@ -75,3 +76,86 @@ func TestKVIsRetryable(t *testing.T) {
 		t.Fatalf("StatusTooBig error should not have been retryable")
 	}
 }
+
+func TestQueryErrorClassification(t *testing.T) {
+	type testCase struct {
+		entry       QueryErrorEntry
+		planning    bool
+		index       bool
+		prepared    bool
+		casMismatch bool
+	}
+
+	testCases := []testCase{
+		{entry: QueryErrorEntry{Code: 4000, Message: "No index available on keyspace default"}, planning: true},
+		{entry: QueryErrorEntry{Code: 4040, Message: "No such prepared statement"}, prepared: true},
+		{entry: QueryErrorEntry{Code: 4070, Message: "Unable to decode prepared statement"}, prepared: true},
+		{entry: QueryErrorEntry{Code: 12004, Message: "Primary index #primary not online"}, index: true},
+		{entry: QueryErrorEntry{Code: 14000, Message: "GSI index does not exist"}, index: true},
+		{entry: QueryErrorEntry{Code: 12009, Message: "DML Error, possible causes include CAS mismatch"}, casMismatch: true},
+		{entry: QueryErrorEntry{Code: 12009, Message: "DML Error", Reason: map[string]interface{}{"code": float64(12033)}},
+			casMismatch: true},
+		{entry: QueryErrorEntry{Code: 12009, Message: "DML Error, key already exists"}},
+		{entry: QueryErrorEntry{Code: 3000, Message: "syntax error"}},
+	}
+
+	for _, tc := range testCases {
+		err := errors.Wrap(newQueryError([]QueryErrorEntry{{Code: 5010, Message: "other"}, tc.entry}), "wrapped")
+
+		if IsPlanningFailure(err) != tc.planning {
+			t.Errorf("Expected IsPlanningFailure to be %t for %d", tc.planning, tc.entry.Code)
+		}
+		if IsIndexFailure(err) != tc.index {
+			t.Errorf("Expected IsIndexFailure to be %t for %d", tc.index, tc.entry.Code)
+		}
+		if IsPreparedStatementFailure(err) != tc.prepared {
+			t.Errorf("Expected IsPreparedStatementFailure to be %t for %d", tc.prepared, tc.entry.Code)
+		}
+		if IsDMLCasMismatch(err) != tc.casMismatch {
+			t.Errorf("Expected IsDMLCasMismatch to be %t for %d", tc.casMismatch, tc.entry.Code)
+		}
+	}
+
+	if IsPlanningFailure(timeoutError{}) || IsIndexFailure(nil) {
+		t.Fatalf("Expected errors which are not query errors to not be classified")
+	}
+}
+
+func TestQueryErrorIsRetryable(t *testing.T) {
+	retryable := newQueryError([]QueryErrorEntry{{Code: 1234, Message: "try again", Retry: true}, {Code: 4040}})
+	if !IsRetryableError(retryable) {
+		t.Fatalf("Expected errors which the server marked as retryable to be retryable")
+	}
+
+	notRetryable := newQueryError([]QueryErrorEntry{{Code: 4040}, {Code: 12009, Message: "CAS mismatch"}})
+	if IsRetryableError(notRetryable) {
+		t.Fatalf("Expected errors to not be retryable unless every error is")
+	}
+
+	internal := newQueryError([]QueryErrorEntry{{Code: 5000, Message: "panic"}})
+	if IsRetryableError(internal) {
+		t.Fatalf("Expected internal errors to not be retryable")
+	}
+
+	indexNotFound := newQueryError([]QueryErrorEntry{{Code: 5000, Message: "queryport.indexNotFound"}})
+	if !IsRetryableError(indexNotFound) {
+		t.Fatalf("Expected plans using dropped indexes to be retryable")
+	}
+
+	indexNotFound.enhancedStmtSupported = true
+	if IsRetryableError(indexNotFound) {
+		t.Fatalf("Expected plans using dropped indexes to not be retryable with enhanced prepared statements")
+	}
+}
+
+func TestAnalyticsQueryErrorClassification(t *testing.T) {
+	err := newAnalyticsQueryError([]AnalyticsErrorEntry{{Code: 24045, Message: "Cannot find dataset"}})
+	if !IsAnalyticsCompilationFailure(err) || IsAnalyticsJobQueueFull(err) || IsRetryableError(err) {
+		t.Fatalf("Expected error to be a compilation failure which cannot be retried")
+	}
+
+	err = newAnalyticsQueryError([]AnalyticsErrorEntry{{Code: 23007, Message: "Job queue is full"}})
+	if IsAnalyticsCompilationFailure(err) || !IsAnalyticsJobQueueFull(err) || !IsRetryableError(err) {
+		t.Fatalf("Expected error to be a full job queue which can be retried")
+	}
+}
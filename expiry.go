@@ -0,0 +1,102 @@
+package gocb
+
+import (
+	"math"
+	"time"
+)
+
+// relativeExpiryLimit is the longest expiry which the server treats as relative to the current time, any larger
+// value is treated as an absolute Unix timestamp.
+const relativeExpiryLimit = 30 * 24 * time.Hour
+
+// Expiry specifies when a document expires, either after a length of time or at an absolute time.
+// The zero value indicates that the document never expires.
+type Expiry struct {
+	duration time.Duration
+	at       time.Time
+}
+
+// ExpiryIn returns an Expiry which causes a document to expire after d has passed from when the operation is
+// performed. Durations longer than 30 days are converted to absolute times before being sent to the server.
+func ExpiryIn(d time.Duration) Expiry {
+	return Expiry{duration: d}
+}
+
+// ExpiryAt returns an Expiry which causes a document to expire at t.
+func ExpiryAt(t time.Time) Expiry {
+	return Expiry{at: t}
+}
+
+// IsZero returns whether or not this Expiry indicates that the document never expires.
+func (e Expiry) IsZero() bool {
+	return e.duration == 0 && e.at.IsZero()
+}
+
+// Duration returns the relative expiry, 0 if this is an absolute or zero Expiry.
+func (e Expiry) Duration() time.Duration {
+	return e.duration
+}
+
+// Time returns the absolute expiry, the zero time if this is a relative or zero Expiry.
+func (e Expiry) Time() time.Time {
+	return e.at
+}
+
+// wire converts the expiry into the format expected by the server, relative to now.
+func (e Expiry) wire(now time.Time) (uint32, error) {
+	if e.IsZero() {
+		return 0, nil
+	}
+
+	if e.at.IsZero() {
+		if e.duration < 0 {
+			return 0, invalidArgumentsError{message: "expiry duration cannot be negative"}
+		}
+
+		if e.duration <= relativeExpiryLimit {
+			// Sub-second durations are rounded up as 0 would mean that the document never expires.
+			return uint32((e.duration + time.Second - 1) / time.Second), nil
+		}
+
+		return absoluteExpiry(now.Add(e.duration))
+	}
+
+	if !e.at.After(now) {
+		return 0, invalidArgumentsError{message: "expiry time must be in the future"}
+	}
+
+	return absoluteExpiry(e.at)
+}
+
+func absoluteExpiry(t time.Time) (uint32, error) {
+	// Absolute times which fall within the relative range would be misinterpreted by the server.
+	unix := t.Unix()
+	if unix <= int64(relativeExpiryLimit/time.Second) || unix > math.MaxUint32 {
+		return 0, invalidArgumentsError{message: "expiry time is out of range"}
+	}
+
+	return uint32(unix), nil
+}
+
+// expiryFromWire converts an absolute expiry returned by the server into a time, the zero time if the document
+// never expires.
+func expiryFromWire(expiry uint32) time.Time {
+	if expiry == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(expiry), 0)
+}
+
+// lockTimeToWire converts a lock duration into the number of seconds expected by the server.
+func lockTimeToWire(lockTime time.Duration) (uint32, error) {
+	if lockTime < 0 {
+		return 0, invalidArgumentsError{message: "lock time cannot be negative"}
+	}
+
+	if lockTime > relativeExpiryLimit {
+		return 0, invalidArgumentsError{message: "lock time is out of range"}
+	}
+
+	return uint32((lockTime + time.Second - 1) / time.Second), nil
+}
@@ -0,0 +1,85 @@
+package gocb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryWire(t *testing.T) {
+	now := time.Unix(1571057032, 0)
+
+	tests := []struct {
+		name     string
+		expiry   Expiry
+		expected uint32
+	}{
+		{name: "zero", expiry: Expiry{}, expected: 0},
+		{name: "relative", expiry: ExpiryIn(90 * time.Second), expected: 90},
+		{name: "sub-second", expiry: ExpiryIn(10 * time.Millisecond), expected: 1},
+		{name: "thirty days", expiry: ExpiryIn(30 * 24 * time.Hour), expected: 30 * 24 * 3600},
+		{name: "ninety days", expiry: ExpiryIn(90 * 24 * time.Hour), expected: 1571057032 + 90*24*3600},
+		{name: "absolute", expiry: ExpiryAt(now.Add(time.Hour)), expected: 1571057032 + 3600},
+	}
+
+	for _, test := range tests {
+		wire, err := test.expiry.wire(now)
+		if err != nil {
+			t.Fatalf("Expected %s expiry to not error %v", test.name, err)
+		}
+
+		if wire != test.expected {
+			t.Fatalf("Expected %s expiry to be %d but was %d", test.name, test.expected, wire)
+		}
+	}
+}
+
+func TestExpiryWireInvalid(t *testing.T) {
+	now := time.Unix(1571057032, 0)
+
+	tests := []struct {
+		name   string
+		expiry Expiry
+		now    time.Time
+	}{
+		{name: "negative", expiry: ExpiryIn(-time.Second), now: now},
+		{name: "past", expiry: ExpiryAt(now.Add(-time.Second)), now: now},
+		{name: "relative range", expiry: ExpiryAt(time.Unix(3600, 0)), now: time.Unix(0, 0)},
+		{name: "too large", expiry: ExpiryAt(time.Unix(1<<33, 0)), now: now},
+	}
+
+	for _, test := range tests {
+		_, err := test.expiry.wire(test.now)
+		if !IsInvalidArgumentsError(err) {
+			t.Fatalf("Expected %s expiry to be invalid but was %v", test.name, err)
+		}
+	}
+}
+
+func TestLockTimeToWire(t *testing.T) {
+	lockTime, err := lockTimeToWire(1500 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected lock time to not error %v", err)
+	}
+
+	if lockTime != 2 {
+		t.Fatalf("Expected lock time to be 2 but was %d", lockTime)
+	}
+
+	_, err = lockTimeToWire(-time.Second)
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected negative lock time to be invalid but was %v", err)
+	}
+}
+
+func TestResultExpiresIn(t *testing.T) {
+	res := Result{}
+	if !res.Expiration().IsZero() || res.ExpiresIn() != 0 {
+		t.Fatalf("Expected a result without an expiry to never expire")
+	}
+
+	res.expiration = uint32(time.Now().Add(time.Hour).Unix())
+	remaining := res.ExpiresIn()
+	if remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Fatalf("Expected the remaining time to be about an hour but was %v", remaining)
+	}
+}
@@ -1,5 +1,7 @@
 module github.com/couchbase/gocb/v2
 
+go 1.27.1
+
 require (
 	github.com/couchbase/gocbcore/v8 v8.0.0-alpha.5.0.20190822080250-9bba5ee0a3db
 	github.com/couchbaselabs/gocbconnstr v1.0.3
@@ -8,3 +10,12 @@ require (
 	github.com/google/uuid v1.1.1
 	github.com/pkg/errors v0.8.1
 )
+
+require (
+	github.com/golang/snappy v0.0.1 // indirect
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2 // indirect
+	golang.org/x/net v0.0.0-20190311183353-d8887717615a // indirect
+	golang.org/x/sync v0.0.0-20190423024810-112230192c58 // indirect
+	golang.org/x/sys v0.0.0-20190215142949-d0b11bdaac8a // indirect
+	golang.org/x/text v0.3.0 // indirect
+)
@@ -1,6 +1,7 @@
 package gocb
 
 import (
+	"crypto/sha1"
 	"fmt"
 	"log"
 	"strings"
@@ -59,6 +60,20 @@ var (
 	globalLogRedactionLevel LogRedactLevel
 )
 
+// redactUserData prepares user data, such as a document key, for inclusion in logs and reports. Partial redaction
+// wraps the data in user data tags so that it can be redacted later, full redaction also replaces the data with a
+// hash of it so that it can still be correlated.
+func redactUserData(v string) string {
+	switch globalLogRedactionLevel {
+	case RedactNone:
+		return v
+	case RedactPartial:
+		return "<ud>" + v + "</ud>"
+	default:
+		return fmt.Sprintf("<ud>%x</ud>", sha1.Sum([]byte(v)))
+	}
+}
+
 type coreLogWrapper struct {
 	wrapped gocbcore.Logger
 }
@@ -1,11 +1,15 @@
 package gocb
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/couchbase/gocbcore/v8"
+	"github.com/pkg/errors"
 )
 
 type mockClient struct {
@@ -16,6 +20,9 @@ type mockClient struct {
 	mockKvProvider          kvProvider
 	mockHTTPProvider        httpProvider
 	mockDiagnosticsProvider diagnosticsProvider
+	mockDcpProvider         dcpProvider
+	durabilityUnsupported   bool
+	kvServers               []string
 }
 
 type mockKvProvider struct {
@@ -27,18 +34,46 @@ type mockKvProvider struct {
 	datatype              uint8
 	err                   error
 	opCancellationSuccess bool
+	numReplicas           int
+	numVbuckets           int
+	numServers            int
+	observeVbCount        uint32
+	observeErr            error
+
+	// getWait and getErr only apply to GetEx, allowing reads of the active copy to behave differently to replicas.
+	getWait time.Duration
+	getErr  error
+	// replicaWait only applies to GetAnyReplicaEx, allowing the active copy to respond before a replica.
+	replicaWait time.Duration
+
+	// replicaCas and replicaErr override cas and err for GetOneReplicaEx, keyed by replica index.
+	replicaCas map[int]gocbcore.Cas
+	replicaErr map[int]error
+
+	// lookupInFn, if set, is used to respond to LookupInEx requests.
+	lookupInFn func(opts gocbcore.LookupInOptions) (*gocbcore.LookupInResult, error)
+	// mutateInFn, if set, is used to respond to MutateInEx requests.
+	mutateInFn func(opts gocbcore.MutateInOptions) (*gocbcore.MutateInResult, error)
 }
 
 type mockHTTPProvider struct {
 	doFn      func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error)
 	supportFn func(capability gocbcore.ClusterCapability) bool
+	// cancelFn handles requests cancelling abandoned queries, which are otherwise answered with an empty response.
+	cancelFn func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error)
 }
 
 type mockPendingOp struct {
 	cancelSuccess bool
+	// timer, if set, is stopped on a successful cancellation so that the callback is not invoked.
+	timer *time.Timer
 }
 
 func (mpo *mockPendingOp) Cancel() bool {
+	if mpo.cancelSuccess && mpo.timer != nil {
+		return mpo.timer.Stop()
+	}
+
 	return mpo.cancelSuccess
 }
 
@@ -89,8 +124,11 @@ func (mko *mockKvProvider) ReplaceEx(opts gocbcore.ReplaceOptions, cb gocbcore.S
 }
 
 func (mko *mockKvProvider) GetEx(opts gocbcore.GetOptions, cb gocbcore.GetExCallback) (gocbcore.PendingOp, error) {
-	time.AfterFunc(mko.opWait, func() {
-		if mko.err == nil {
+	op := &mockPendingOp{cancelSuccess: mko.opCancellationSuccess}
+	op.timer = time.AfterFunc(mko.opWait+mko.getWait, func() {
+		if mko.getErr != nil {
+			cb(nil, mko.getErr)
+		} else if mko.err == nil {
 			cb(&gocbcore.GetResult{
 				Cas:      mko.cas,
 				Flags:    mko.flags,
@@ -102,7 +140,7 @@ func (mko *mockKvProvider) GetEx(opts gocbcore.GetOptions, cb gocbcore.GetExCall
 		}
 	})
 
-	return &mockPendingOp{cancelSuccess: mko.opCancellationSuccess}, nil
+	return op, nil
 }
 
 func (mko *mockKvProvider) GetAndTouchEx(opts gocbcore.GetAndTouchOptions, cb gocbcore.GetAndTouchExCallback) (gocbcore.PendingOp, error) {
@@ -248,7 +286,9 @@ func (mko *mockKvProvider) PrependEx(opts gocbcore.AdjoinOptions, cb gocbcore.Ad
 
 func (mko *mockKvProvider) LookupInEx(opts gocbcore.LookupInOptions, cb gocbcore.LookupInExCallback) (gocbcore.PendingOp, error) {
 	time.AfterFunc(mko.opWait, func() {
-		if mko.err == nil {
+		if mko.lookupInFn != nil {
+			cb(mko.lookupInFn(opts))
+		} else if mko.err == nil {
 			cb(&gocbcore.LookupInResult{
 				Cas: mko.cas,
 				Ops: mko.value.([]gocbcore.SubDocResult),
@@ -264,7 +304,9 @@ func (mko *mockKvProvider) LookupInEx(opts gocbcore.LookupInOptions, cb gocbcore
 
 func (mko *mockKvProvider) MutateInEx(opts gocbcore.MutateInOptions, cb gocbcore.MutateInExCallback) (gocbcore.PendingOp, error) {
 	time.AfterFunc(mko.opWait, func() {
-		if mko.err == nil {
+		if mko.mutateInFn != nil {
+			cb(mko.mutateInFn(opts))
+		} else if mko.err == nil {
 			cb(&gocbcore.MutateInResult{
 				Cas:           mko.cas,
 				Ops:           mko.value.([]gocbcore.SubDocResult),
@@ -294,9 +336,17 @@ func (mko *mockKvProvider) ObserveEx(opts gocbcore.ObserveOptions, cb gocbcore.O
 }
 
 func (mko *mockKvProvider) ObserveVbEx(opts gocbcore.ObserveVbOptions, cb gocbcore.ObserveVbExCallback) (gocbcore.PendingOp, error) {
+	atomic.AddUint32(&mko.observeVbCount, 1)
 	time.AfterFunc(mko.opWait, func() {
-		if mko.err == nil {
-			cb(&gocbcore.ObserveVbResult{}, nil)
+		if mko.observeErr != nil {
+			cb(nil, mko.observeErr)
+		} else if mko.err == nil {
+			cb(&gocbcore.ObserveVbResult{
+				VbId:         opts.VbId,
+				VbUuid:       opts.VbUuid,
+				PersistSeqNo: mko.mt.SeqNo,
+				CurrentSeqNo: mko.mt.SeqNo,
+			}, nil)
 		} else {
 			cb(nil, mko.err)
 		}
@@ -306,7 +356,8 @@ func (mko *mockKvProvider) ObserveVbEx(opts gocbcore.ObserveVbOptions, cb gocbco
 }
 
 func (mko *mockKvProvider) GetAnyReplicaEx(opts gocbcore.GetAnyReplicaOptions, cb gocbcore.GetReplicaExCallback) (gocbcore.PendingOp, error) {
-	time.AfterFunc(mko.opWait, func() {
+	op := &mockPendingOp{cancelSuccess: mko.opCancellationSuccess}
+	op.timer = time.AfterFunc(mko.opWait+mko.replicaWait, func() {
 		if mko.err == nil {
 			cb(&gocbcore.GetReplicaResult{
 				Cas:      mko.cas,
@@ -319,14 +370,22 @@ func (mko *mockKvProvider) GetAnyReplicaEx(opts gocbcore.GetAnyReplicaOptions, c
 		}
 	})
 
-	return &mockPendingOp{cancelSuccess: mko.opCancellationSuccess}, nil
+	return op, nil
 }
 
 func (mko *mockKvProvider) GetOneReplicaEx(opts gocbcore.GetOneReplicaOptions, cb gocbcore.GetReplicaExCallback) (gocbcore.PendingOp, error) {
-	time.AfterFunc(mko.opWait, func() {
-		if mko.err == nil {
+	op := &mockPendingOp{cancelSuccess: mko.opCancellationSuccess}
+	op.timer = time.AfterFunc(mko.opWait, func() {
+		cas := mko.cas
+		if replicaCas, ok := mko.replicaCas[opts.ReplicaIdx]; ok {
+			cas = replicaCas
+		}
+
+		if replicaErr, ok := mko.replicaErr[opts.ReplicaIdx]; ok {
+			cb(nil, replicaErr)
+		} else if mko.err == nil {
 			cb(&gocbcore.GetReplicaResult{
-				Cas:      mko.cas,
+				Cas:      cas,
 				Flags:    mko.flags,
 				Datatype: mko.datatype,
 				Value:    mko.value.([]byte),
@@ -336,7 +395,7 @@ func (mko *mockKvProvider) GetOneReplicaEx(opts gocbcore.GetOneReplicaOptions, c
 		}
 	})
 
-	return &mockPendingOp{cancelSuccess: mko.opCancellationSuccess}, nil
+	return op, nil
 }
 
 func (mko *mockKvProvider) PingKvEx(opts gocbcore.PingKvOptions, cb gocbcore.PingKvExCallback) (gocbcore.PendingOp, error) {
@@ -352,10 +411,38 @@ func (mko *mockKvProvider) PingKvEx(opts gocbcore.PingKvOptions, cb gocbcore.Pin
 }
 
 func (mko *mockKvProvider) NumReplicas() int {
-	return 0
+	return mko.numReplicas
+}
+
+func (mko *mockKvProvider) NumVbuckets() int {
+	return mko.numVbuckets
+}
+
+func (mko *mockKvProvider) NumServers() int {
+	return mko.numServers
+}
+
+func (mko *mockKvProvider) KeyToVbucket(key []byte) uint16 {
+	return mko.mt.VbId
+}
+
+func (mko *mockKvProvider) VbucketToServer(vbID uint16, replicaIdx uint32) int {
+	return int(replicaIdx)
 }
 
 func (p *mockHTTPProvider) DoHttpRequest(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+	if req.Method == "DELETE" && strings.Contains(req.Path, "/active_requests") {
+		if p.cancelFn != nil {
+			return p.cancelFn(req)
+		}
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   req.Endpoint,
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(nil), nil},
+		}, nil
+	}
+
 	return p.doFn(req)
 }
 
@@ -391,3 +478,18 @@ func (mc *mockClient) getHTTPProvider() (httpProvider, error) {
 func (mc *mockClient) getDiagnosticsProvider() (diagnosticsProvider, error) {
 	return mc.mockDiagnosticsProvider, nil
 }
+
+func (mc *mockClient) getDcpProvider() (dcpProvider, error) {
+	if mc.mockDcpProvider == nil {
+		return nil, errors.New("no dcp provider")
+	}
+	return mc.mockDcpProvider, nil
+}
+
+func (mc *mockClient) supportsEnhancedDurability() bool {
+	return !mc.durabilityUnsupported
+}
+
+func (mc *mockClient) kvServerAddresses() []string {
+	return mc.kvServers
+}
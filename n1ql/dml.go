@@ -0,0 +1,343 @@
+package n1ql
+
+import (
+	"strings"
+)
+
+// InsertBuilder builds an INSERT or UPSERT statement.
+type InsertBuilder struct {
+	verb      string
+	keyspace  []string
+	values    []setTerm
+	returning []interface{}
+}
+
+// InsertInto starts an INSERT statement into keyspace, which fails for documents which already exist.
+func InsertInto(keyspace ...string) *InsertBuilder {
+	return &InsertBuilder{verb: "INSERT", keyspace: keyspace}
+}
+
+// UpsertInto starts an UPSERT statement into keyspace, which replaces documents which already exist.
+func UpsertInto(keyspace ...string) *InsertBuilder {
+	return &InsertBuilder{verb: "UPSERT", keyspace: keyspace}
+}
+
+// Value adds a document to write, key and value are bound unless they are expressions.
+func (b *InsertBuilder) Value(key, value interface{}) *InsertBuilder {
+	b.values = append(b.values, setTerm{path: key, value: value})
+	return b
+}
+
+// Returning sets the fields returned for each document written.
+func (b *InsertBuilder) Returning(fields ...interface{}) *InsertBuilder {
+	b.returning = fields
+	return b
+}
+
+// Build returns the statement and its parameters.
+func (b *InsertBuilder) Build() (*Statement, error) {
+	w := newWriter()
+	if len(b.values) == 0 {
+		w.fail("%s requires at least one value", b.verb)
+	}
+
+	values := make([]string, len(b.values))
+	for i, value := range b.values {
+		values[i] = "(" + operand(value.path).render(w) + ", " + operand(value.value).render(w) + ")"
+	}
+
+	return w.statement(clauses(
+		b.verb+" INTO "+w.keyspace(b.keyspace)+" (KEY, VALUE) VALUES "+strings.Join(values, ", "),
+		w.returning(b.returning),
+	))
+}
+
+// UpdateBuilder builds an UPDATE statement.
+type UpdateBuilder struct {
+	keyspace  []string
+	alias     string
+	useKeys   []string
+	sets      []setTerm
+	unsets    []interface{}
+	where     []Expr
+	limit     int
+	returning []interface{}
+}
+
+// Update starts an UPDATE statement of keyspace.
+func Update(keyspace ...string) *UpdateBuilder {
+	return &UpdateBuilder{keyspace: keyspace, limit: -1}
+}
+
+// As aliases the keyspace.
+func (b *UpdateBuilder) As(alias string) *UpdateBuilder {
+	b.alias = alias
+	return b
+}
+
+// UseKeys restricts the update to the documents with keys.
+func (b *UpdateBuilder) UseKeys(keys ...string) *UpdateBuilder {
+	b.useKeys = keys
+	return b
+}
+
+// Set sets the field at path, which is a string path or an expression, to value.
+func (b *UpdateBuilder) Set(path, value interface{}) *UpdateBuilder {
+	b.sets = append(b.sets, setTerm{path: path, value: value})
+	return b
+}
+
+// Unset removes the field at path, which is a string path or an expression.
+func (b *UpdateBuilder) Unset(path interface{}) *UpdateBuilder {
+	b.unsets = append(b.unsets, path)
+	return b
+}
+
+// Where adds a condition which documents must satisfy, multiple conditions are combined with AND.
+func (b *UpdateBuilder) Where(cond Expr) *UpdateBuilder {
+	b.where = append(b.where, cond)
+	return b
+}
+
+// Limit restricts the number of documents updated.
+func (b *UpdateBuilder) Limit(n int) *UpdateBuilder {
+	b.limit = n
+	return b
+}
+
+// Returning sets the fields returned for each document updated.
+func (b *UpdateBuilder) Returning(fields ...interface{}) *UpdateBuilder {
+	b.returning = fields
+	return b
+}
+
+// Build returns the statement and its parameters.
+func (b *UpdateBuilder) Build() (*Statement, error) {
+	w := newWriter()
+	if len(b.sets) == 0 && len(b.unsets) == 0 {
+		w.fail("UPDATE requires at least one SET or UNSET")
+	}
+	if b.limit < -1 {
+		w.fail("limit cannot be negative")
+	}
+
+	var unset string
+	if len(b.unsets) > 0 {
+		unset = "UNSET " + w.list(b.unsets, field)
+	}
+
+	return w.statement(clauses(
+		"UPDATE "+w.keyspace(b.keyspace),
+		alias(w, b.alias),
+		w.useKeys(b.useKeys),
+		w.sets(b.sets),
+		unset,
+		w.where(b.where),
+		w.limit(b.limit),
+		w.returning(b.returning),
+	))
+}
+
+// DeleteBuilder builds a DELETE statement.
+type DeleteBuilder struct {
+	keyspace  []string
+	alias     string
+	useKeys   []string
+	where     []Expr
+	limit     int
+	returning []interface{}
+}
+
+// DeleteFrom starts a DELETE statement from keyspace.
+func DeleteFrom(keyspace ...string) *DeleteBuilder {
+	return &DeleteBuilder{keyspace: keyspace, limit: -1}
+}
+
+// As aliases the keyspace.
+func (b *DeleteBuilder) As(alias string) *DeleteBuilder {
+	b.alias = alias
+	return b
+}
+
+// UseKeys restricts the delete to the documents with keys.
+func (b *DeleteBuilder) UseKeys(keys ...string) *DeleteBuilder {
+	b.useKeys = keys
+	return b
+}
+
+// Where adds a condition which documents must satisfy, multiple conditions are combined with AND.
+func (b *DeleteBuilder) Where(cond Expr) *DeleteBuilder {
+	b.where = append(b.where, cond)
+	return b
+}
+
+// Limit restricts the number of documents deleted.
+func (b *DeleteBuilder) Limit(n int) *DeleteBuilder {
+	b.limit = n
+	return b
+}
+
+// Returning sets the fields returned for each document deleted.
+func (b *DeleteBuilder) Returning(fields ...interface{}) *DeleteBuilder {
+	b.returning = fields
+	return b
+}
+
+// Build returns the statement and its parameters.
+func (b *DeleteBuilder) Build() (*Statement, error) {
+	w := newWriter()
+	if b.limit < -1 {
+		w.fail("limit cannot be negative")
+	}
+
+	return w.statement(clauses(
+		"DELETE FROM "+w.keyspace(b.keyspace),
+		alias(w, b.alias),
+		w.useKeys(b.useKeys),
+		w.where(b.where),
+		w.limit(b.limit),
+		w.returning(b.returning),
+	))
+}
+
+// MergeBuilder builds a MERGE statement.
+type MergeBuilder struct {
+	target      []string
+	targetAlias string
+	source      []string
+	sourceAlias string
+	usingSource bool
+	on          *Expr
+	onKey       *Expr
+	sets        []setTerm
+	delete      bool
+	insertKey   interface{}
+	insertValue interface{}
+	insert      bool
+	returning   []interface{}
+}
+
+// MergeInto starts a MERGE statement into keyspace.
+func MergeInto(keyspace ...string) *MergeBuilder {
+	return &MergeBuilder{target: keyspace}
+}
+
+// As aliases the target keyspace, or the source keyspace once Using has been called.
+func (b *MergeBuilder) As(alias string) *MergeBuilder {
+	if b.usingSource {
+		b.sourceAlias = alias
+	} else {
+		b.targetAlias = alias
+	}
+	return b
+}
+
+// Using sets the keyspace to merge from.
+func (b *MergeBuilder) Using(keyspace ...string) *MergeBuilder {
+	b.source = keyspace
+	b.usingSource = true
+	return b
+}
+
+// On matches source and target documents using cond.
+func (b *MergeBuilder) On(cond Expr) *MergeBuilder {
+	b.on = &cond
+	return b
+}
+
+// OnKey matches source documents to the target document with the key given by expr.
+func (b *MergeBuilder) OnKey(expr Expr) *MergeBuilder {
+	b.onKey = &expr
+	return b
+}
+
+// WhenMatchedSet sets the field at path of matched target documents to value.
+func (b *MergeBuilder) WhenMatchedSet(path, value interface{}) *MergeBuilder {
+	b.sets = append(b.sets, setTerm{path: path, value: value})
+	return b
+}
+
+// WhenMatchedDelete deletes matched target documents.
+func (b *MergeBuilder) WhenMatchedDelete() *MergeBuilder {
+	b.delete = true
+	return b
+}
+
+// WhenNotMatchedInsert inserts value when there is no matching target document. When merging using OnKey the
+// key is given by the OnKey expression and key must be nil.
+func (b *MergeBuilder) WhenNotMatchedInsert(key, value interface{}) *MergeBuilder {
+	b.insertKey = key
+	b.insertValue = value
+	b.insert = true
+	return b
+}
+
+// Returning sets the fields returned for each document written.
+func (b *MergeBuilder) Returning(fields ...interface{}) *MergeBuilder {
+	b.returning = fields
+	return b
+}
+
+// Build returns the statement and its parameters.
+func (b *MergeBuilder) Build() (*Statement, error) {
+	w := newWriter()
+	if len(b.sets) == 0 && !b.delete && !b.insert {
+		w.fail("MERGE requires at least one action")
+	}
+	if len(b.sets) > 0 && b.delete {
+		w.fail("MERGE cannot both update and delete matched documents")
+	}
+	if (b.on == nil) == (b.onKey == nil) {
+		w.fail("MERGE requires exactly one of ON or ON KEY")
+	}
+
+	var on string
+	if b.on != nil {
+		on = "ON " + b.on.render(w)
+	} else if b.onKey != nil {
+		on = "ON KEY " + b.onKey.render(w)
+	}
+
+	var update string
+	if len(b.sets) > 0 {
+		update = "WHEN MATCHED THEN UPDATE " + w.sets(b.sets)
+	}
+
+	var del string
+	if b.delete {
+		del = "WHEN MATCHED THEN DELETE"
+	}
+
+	var insert string
+	if b.insert {
+		if b.onKey != nil {
+			if b.insertKey != nil {
+				w.fail("the key of inserted documents is given by ON KEY")
+			}
+			insert = "WHEN NOT MATCHED THEN INSERT " + operand(b.insertValue).render(w)
+		} else {
+			insert = "WHEN NOT MATCHED THEN INSERT (KEY " + operand(b.insertKey).render(w) + ", VALUE " +
+				operand(b.insertValue).render(w) + ")"
+		}
+	}
+
+	return w.statement(clauses(
+		"MERGE INTO "+w.keyspace(b.target),
+		alias(w, b.targetAlias),
+		"USING "+w.keyspace(b.source),
+		alias(w, b.sourceAlias),
+		on,
+		update,
+		del,
+		insert,
+		w.returning(b.returning),
+	))
+}
+
+func alias(w *writer, alias string) string {
+	if alias == "" {
+		return ""
+	}
+
+	return "AS " + w.ident(alias)
+}
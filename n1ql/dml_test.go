@@ -0,0 +1,117 @@
+package n1ql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInsertAndUpsert(t *testing.T) {
+	stmt, err := InsertInto("travel-sample", "inventory", "airline").
+		Value("airline_1", map[string]string{"name": "One"}).
+		Value("airline_2", map[string]string{"name": "Two"}).
+		Returning(MetaID("").As("id")).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	expected := "INSERT INTO `travel-sample`.`inventory`.`airline` (KEY, VALUE) VALUES ($p1, $p2), ($p3, $p4) RETURNING META().id AS `id`"
+	if stmt.Statement != expected {
+		t.Fatalf("Expected statement to be\n%s\nbut was\n%s", expected, stmt.Statement)
+	}
+
+	if len(stmt.Parameters) != 4 || stmt.Parameters["p3"] != "airline_2" {
+		t.Fatalf("Expected parameters to be bound in order but was %v", stmt.Parameters)
+	}
+
+	stmt, err = UpsertInto("default").Value("key", "value").Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	if stmt.Statement != "UPSERT INTO `default` (KEY, VALUE) VALUES ($p1, $p2)" {
+		t.Fatalf("Statement was not as expected %s", stmt.Statement)
+	}
+
+	_, err = InsertInto("default").Build()
+	if err == nil {
+		t.Fatalf("Expected an insert without values to error")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	stmt, err := Update("travel-sample").As("a").
+		Set("a.callsign", "NEW").
+		Set(I("a", "updated"), Func("NOW_STR")).
+		Unset("a.legacy").
+		Where(I("a", "type").Eq("airline")).
+		Limit(5).
+		Returning("a.*").
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	expected := "UPDATE `travel-sample` AS `a` SET `a`.`callsign` = $p1, `a`.`updated` = NOW_STR() UNSET `a`.`legacy` " +
+		"WHERE `a`.`type` = $p2 LIMIT 5 RETURNING `a`.*"
+	if stmt.Statement != expected {
+		t.Fatalf("Expected statement to be\n%s\nbut was\n%s", expected, stmt.Statement)
+	}
+
+	_, err = Update("travel-sample").Build()
+	if err == nil {
+		t.Fatalf("Expected an update without SET or UNSET to error")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	stmt, err := DeleteFrom("travel-sample").UseKeys("a", "b").Returning(MetaID("")).Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	if stmt.Statement != "DELETE FROM `travel-sample` USE KEYS $p1 RETURNING META().id" {
+		t.Fatalf("Statement was not as expected %s", stmt.Statement)
+	}
+
+	if !reflect.DeepEqual(stmt.Parameters["p1"], []string{"a", "b"}) {
+		t.Fatalf("Expected keys to be bound but was %v", stmt.Parameters)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	stmt, err := MergeInto("orders").As("o").
+		Using("staging").As("s").
+		On(MetaID("o").Eq(MetaID("s"))).
+		WhenMatchedSet("o.status", I("s", "status")).
+		WhenNotMatchedInsert(MetaID("s"), I("s")).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	expected := "MERGE INTO `orders` AS `o` USING `staging` AS `s` ON META(`o`).id = META(`s`).id " +
+		"WHEN MATCHED THEN UPDATE SET `o`.`status` = `s`.`status` WHEN NOT MATCHED THEN INSERT (KEY META(`s`).id, VALUE `s`)"
+	if stmt.Statement != expected {
+		t.Fatalf("Expected statement to be\n%s\nbut was\n%s", expected, stmt.Statement)
+	}
+
+	stmt, err = MergeInto("orders").Using("staging").As("s").
+		OnKey(I("s", "orderId")).
+		WhenMatchedDelete().
+		WhenNotMatchedInsert(nil, map[string]string{"status": "new"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	expected = "MERGE INTO `orders` USING `staging` AS `s` ON KEY `s`.`orderId` WHEN MATCHED THEN DELETE WHEN NOT MATCHED THEN INSERT $p1"
+	if stmt.Statement != expected {
+		t.Fatalf("Expected statement to be\n%s\nbut was\n%s", expected, stmt.Statement)
+	}
+
+	_, err = MergeInto("orders").Using("staging").WhenMatchedDelete().Build()
+	if err == nil {
+		t.Fatalf("Expected a merge without ON to error")
+	}
+}
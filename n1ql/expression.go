@@ -0,0 +1,273 @@
+package n1ql
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Expr is an expression within a statement. Functions which accept an interface{} operand treat an Expr as
+// an expression and bind any other value as a parameter.
+type Expr struct {
+	fn func(w *writer) string
+}
+
+func (e Expr) render(w *writer) string {
+	if e.fn == nil {
+		w.fail("expressions cannot be empty")
+		return ""
+	}
+
+	return e.fn(w)
+}
+
+// I returns an identifier path such as a field, each element is escaped. I("a", "b") refers to field b of a.
+func I(path ...string) Expr {
+	return Expr{func(w *writer) string {
+		if len(path) == 0 {
+			w.fail("identifier paths cannot be empty")
+		}
+
+		escaped := make([]string, len(path))
+		for i, name := range path {
+			escaped[i] = w.ident(name)
+		}
+
+		return strings.Join(escaped, ".")
+	}}
+}
+
+// V returns an expression which binds value as a parameter.
+func V(value interface{}) Expr {
+	return Expr{func(w *writer) string {
+		return w.bind(value)
+	}}
+}
+
+// Raw returns an expression which is written into the statement as is. It must not contain untrusted input.
+func Raw(expression string) Expr {
+	return Expr{func(w *writer) string {
+		return expression
+	}}
+}
+
+// All returns every field of the keyspace alias, or every field when alias is empty.
+func All(alias string) Expr {
+	return Expr{func(w *writer) string {
+		if alias == "" {
+			return "*"
+		}
+
+		return w.ident(alias) + ".*"
+	}}
+}
+
+// MetaID returns the document key of the keyspace alias, which may be empty when there is only one keyspace.
+func MetaID(alias string) Expr {
+	return Expr{func(w *writer) string {
+		if alias == "" {
+			return "META().id"
+		}
+
+		return "META(" + w.ident(alias) + ").id"
+	}}
+}
+
+var funcNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Func returns a call to the function name, such as LOWER or ARRAY_LENGTH.
+func Func(name string, args ...interface{}) Expr {
+	return Expr{func(w *writer) string {
+		if !funcNameRegexp.MatchString(name) {
+			w.fail("invalid function name %q", name)
+		}
+
+		return name + "(" + w.list(args, operand) + ")"
+	}}
+}
+
+// Not returns the negation of e.
+func Not(e Expr) Expr {
+	return Expr{func(w *writer) string {
+		return "NOT (" + e.render(w) + ")"
+	}}
+}
+
+// operand converts a value within an expression into an Expr, binding it unless it already is one.
+func operand(v interface{}) Expr {
+	if e, ok := v.(Expr); ok {
+		return e
+	}
+
+	return V(v)
+}
+
+// field converts a field reference into an Expr. Strings are treated as dotted paths, use I for names which
+// contain dots.
+func field(v interface{}) Expr {
+	switch f := v.(type) {
+	case Expr:
+		return f
+	case string:
+		if f == "*" {
+			return All("")
+		}
+		if strings.HasSuffix(f, ".*") {
+			path := I(strings.Split(strings.TrimSuffix(f, ".*"), ".")...)
+			return Expr{func(w *writer) string {
+				return path.render(w) + ".*"
+			}}
+		}
+		return I(strings.Split(f, ".")...)
+	default:
+		return Expr{func(w *writer) string {
+			w.fail("unsupported field type %T, fields must be strings or expressions", v)
+			return ""
+		}}
+	}
+}
+
+func and(conds []Expr) Expr {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+
+	return conds[0].And(conds[1:]...)
+}
+
+func (e Expr) binary(op string, other interface{}) Expr {
+	return Expr{func(w *writer) string {
+		return e.render(w) + " " + op + " " + operand(other).render(w)
+	}}
+}
+
+func (e Expr) suffix(s string) Expr {
+	return Expr{func(w *writer) string {
+		return e.render(w) + " " + s
+	}}
+}
+
+// Eq returns e = other.
+func (e Expr) Eq(other interface{}) Expr {
+	return e.binary("=", other)
+}
+
+// Ne returns e != other.
+func (e Expr) Ne(other interface{}) Expr {
+	return e.binary("!=", other)
+}
+
+// Lt returns e < other.
+func (e Expr) Lt(other interface{}) Expr {
+	return e.binary("<", other)
+}
+
+// Le returns e <= other.
+func (e Expr) Le(other interface{}) Expr {
+	return e.binary("<=", other)
+}
+
+// Gt returns e > other.
+func (e Expr) Gt(other interface{}) Expr {
+	return e.binary(">", other)
+}
+
+// Ge returns e >= other.
+func (e Expr) Ge(other interface{}) Expr {
+	return e.binary(">=", other)
+}
+
+// Like returns e LIKE pattern.
+func (e Expr) Like(pattern interface{}) Expr {
+	return e.binary("LIKE", pattern)
+}
+
+// NotLike returns e NOT LIKE pattern.
+func (e Expr) NotLike(pattern interface{}) Expr {
+	return e.binary("NOT LIKE", pattern)
+}
+
+// In returns e IN values, values is usually a slice.
+func (e Expr) In(values interface{}) Expr {
+	return e.binary("IN", values)
+}
+
+// NotIn returns e NOT IN values, values is usually a slice.
+func (e Expr) NotIn(values interface{}) Expr {
+	return e.binary("NOT IN", values)
+}
+
+// Between returns e BETWEEN low AND high.
+func (e Expr) Between(low, high interface{}) Expr {
+	return Expr{func(w *writer) string {
+		return e.render(w) + " BETWEEN " + operand(low).render(w) + " AND " + operand(high).render(w)
+	}}
+}
+
+// IsNull returns e IS NULL.
+func (e Expr) IsNull() Expr {
+	return e.suffix("IS NULL")
+}
+
+// IsNotNull returns e IS NOT NULL.
+func (e Expr) IsNotNull() Expr {
+	return e.suffix("IS NOT NULL")
+}
+
+// IsMissing returns e IS MISSING.
+func (e Expr) IsMissing() Expr {
+	return e.suffix("IS MISSING")
+}
+
+// IsNotMissing returns e IS NOT MISSING.
+func (e Expr) IsNotMissing() Expr {
+	return e.suffix("IS NOT MISSING")
+}
+
+// IsValued returns e IS VALUED.
+func (e Expr) IsValued() Expr {
+	return e.suffix("IS VALUED")
+}
+
+// IsNotValued returns e IS NOT VALUED.
+func (e Expr) IsNotValued() Expr {
+	return e.suffix("IS NOT VALUED")
+}
+
+func (e Expr) logical(op string, others []Expr) Expr {
+	return Expr{func(w *writer) string {
+		rendered := make([]string, len(others)+1)
+		rendered[0] = e.render(w)
+		for i, other := range others {
+			rendered[i+1] = other.render(w)
+		}
+
+		return "(" + strings.Join(rendered, " "+op+" ") + ")"
+	}}
+}
+
+// And returns the conjunction of e and others.
+func (e Expr) And(others ...Expr) Expr {
+	return e.logical("AND", others)
+}
+
+// Or returns the disjunction of e and others.
+func (e Expr) Or(others ...Expr) Expr {
+	return e.logical("OR", others)
+}
+
+// As aliases e, for use in a projection.
+func (e Expr) As(alias string) Expr {
+	return Expr{func(w *writer) string {
+		return e.render(w) + " AS " + w.ident(alias)
+	}}
+}
+
+// Asc orders by e ascending, for use in ORDER BY.
+func (e Expr) Asc() Expr {
+	return e.suffix("ASC")
+}
+
+// Desc orders by e descending, for use in ORDER BY.
+func (e Expr) Desc() Expr {
+	return e.suffix("DESC")
+}
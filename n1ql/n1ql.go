@@ -0,0 +1,188 @@
+// Package n1ql provides builders for N1QL statements. Identifiers are always escaped and values are never
+// written into statements, instead they are bound as named parameters:
+//
+//	stmt, err := n1ql.Select("name", "iata").
+//		From("travel-sample").
+//		Where(n1ql.I("type").Eq("airline").And(n1ql.I("country").Eq(country))).
+//		OrderBy("name").
+//		Limit(10).
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//	results, err := cluster.Query(stmt.Statement, &gocb.QueryOptions{NamedParameters: stmt.Parameters})
+package n1ql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Statement is a N1QL statement produced by a builder, along with the values which it references.
+type Statement struct {
+	Statement string
+	// Parameters are the named parameters referenced by the statement, keyed without the leading $. They can be
+	// used as QueryOptions.NamedParameters.
+	Parameters map[string]interface{}
+}
+
+// EscapeIdentifier returns name quoted for use as an identifier, such as a keyspace, field or index name.
+func EscapeIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// EscapeKeyspace returns the escaped path of a keyspace, such as a bucket or a bucket, scope and collection.
+func EscapeKeyspace(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = EscapeIdentifier(part)
+	}
+
+	return strings.Join(escaped, ".")
+}
+
+// Render returns e as it is written into statements, for use within statements which are not built by this package.
+// Expressions which bind values cannot be rendered on their own as their parameters would be lost.
+func Render(e Expr) (string, error) {
+	w := newWriter()
+	rendered := e.render(w)
+	if w.err != nil {
+		return "", w.err
+	}
+
+	if len(w.params) > 0 {
+		return "", fmt.Errorf("expressions which bind values cannot be rendered on their own")
+	}
+
+	return rendered, nil
+}
+
+// writer accumulates the parameters and the first error encountered whilst building a statement.
+type writer struct {
+	params map[string]interface{}
+	err    error
+}
+
+func newWriter() *writer {
+	return &writer{
+		params: make(map[string]interface{}),
+	}
+}
+
+func (w *writer) fail(format string, args ...interface{}) {
+	if w.err == nil {
+		w.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (w *writer) ident(name string) string {
+	if name == "" {
+		w.fail("identifiers cannot be empty")
+	}
+
+	return EscapeIdentifier(name)
+}
+
+func (w *writer) keyspace(parts []string) string {
+	if len(parts) == 0 {
+		w.fail("a keyspace must be specified")
+	}
+
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = w.ident(part)
+	}
+
+	return strings.Join(escaped, ".")
+}
+
+// bind adds value as a parameter, returning the name to reference it by.
+func (w *writer) bind(value interface{}) string {
+	name := "p" + strconv.Itoa(len(w.params)+1)
+	w.params[name] = value
+
+	return "$" + name
+}
+
+func (w *writer) list(items []interface{}, toExpr func(interface{}) Expr) string {
+	rendered := make([]string, len(items))
+	for i, item := range items {
+		rendered[i] = toExpr(item).render(w)
+	}
+
+	return strings.Join(rendered, ", ")
+}
+
+func (w *writer) statement(sql string) (*Statement, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+
+	return &Statement{
+		Statement:  sql,
+		Parameters: w.params,
+	}, nil
+}
+
+// clauses joins the non-empty clauses of a statement.
+func clauses(parts ...string) string {
+	nonEmpty := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+
+	return strings.Join(nonEmpty, " ")
+}
+
+func (w *writer) where(conds []Expr) string {
+	if len(conds) == 0 {
+		return ""
+	}
+
+	return "WHERE " + and(conds).render(w)
+}
+
+func (w *writer) limit(n int) string {
+	if n < 0 {
+		return ""
+	}
+
+	return "LIMIT " + strconv.Itoa(n)
+}
+
+func (w *writer) returning(fields []interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return "RETURNING " + w.list(fields, field)
+}
+
+func (w *writer) useKeys(keys []string) string {
+	if keys == nil {
+		return ""
+	}
+
+	return "USE KEYS " + w.bind(keys)
+}
+
+func (w *writer) sets(sets []setTerm) string {
+	if len(sets) == 0 {
+		return ""
+	}
+
+	rendered := make([]string, len(sets))
+	for i, set := range sets {
+		rendered[i] = field(set.path).render(w) + " = " + operand(set.value).render(w)
+	}
+
+	return "SET " + strings.Join(rendered, ", ")
+}
+
+type setTerm struct {
+	path  interface{}
+	value interface{}
+}
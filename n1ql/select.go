@@ -0,0 +1,303 @@
+package n1ql
+
+import (
+	"strconv"
+	"strings"
+)
+
+type keyspaceTerm struct {
+	kind     string
+	keyspace []string
+	unnest   Expr
+	alias    string
+	useKeys  []string
+	useIndex []string
+	on       *Expr
+	onKeys   *Expr
+}
+
+func (t *keyspaceTerm) render(w *writer) string {
+	var parts []string
+	if t.kind != "" {
+		parts = append(parts, t.kind)
+	}
+
+	if t.kind == "UNNEST" || t.kind == "LEFT UNNEST" {
+		parts = append(parts, t.unnest.render(w))
+	} else {
+		parts = append(parts, w.keyspace(t.keyspace))
+	}
+
+	if t.alias != "" {
+		parts = append(parts, "AS "+w.ident(t.alias))
+	}
+
+	if t.useKeys != nil {
+		parts = append(parts, w.useKeys(t.useKeys))
+	}
+
+	if len(t.useIndex) > 0 {
+		indexes := make([]string, len(t.useIndex))
+		for i, index := range t.useIndex {
+			indexes[i] = w.ident(index)
+		}
+		parts = append(parts, "USE INDEX ("+strings.Join(indexes, ", ")+")")
+	}
+
+	if t.on != nil {
+		parts = append(parts, "ON "+t.on.render(w))
+	} else if t.onKeys != nil {
+		parts = append(parts, "ON KEYS "+t.onKeys.render(w))
+	} else if t.kind != "" && t.kind != "UNNEST" && t.kind != "LEFT UNNEST" {
+		w.fail("%s requires an ON or ON KEYS condition", t.kind)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// SelectBuilder builds a SELECT statement. Clauses may be added in any order.
+type SelectBuilder struct {
+	distinct bool
+	raw      bool
+	fields   []interface{}
+	terms    []*keyspaceTerm
+	where    []Expr
+	groupBy  []interface{}
+	having   []Expr
+	orderBy  []interface{}
+	limit    int
+	offset   int
+	last     *keyspaceTerm
+	err      string
+}
+
+// Select starts a SELECT statement projecting fields, or every field if none are given. Fields are strings,
+// which are treated as dotted paths, or expressions.
+func Select(fields ...interface{}) *SelectBuilder {
+	return &SelectBuilder{
+		fields: fields,
+		limit:  -1,
+		offset: -1,
+	}
+}
+
+// Distinct removes duplicate results.
+func (b *SelectBuilder) Distinct() *SelectBuilder {
+	b.distinct = true
+	return b
+}
+
+// Raw returns the value of the single projected field rather than an object.
+func (b *SelectBuilder) Raw() *SelectBuilder {
+	b.raw = true
+	return b
+}
+
+func (b *SelectBuilder) addTerm(term *keyspaceTerm) *SelectBuilder {
+	b.terms = append(b.terms, term)
+	b.last = term
+	return b
+}
+
+func (b *SelectBuilder) lastTerm(clause string) *keyspaceTerm {
+	if b.last == nil {
+		if b.err == "" {
+			b.err = clause + " must follow FROM, JOIN, NEST or UNNEST"
+		}
+		return &keyspaceTerm{}
+	}
+
+	return b.last
+}
+
+// From sets the keyspace to select from, such as a bucket or a bucket, scope and collection.
+func (b *SelectBuilder) From(keyspace ...string) *SelectBuilder {
+	term := &keyspaceTerm{keyspace: keyspace}
+	b.last = term
+
+	if len(b.terms) > 0 && b.terms[0].kind == "" {
+		b.terms[0] = term
+	} else {
+		b.terms = append([]*keyspaceTerm{term}, b.terms...)
+	}
+
+	return b
+}
+
+// As aliases the most recently called FROM, JOIN, NEST or UNNEST term.
+func (b *SelectBuilder) As(alias string) *SelectBuilder {
+	b.lastTerm("AS").alias = alias
+	return b
+}
+
+// UseKeys restricts the most recently added keyspace to the documents with keys.
+func (b *SelectBuilder) UseKeys(keys ...string) *SelectBuilder {
+	b.lastTerm("USE KEYS").useKeys = keys
+	return b
+}
+
+// UseIndex hints which indexes should be used for the most recently added keyspace.
+func (b *SelectBuilder) UseIndex(indexes ...string) *SelectBuilder {
+	b.lastTerm("USE INDEX").useIndex = indexes
+	return b
+}
+
+// Join adds an inner join with keyspace, which must be followed by On or OnKeys.
+func (b *SelectBuilder) Join(keyspace ...string) *SelectBuilder {
+	return b.addTerm(&keyspaceTerm{kind: "JOIN", keyspace: keyspace})
+}
+
+// LeftJoin adds a left outer join with keyspace, which must be followed by On or OnKeys.
+func (b *SelectBuilder) LeftJoin(keyspace ...string) *SelectBuilder {
+	return b.addTerm(&keyspaceTerm{kind: "LEFT JOIN", keyspace: keyspace})
+}
+
+// Nest adds an inner nest of keyspace, which must be followed by On or OnKeys.
+func (b *SelectBuilder) Nest(keyspace ...string) *SelectBuilder {
+	return b.addTerm(&keyspaceTerm{kind: "NEST", keyspace: keyspace})
+}
+
+// LeftNest adds a left outer nest of keyspace, which must be followed by On or OnKeys.
+func (b *SelectBuilder) LeftNest(keyspace ...string) *SelectBuilder {
+	return b.addTerm(&keyspaceTerm{kind: "LEFT NEST", keyspace: keyspace})
+}
+
+// Unnest adds an inner unnest of the array expr, which is a string path or an expression.
+func (b *SelectBuilder) Unnest(expr interface{}) *SelectBuilder {
+	return b.addTerm(&keyspaceTerm{kind: "UNNEST", unnest: field(expr)})
+}
+
+// LeftUnnest adds a left outer unnest of the array expr, which is a string path or an expression.
+func (b *SelectBuilder) LeftUnnest(expr interface{}) *SelectBuilder {
+	return b.addTerm(&keyspaceTerm{kind: "LEFT UNNEST", unnest: field(expr)})
+}
+
+// On sets the condition of the most recently added JOIN or NEST.
+func (b *SelectBuilder) On(cond Expr) *SelectBuilder {
+	b.lastTerm("ON").on = &cond
+	return b
+}
+
+// OnKeys sets the keys used by the most recently added lookup JOIN or NEST, keys is usually an expression.
+func (b *SelectBuilder) OnKeys(keys interface{}) *SelectBuilder {
+	expr := operand(keys)
+	b.lastTerm("ON KEYS").onKeys = &expr
+	return b
+}
+
+// Where adds a condition which results must satisfy, multiple conditions are combined with AND.
+func (b *SelectBuilder) Where(cond Expr) *SelectBuilder {
+	b.where = append(b.where, cond)
+	return b
+}
+
+// GroupBy groups results by fields.
+func (b *SelectBuilder) GroupBy(fields ...interface{}) *SelectBuilder {
+	b.groupBy = append(b.groupBy, fields...)
+	return b
+}
+
+// Having adds a condition which groups must satisfy, multiple conditions are combined with AND.
+func (b *SelectBuilder) Having(cond Expr) *SelectBuilder {
+	b.having = append(b.having, cond)
+	return b
+}
+
+// OrderBy orders results by fields, use Expr.Desc to sort in descending order.
+func (b *SelectBuilder) OrderBy(fields ...interface{}) *SelectBuilder {
+	b.orderBy = append(b.orderBy, fields...)
+	return b
+}
+
+// Limit restricts the number of results.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Offset skips the first n results.
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+func (b *SelectBuilder) render(w *writer) string {
+	if b.err != "" {
+		w.fail("%s", b.err)
+	}
+
+	if b.limit < -1 || b.offset < -1 {
+		w.fail("limit and offset cannot be negative")
+	}
+
+	if b.raw && len(b.fields) != 1 {
+		w.fail("RAW requires exactly one field but %d were given", len(b.fields))
+	}
+
+	if len(b.having) > 0 && len(b.groupBy) == 0 {
+		w.fail("HAVING requires GROUP BY")
+	}
+
+	if len(b.terms) > 0 && b.terms[0].kind != "" {
+		w.fail("%s requires FROM", b.terms[0].kind)
+	}
+
+	head := "SELECT"
+	if b.distinct {
+		head += " DISTINCT"
+	}
+	if b.raw {
+		head += " RAW"
+	}
+
+	fields := All("").render(w)
+	if len(b.fields) > 0 {
+		fields = w.list(b.fields, field)
+	}
+
+	var from string
+	if len(b.terms) > 0 {
+		terms := make([]string, len(b.terms))
+		for i, term := range b.terms {
+			terms[i] = term.render(w)
+		}
+		from = "FROM " + strings.Join(terms, " ")
+	}
+
+	where := w.where(b.where)
+
+	var groupBy string
+	if len(b.groupBy) > 0 {
+		groupBy = "GROUP BY " + w.list(b.groupBy, field)
+	}
+
+	var having string
+	if len(b.having) > 0 {
+		having = "HAVING " + and(b.having).render(w)
+	}
+
+	var orderBy string
+	if len(b.orderBy) > 0 {
+		orderBy = "ORDER BY " + w.list(b.orderBy, field)
+	}
+
+	var offset string
+	if b.offset >= 0 {
+		offset = "OFFSET " + strconv.Itoa(b.offset)
+	}
+
+	return clauses(head+" "+fields, from, where, groupBy, having, orderBy, w.limit(b.limit), offset)
+}
+
+// Expr returns the statement as a subquery, its values are bound as parameters of the enclosing statement.
+func (b *SelectBuilder) Expr() Expr {
+	return Expr{func(w *writer) string {
+		return "(" + b.render(w) + ")"
+	}}
+}
+
+// Build returns the statement and its parameters.
+func (b *SelectBuilder) Build() (*Statement, error) {
+	w := newWriter()
+	return w.statement(b.render(w))
+}
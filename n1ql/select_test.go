@@ -0,0 +1,129 @@
+package n1ql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscapeIdentifier(t *testing.T) {
+	if escaped := EscapeIdentifier("travel-sample"); escaped != "`travel-sample`" {
+		t.Fatalf("Expected identifier to be quoted but was %s", escaped)
+	}
+
+	if escaped := EscapeIdentifier("a` UNION SELECT *"); escaped != "`a`` UNION SELECT *`" {
+		t.Fatalf("Expected backticks to be escaped but was %s", escaped)
+	}
+
+	if escaped := EscapeKeyspace("travel-sample", "inventory", "airline"); escaped != "`travel-sample`.`inventory`.`airline`" {
+		t.Fatalf("Expected keyspace to be escaped but was %s", escaped)
+	}
+}
+
+func TestRender(t *testing.T) {
+	rendered, err := Render(Func("LOWER", I("a", "name`")))
+	if err != nil || rendered != "LOWER(`a`.`name```)" {
+		t.Fatalf("Expected expression to be rendered but was %s, %v", rendered, err)
+	}
+
+	rendered, err = Render(MetaID("a"))
+	if err != nil || rendered != "META(`a`).id" {
+		t.Fatalf("Expected expression to be rendered but was %s, %v", rendered, err)
+	}
+
+	_, err = Render(I("a").Eq("value"))
+	if err == nil {
+		t.Fatalf("Expected expression which binds a value not to be rendered")
+	}
+
+	_, err = Render(Expr{})
+	if err == nil {
+		t.Fatalf("Expected empty expression not to be rendered")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	stmt, err := Select("a.name", I("a", "iata").As("code"), Func("COUNT", Raw("*")).As("routes")).
+		From("travel-sample", "inventory", "airline").As("a").
+		UseIndex("def_type").
+		Join("travel-sample", "inventory", "route").As("r").On(I("r", "airlineid").Eq(MetaID("a"))).
+		Unnest("r.schedule").As("s").
+		Where(I("a", "country").Eq("United Kingdom")).
+		Where(I("s", "day").In([]int{1, 2}).Or(I("s", "utc").IsMissing())).
+		GroupBy("a.name", "a.iata").
+		Having(Func("COUNT", Raw("*")).Gt(10)).
+		OrderBy(I("routes").Desc(), "a.name").
+		Limit(20).
+		Offset(40).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	expected := "SELECT `a`.`name`, `a`.`iata` AS `code`, COUNT(*) AS `routes` " +
+		"FROM `travel-sample`.`inventory`.`airline` AS `a` USE INDEX (`def_type`) " +
+		"JOIN `travel-sample`.`inventory`.`route` AS `r` ON `r`.`airlineid` = META(`a`).id " +
+		"UNNEST `r`.`schedule` AS `s` " +
+		"WHERE (`a`.`country` = $p1 AND (`s`.`day` IN $p2 OR `s`.`utc` IS MISSING)) " +
+		"GROUP BY `a`.`name`, `a`.`iata` HAVING COUNT(*) > $p3 ORDER BY `routes` DESC, `a`.`name` LIMIT 20 OFFSET 40"
+	if stmt.Statement != expected {
+		t.Fatalf("Expected statement to be\n%s\nbut was\n%s", expected, stmt.Statement)
+	}
+
+	expectedParams := map[string]interface{}{"p1": "United Kingdom", "p2": []int{1, 2}, "p3": 10}
+	if !reflect.DeepEqual(stmt.Parameters, expectedParams) {
+		t.Fatalf("Expected parameters to be %v but was %v", expectedParams, stmt.Parameters)
+	}
+}
+
+func TestSelectKeysAndNest(t *testing.T) {
+	stmt, err := Select().Distinct().
+		From("travel-sample").As("h").UseKeys("hotel_1", "hotel_2").
+		LeftNest("travel-sample").As("r").OnKeys(I("h", "reviews")).
+		Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	expected := "SELECT DISTINCT * FROM `travel-sample` AS `h` USE KEYS $p1 LEFT NEST `travel-sample` AS `r` ON KEYS `h`.`reviews`"
+	if stmt.Statement != expected {
+		t.Fatalf("Expected statement to be\n%s\nbut was\n%s", expected, stmt.Statement)
+	}
+
+	if !reflect.DeepEqual(stmt.Parameters["p1"], []string{"hotel_1", "hotel_2"}) {
+		t.Fatalf("Expected keys to be bound but was %v", stmt.Parameters)
+	}
+}
+
+func TestSelectSubquery(t *testing.T) {
+	sub := Select(Raw("RAW iata")).From("airports").Where(I("country").Eq("France"))
+	stmt, err := Select("name").From("airlines").Where(I("iata").In(sub.Expr())).Build()
+	if err != nil {
+		t.Fatalf("Expected Build to not error %v", err)
+	}
+
+	expected := "SELECT `name` FROM `airlines` WHERE `iata` IN (SELECT RAW iata FROM `airports` WHERE `country` = $p1)"
+	if stmt.Statement != expected {
+		t.Fatalf("Expected statement to be\n%s\nbut was\n%s", expected, stmt.Statement)
+	}
+}
+
+func TestSelectErrors(t *testing.T) {
+	builders := map[string]*SelectBuilder{
+		"empty identifier":  Select("name").From(""),
+		"join without on":   Select().From("a").Join("b"),
+		"alias before from": Select().As("a"),
+		"raw fields":        Select("a", "b").Raw(),
+		"having":            Select().From("a").Having(I("a").Gt(1)),
+		"negative limit":    Select().From("a").Limit(-2),
+		"function name":     Select(Func("LOWER(x); DROP", "a")),
+		"field type":        Select(1),
+		"empty expression":  Select().From("a").Where(Expr{}),
+	}
+
+	for name, builder := range builders {
+		_, err := builder.Build()
+		if err == nil {
+			t.Errorf("Expected %s to error", name)
+		}
+	}
+}
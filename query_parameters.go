@@ -0,0 +1,232 @@
+package gocb
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// structParameters converts the exported fields of params, a struct or a pointer to a struct, into named
+// parameters keyed without the leading $. Each field is named by its n1ql tag, falling back to its json tag and
+// then to the field name, and a tag of "-" excludes the field. Fields of embedded structs are promoted in the same
+// way as encoding/json, embedded nil pointers are skipped and where names collide the shallowest field wins, then
+// the tagged field. Names which would still collide, and so be ignored by encoding/json, are an error. Values are
+// serialized using serializer.
+func structParameters(params interface{}, serializer JSONSerializer) (map[string]interface{}, error) {
+	val := reflect.ValueOf(params)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, invalidArgumentsError{message: "query parameters cannot be a nil pointer"}
+		}
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Struct {
+		return nil, invalidArgumentsError{
+			message: fmt.Sprintf("query parameters must be a struct or a pointer to a struct but were %T", params),
+		}
+	}
+
+	var fields []structParameter
+	collectStructParameters(val, 0, &fields)
+
+	dominant := make(map[string]structParameter, len(fields))
+	ambiguous := make(map[string]bool)
+	for _, field := range fields {
+		current, ok := dominant[field.name]
+		switch {
+		case !ok || field.depth < current.depth || field.depth == current.depth && field.tagged && !current.tagged:
+			dominant[field.name] = field
+			delete(ambiguous, field.name)
+		case field.depth == current.depth && field.tagged == current.tagged:
+			ambiguous[field.name] = true
+		}
+	}
+
+	for name := range ambiguous {
+		return nil, invalidArgumentsError{message: fmt.Sprintf("query parameter $%s is defined more than once", name)}
+	}
+
+	named := make(map[string]interface{}, len(dominant))
+	for name, field := range dominant {
+		bytes, err := serializer.Serialize(field.value.Interface())
+		if err != nil {
+			return nil, invalidArgumentsError{message: fmt.Sprintf("could not serialize query parameter $%s: %v", name, err)}
+		}
+
+		named[name] = json.RawMessage(bytes)
+	}
+
+	return named, nil
+}
+
+// structParameter is a field which is a candidate to be bound as a named parameter, depth is how deeply embedded
+// the field is.
+type structParameter struct {
+	name   string
+	depth  int
+	tagged bool
+	value  reflect.Value
+}
+
+func collectStructParameters(val reflect.Value, depth int, fields *[]structParameter) {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, tagged := parameterName(field)
+		if name == "-" {
+			continue
+		}
+
+		fieldVal := val.Field(i)
+		if field.Anonymous && !tagged {
+			embedded := fieldVal
+			for embedded.Kind() == reflect.Ptr && !embedded.IsNil() {
+				embedded = embedded.Elem()
+			}
+
+			if embedded.Kind() == reflect.Struct {
+				collectStructParameters(embedded, depth+1, fields)
+				continue
+			}
+
+			// Nil embedded pointers to structs have no fields to promote, this matches the behaviour of
+			// encoding/json.
+			if embedded.Kind() == reflect.Ptr && embedded.Type().Elem().Kind() == reflect.Struct {
+				continue
+			}
+		}
+
+		// Unexported fields cannot be read, this matches the behaviour of encoding/json.
+		if !fieldVal.CanInterface() {
+			continue
+		}
+
+		*fields = append(*fields, structParameter{
+			name:   name,
+			depth:  depth,
+			tagged: tagged,
+			value:  fieldVal,
+		})
+	}
+}
+
+// parameterName returns the parameter name of field and whether it was given by a tag.
+func parameterName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"n1ql", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if name != "" {
+			return name, true
+		}
+	}
+
+	return field.Name, false
+}
+
+// statementParameters returns the names of the named parameters referenced by statement, without the leading $.
+// Positional parameters, string literals, escaped identifiers and comments are skipped.
+func statementParameters(statement string) map[string]struct{} {
+	names := make(map[string]struct{})
+	for i := 0; i < len(statement); i++ {
+		switch c := statement[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(statement, i, c)
+		case c == '-' && strings.HasPrefix(statement[i:], "--"):
+			end := strings.IndexByte(statement[i:], '\n')
+			if end < 0 {
+				return names
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(statement[i:], "/*"):
+			end := strings.Index(statement[i+2:], "*/")
+			if end < 0 {
+				return names
+			}
+			i += end + 3
+		case c == '$':
+			start := i + 1
+			end := start
+			for end < len(statement) && isParameterChar(statement[end], end == start) {
+				end++
+			}
+			if end > start {
+				names[statement[start:end]] = struct{}{}
+			}
+			i = end - 1
+		}
+	}
+
+	return names
+}
+
+// skipQuoted returns the index of the quote closing the literal or identifier which starts at start. Quotes are
+// escaped either by doubling them or with a backslash.
+func skipQuoted(statement string, start int, quote byte) int {
+	for i := start + 1; i < len(statement); i++ {
+		switch statement[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(statement) && statement[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+
+	return len(statement)
+}
+
+func isParameterChar(c byte, first bool) bool {
+	if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+
+	// Parameters beginning with a digit are positional.
+	return !first && c >= '0' && c <= '9'
+}
+
+// checkNamedParameters verifies that every named parameter referenced by statement has a value in named, and that
+// every value in named is referenced by statement.
+func checkNamedParameters(statement string, named map[string]interface{}) error {
+	referenced := statementParameters(statement)
+
+	var missing, unused []string
+	for name := range referenced {
+		_, ok := named[name]
+		_, prefixed := named["$"+name]
+		if !ok && !prefixed {
+			missing = append(missing, "$"+name)
+		}
+	}
+	for name := range named {
+		if _, ok := referenced[strings.TrimPrefix(name, "$")]; !ok {
+			unused = append(unused, "$"+strings.TrimPrefix(name, "$"))
+		}
+	}
+
+	if len(missing) == 0 && len(unused) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(unused)
+
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "missing values for "+strings.Join(missing, ", "))
+	}
+	if len(unused) > 0 {
+		problems = append(problems, "unused values for "+strings.Join(unused, ", "))
+	}
+
+	return invalidArgumentsError{message: "query parameters do not match statement: " + strings.Join(problems, "; ")}
+}
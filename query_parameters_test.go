@@ -0,0 +1,214 @@
+package gocb
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+type testQueryParamsBase struct {
+	Country string `n1ql:"country"`
+}
+
+type testQueryParams struct {
+	testQueryParamsBase
+	Type     string   `n1ql:"type" json:"kind"`
+	Names    []string `json:"names,omitempty"`
+	Limit    int
+	Ignored  string `n1ql:"-"`
+	internal string
+}
+
+func TestStructParameters(t *testing.T) {
+	params, err := structParameters(&testQueryParams{
+		testQueryParamsBase: testQueryParamsBase{Country: "France"},
+		Type:                "airline",
+		Names:               []string{"a", "b"},
+		Limit:               10,
+		Ignored:             "ignored",
+		internal:            "internal",
+	}, &DefaultJSONSerializer{})
+	if err != nil {
+		t.Fatalf("Expected structParameters to not error %v", err)
+	}
+
+	expected := map[string]string{
+		"country": `"France"`,
+		"type":    `"airline"`,
+		"names":   `["a","b"]`,
+		"Limit":   `10`,
+	}
+	if len(params) != len(expected) {
+		t.Fatalf("Expected %d parameters but was %v", len(expected), params)
+	}
+
+	for name, value := range expected {
+		raw, ok := params[name].(json.RawMessage)
+		if !ok {
+			t.Fatalf("Expected parameter %s to be serialized but was %v", name, params[name])
+		}
+
+		if string(raw) != value {
+			t.Fatalf("Expected parameter %s to be %s but was %s", name, value, raw)
+		}
+	}
+
+	_, err = structParameters(map[string]interface{}{"a": 1}, &DefaultJSONSerializer{})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected a map to be rejected but was %v", err)
+	}
+
+	_, err = structParameters(struct {
+		A string `n1ql:"dup"`
+		B string `json:"dup"`
+	}{}, &DefaultJSONSerializer{})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected duplicate names to be rejected but was %v", err)
+	}
+}
+
+type testQueryParamsPaging struct {
+	Limit  int `n1ql:"limit"`
+	Offset int `n1ql:"offset"`
+}
+
+type testQueryParamsEmbedded struct {
+	*testQueryParamsPaging
+	testQueryParamsBase
+	Country string `n1ql:"country"`
+}
+
+func TestStructParametersEmbedded(t *testing.T) {
+	params, err := structParameters(&testQueryParamsEmbedded{
+		testQueryParamsBase: testQueryParamsBase{Country: "France"},
+		Country:             "Spain",
+	}, &DefaultJSONSerializer{})
+	if err != nil {
+		t.Fatalf("Expected nil embedded pointer to be skipped but was %v", err)
+	}
+
+	if len(params) != 1 || string(params["country"].(json.RawMessage)) != `"Spain"` {
+		t.Fatalf("Expected the shallowest field to win but parameters were %v", params)
+	}
+
+	params, err = structParameters(&testQueryParamsEmbedded{
+		testQueryParamsPaging: &testQueryParamsPaging{Limit: 10},
+	}, &DefaultJSONSerializer{})
+	if err != nil {
+		t.Fatalf("Expected structParameters to not error %v", err)
+	}
+
+	if len(params) != 3 || string(params["limit"].(json.RawMessage)) != `10` {
+		t.Fatalf("Expected fields of the embedded pointer to be promoted but parameters were %v", params)
+	}
+
+	params, err = structParameters(&testQueryParamsEmbedded{}, &DefaultJSONSerializer{})
+	if err != nil {
+		t.Fatalf("Expected structParameters to not error %v", err)
+	}
+
+	err = checkNamedParameters("SELECT * FROM airline WHERE country = $country", params)
+	if err != nil {
+		t.Fatalf("Expected a nil embedded pointer not to be bound but was %v", err)
+	}
+
+	type untagged struct{ Country string }
+	type tagged struct {
+		Name string `json:"Country"`
+	}
+
+	params, err = structParameters(struct {
+		untagged
+		tagged
+	}{untagged{"France"}, tagged{"Spain"}}, &DefaultJSONSerializer{})
+	if err != nil || len(params) != 1 || string(params["Country"].(json.RawMessage)) != `"Spain"` {
+		t.Fatalf("Expected the tagged field to win but was %v, %v", params, err)
+	}
+
+	_, err = structParameters(struct {
+		testQueryParamsBase
+		testQueryParamsEmbedded
+	}{}, &DefaultJSONSerializer{})
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected names which collide at the same depth to be rejected but was %v", err)
+	}
+}
+
+func TestStatementParameters(t *testing.T) {
+	statement := "SELECT * FROM `bucket$x` WHERE a = $a AND b IN $b_2 AND c = $1 AND d = '$quoted''$s' " +
+		"AND e = \"it\\\"s $escaped\" -- $comment\nAND f=$f /* $block */ LIMIT $limit"
+
+	names := statementParameters(statement)
+	expected := []string{"a", "b_2", "f", "limit"}
+	if len(names) != len(expected) {
+		t.Fatalf("Expected %v but was %v", expected, names)
+	}
+
+	for _, name := range expected {
+		if _, ok := names[name]; !ok {
+			t.Fatalf("Expected %s to be referenced but was %v", name, names)
+		}
+	}
+}
+
+func TestQueryOptionsParameters(t *testing.T) {
+	opts := &QueryOptions{
+		Parameters: testQueryParams{Type: "airline", Limit: 5},
+	}
+
+	statement := "SELECT * FROM default WHERE type = $type AND country = $country AND name IN $names LIMIT $Limit"
+	optMap, err := opts.toMap(statement)
+	if err != nil {
+		t.Fatalf("Expected no error but was %v", err)
+	}
+
+	testAssertRawParameter(t, `"airline"`, "$type", optMap)
+	testAssertRawParameter(t, `5`, "$Limit", optMap)
+
+	_, err = opts.toMap("SELECT * FROM default WHERE type = $type AND id = $id")
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected mismatched parameters to error but was %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "missing values for $id") ||
+		!strings.Contains(err.Error(), "unused values for $Limit, $country, $names") {
+		t.Fatalf("Expected error to list missing and unused parameters but was %v", err)
+	}
+
+	opts.NamedParameters = map[string]interface{}{"type": "airline"}
+	_, err = opts.toMap(statement)
+	if err == nil {
+		t.Fatalf("Expected Parameters and NamedParameters to be used exclusively")
+	}
+}
+
+func TestAnalyticsQueryOptionsParameters(t *testing.T) {
+	opts := &AnalyticsQueryOptions{
+		Parameters: struct {
+			Country string `json:"country"`
+		}{Country: "France"},
+	}
+
+	optMap, err := opts.toMap("SELECT * FROM airports WHERE country = $country")
+	if err != nil {
+		t.Fatalf("Expected no error but was %v", err)
+	}
+
+	testAssertRawParameter(t, `"France"`, "$country", optMap)
+
+	_, err = opts.toMap("SELECT * FROM airports")
+	if !IsInvalidArgumentsError(err) {
+		t.Fatalf("Expected unused parameters to error but was %v", err)
+	}
+}
+
+func testAssertRawParameter(t *testing.T, expected, key string, optMap map[string]interface{}) {
+	raw, ok := optMap[key].(json.RawMessage)
+	if !ok {
+		t.Fatalf("Expected %s to be a serialized parameter but was %v", key, optMap[key])
+	}
+
+	if string(raw) != expected {
+		t.Fatalf("Options had incorrect %s, expected %s but was %s", key, expected, raw)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
+	"time"
 
 	"github.com/couchbase/gocbcore/v8"
 	"github.com/pkg/errors"
@@ -27,17 +29,32 @@ func (d *Result) HasExpiration() bool {
 	return d.withExpiration
 }
 
-// Expiration returns the expiration value for the result.
-func (d *Result) Expiration() uint32 {
-	return d.expiration
+// Expiration returns the time at which the document expires, the zero time if it never expires.
+func (d *Result) Expiration() time.Time {
+	return expiryFromWire(d.expiration)
+}
+
+// ExpiresIn returns how long remains until the document expires, 0 if it never expires.
+func (d *Result) ExpiresIn() time.Duration {
+	if d.expiration == 0 {
+		return 0
+	}
+
+	return time.Until(d.Expiration())
 }
 
 // GetResult is the return type of Get operations.
 type GetResult struct {
 	Result
-	transcoder Transcoder
-	flags      uint32
-	contents   []byte
+	transcoder  Transcoder
+	flags       uint32
+	contents    []byte
+	fromReplica bool
+}
+
+// FromReplica returns whether or not this result was read from a replica because of GetOptions.ReplicaFallback.
+func (d *GetResult) FromReplica() bool {
+	return d.fromReplica
 }
 
 // Content assigns the value of the result into the valuePtr using default decoding.
@@ -237,6 +254,131 @@ func (lir *LookupInResult) Exists(idx int) bool {
 	return lir.contents[idx].exists()
 }
 
+// ContentByPath retrieves the value of the operation by its path. If the same path was used by more than one
+// operation then the first of them is used.
+func (lir *LookupInResult) ContentByPath(path string, valuePtr interface{}) error {
+	idx, ok := lir.pathMap[path]
+	if !ok {
+		return errors.New("the supplied path was not part of the lookup")
+	}
+	return lir.contents[idx].as(valuePtr, lir.serializer)
+}
+
+// ExistsPath verifies that the item at path exists.
+func (lir *LookupInResult) ExistsPath(path string) bool {
+	idx, ok := lir.pathMap[path]
+	if !ok {
+		return false
+	}
+	return lir.contents[idx].exists()
+}
+
+// Decode assigns the results of the lookup into the struct pointed to by valuePtr. Each field to be populated
+// must be tagged with the path of the operation it is populated from, e.g. `subdoc:"address.city"`. Fields whose
+// paths were not found are left untouched and bool fields tagged with the path of an Exists operation are set to
+// whether or not the path exists.
+func (lir *LookupInResult) Decode(valuePtr interface{}) error {
+	ptr := reflect.ValueOf(valuePtr)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Struct {
+		return errors.New("the supplied value must be a pointer to a struct")
+	}
+
+	val := ptr.Elem()
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		path, ok := typ.Field(i).Tag.Lookup("subdoc")
+		if !ok || path == "-" {
+			continue
+		}
+
+		idx, ok := lir.pathMap[path]
+		if !ok {
+			continue
+		}
+
+		field := val.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		partial := lir.contents[idx]
+		if partial.err != nil {
+			if IsSubdocPathNotFoundError(partial.err) {
+				continue
+			}
+			return partial.err
+		}
+
+		if len(partial.data) == 0 {
+			if field.Kind() == reflect.Bool {
+				field.SetBool(true)
+			}
+			continue
+		}
+
+		err := partial.as(field.Addr().Interface(), lir.serializer)
+		if err != nil {
+			return errors.Wrapf(err, "could not decode path %s", path)
+		}
+	}
+
+	return nil
+}
+
+// GetMetaResult is the return type of GetMeta operations.
+type GetMetaResult struct {
+	Result
+	revID        uint64
+	seqNo        uint64
+	flags        uint32
+	expiration   uint32
+	valueSize    uint64
+	deleted      bool
+	datatype     []string
+	lastModified time.Time
+}
+
+// RevID returns the revision id of the document.
+func (r *GetMetaResult) RevID() uint64 {
+	return r.revID
+}
+
+// SeqNo returns the sequence number of the last mutation to the document.
+func (r *GetMetaResult) SeqNo() uint64 {
+	return r.seqNo
+}
+
+// Flags returns the flags stored with the document.
+func (r *GetMetaResult) Flags() uint32 {
+	return r.flags
+}
+
+// Expiration returns the time at which the document expires, the zero time if it never expires.
+func (r *GetMetaResult) Expiration() time.Time {
+	return expiryFromWire(r.expiration)
+}
+
+// ValueSize returns the size of the value of the document in bytes.
+func (r *GetMetaResult) ValueSize() uint64 {
+	return r.valueSize
+}
+
+// Deleted returns whether or not the document is a tombstone.
+func (r *GetMetaResult) Deleted() bool {
+	return r.deleted
+}
+
+// Datatype returns the datatypes of the document as reported by the server, e.g. json or xattr.
+func (r *GetMetaResult) Datatype() []string {
+	return r.datatype
+}
+
+// LastModified returns when the document was last modified, this is the zero time if the server does not
+// report it.
+func (r *GetMetaResult) LastModified() time.Time {
+	return r.lastModified
+}
+
 // ExistsResult is the return type of Exist operations.
 type ExistsResult struct {
 	Result
@@ -251,7 +393,9 @@ func (d *ExistsResult) Exists() bool {
 // MutationResult is the return type of any store related operations. It contains Cas and mutation tokens.
 type MutationResult struct {
 	Result
-	mt MutationToken
+	mt             MutationToken
+	durability     *DurabilityResult
+	durabilityMode DurabilityMode
 }
 
 // MutationToken returns the mutation token belonging to an operation.
@@ -259,6 +403,34 @@ func (mr MutationResult) MutationToken() MutationToken {
 	return mr.mt
 }
 
+// Durability returns the outcome of observe based durability for the operation, or nil if
+// PersistTo and ReplicateTo were not used.
+func (mr MutationResult) Durability() *DurabilityResult {
+	return mr.durability
+}
+
+// DurabilityMode returns the mechanism that was used to meet the durability requirements of the operation.
+// This can differ from what was requested when DurabilityFallback is enabled on the cluster.
+func (mr MutationResult) DurabilityMode() DurabilityMode {
+	return mr.durabilityMode
+}
+
+// DurabilityReplicaResult is the observed state of a single copy of a document.
+type DurabilityReplicaResult struct {
+	// ReplicaIdx is the index of the copy, 0 being the active copy.
+	ReplicaIdx int
+	// Node is the address of the node holding the copy, if known.
+	Node       string
+	Replicated bool
+	Persisted  bool
+}
+
+// DurabilityResult is the outcome of observe based durability for a mutation.
+type DurabilityResult struct {
+	Replicas []DurabilityReplicaResult
+	Elapsed  time.Duration
+}
+
 // MutateInResult is the return type of any mutate in related operations.
 // It contains Cas, mutation tokens and any returned content.
 type MutateInResult struct {
@@ -452,7 +624,10 @@ func (r *streamingResult) readAttributes() error {
 // GetReplicaResult is the return type of GetReplica operations.
 type GetReplicaResult struct {
 	GetResult
-	isMaster bool
+	isMaster   bool
+	replicaIdx int
+	node       string
+	err        error
 }
 
 // IsMaster returns whether or not this result came from the active server.
@@ -460,6 +635,24 @@ func (r *GetReplicaResult) IsMaster() bool {
 	return r.isMaster
 }
 
+// ReplicaIdx returns the index of the copy which served this result, where 0 is the active copy. This is only
+// populated for results returned by StreamReplicas.
+func (r *GetReplicaResult) ReplicaIdx() int {
+	return r.replicaIdx
+}
+
+// Node returns the address of the node which served this result. This is only populated for results returned
+// by StreamReplicas.
+func (r *GetReplicaResult) Node() string {
+	return r.node
+}
+
+// Err returns the error which occurred reading this copy of the document, if any. This is only populated for
+// results returned by StreamReplicas.
+func (r *GetReplicaResult) Err() error {
+	return r.err
+}
+
 // GetAllReplicasResult is the return type of GetAllReplica operations.
 type GetAllReplicasResult struct {
 	ctx         context.Context
@@ -470,6 +663,7 @@ type GetAllReplicasResult struct {
 	cancel      context.CancelFunc
 	maxReplicas int
 	transcoder  Transcoder
+	consistency *replicaConsistency
 }
 
 // Next fetches the new replica.
@@ -478,6 +672,20 @@ func (r *GetAllReplicasResult) Next(valuePtr *GetReplicaResult) bool {
 		return false
 	}
 
+	for r.consistency != nil && r.opts.ReplicaIdx <= r.maxReplicas {
+		consistent, err := r.consistency.check(r.ctx, r.provider, r.opts.ReplicaIdx)
+		if err == nil && consistent {
+			break
+		}
+		if r.ctx.Err() != nil {
+			r.err = err
+			return false
+		}
+
+		// This copy is either behind or unavailable so skip it.
+		r.opts.ReplicaIdx++
+	}
+
 	if r.opts.ReplicaIdx > r.maxReplicas {
 		r.closed = true
 		return false
@@ -562,3 +770,116 @@ func (r *GetAllReplicasResult) Close() error {
 	r.closed = true
 	return r.err
 }
+
+// ReplicaStream is the return type of StreamReplicas operations. Results are streamed in the order that the
+// copies respond, including any copies which failed to respond.
+type ReplicaStream struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	resultCh  chan GetReplicaResult
+	numCopies int
+	received  int
+	err       error
+}
+
+// Next assigns the next copy of the document to read into valuePtr, returning false once every copy has been
+// read or the stream has failed. Failures to read an individual copy are reported through GetReplicaResult.Err
+// rather than ending the stream.
+func (r *ReplicaStream) Next(valuePtr *GetReplicaResult) bool {
+	if r.err != nil || r.received >= r.numCopies {
+		return false
+	}
+
+	select {
+	case res := <-r.resultCh:
+		r.received++
+		*valuePtr = res
+		return true
+	case <-r.ctx.Done():
+		if r.ctx.Err() == context.DeadlineExceeded {
+			r.err = timeoutError{}
+		} else {
+			r.err = r.ctx.Err()
+		}
+		return false
+	}
+}
+
+// Close ends the stream, cancelling any outstanding reads, and returns any errors that occurred.
+func (r *ReplicaStream) Close() error {
+	r.cancel()
+	return r.err
+}
+
+// Consensus reads the remaining copies of the document and returns the value which a majority of all copies
+// agree on, by CAS. Copies which failed to be read count against the majority. If a majority of copies agree
+// that the document does not exist then a key not found error is returned alongside the consensus. The stream
+// is closed once Consensus returns.
+func (r *ReplicaStream) Consensus() (*ReplicaConsensus, error) {
+	consensus := &ReplicaConsensus{
+		Copies: r.numCopies,
+	}
+
+	var res GetReplicaResult
+	for r.Next(&res) {
+		consensus.Replicas = append(consensus.Replicas, res)
+	}
+	if err := r.Close(); err != nil {
+		return nil, err
+	}
+
+	agreeing := make(map[Cas]int)
+	var missing []GetReplicaResult
+	var notFoundErr error
+	for i, replica := range consensus.Replicas {
+		if replica.err != nil {
+			if IsKeyNotFoundError(replica.err) {
+				missing = append(missing, replica)
+				notFoundErr = replica.err
+			}
+			continue
+		}
+
+		agreeing[replica.cas]++
+		if consensus.Result == nil || agreeing[replica.cas] > consensus.Agreed {
+			consensus.Result = &consensus.Replicas[i]
+			consensus.Agreed = agreeing[replica.cas]
+		}
+	}
+
+	consensus.Diverged = len(agreeing) > 1 || (len(agreeing) > 0 && len(missing) > 0)
+
+	if len(missing) > consensus.Agreed {
+		consensus.Result = nil
+		consensus.Agreed = len(missing)
+		if consensus.HasMajority() {
+			return consensus, notFoundErr
+		}
+		return consensus, nil
+	}
+
+	if !consensus.HasMajority() {
+		consensus.Result = nil
+	}
+
+	return consensus, nil
+}
+
+// ReplicaConsensus is the result of comparing every copy of a document.
+type ReplicaConsensus struct {
+	// Result is the copy which a majority of copies agree on, or nil if there is no majority.
+	Result *GetReplicaResult
+	// Agreed is the number of copies in the largest group of copies which agree with each other.
+	Agreed int
+	// Copies is the total number of copies of the document, including the active.
+	Copies int
+	// Diverged indicates that the copies which could be read do not all agree with each other.
+	Diverged bool
+	// Replicas contains every copy which was read, including those which failed.
+	Replicas []GetReplicaResult
+}
+
+// HasMajority returns whether or not a majority of copies agree with each other.
+func (c *ReplicaConsensus) HasMajority() bool {
+	return c.Agreed >= c.Copies/2+1
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	gocbcore "github.com/couchbase/gocbcore/v8"
 )
@@ -42,8 +43,8 @@ func TestGetResultExpiry(t *testing.T) {
 		},
 	}
 
-	if res.Expiration() != 10 {
-		t.Fatalf("Expiry value should have been 10 but was %d", res.Expiration())
+	if !res.Expiration().Equal(time.Unix(10, 0)) {
+		t.Fatalf("Expiry value should have been 10 but was %v", res.Expiration())
 	}
 }
 
@@ -181,8 +182,8 @@ func TestLookupInResultExpiry(t *testing.T) {
 		},
 	}
 
-	if res.Expiration() != 10 {
-		t.Fatalf("Expiry value should have been 10 but was %d", res.Expiration())
+	if !res.Expiration().Equal(time.Unix(10, 0)) {
+		t.Fatalf("Expiry value should have been 10 but was %v", res.Expiration())
 	}
 }
 
@@ -3,11 +3,14 @@ package gocb
 // Scope represents a single scope within a bucket.
 type Scope struct {
 	sb stateBlock
+
+	cluster *Cluster
 }
 
 func newScope(bucket *Bucket, scopeName string) *Scope {
 	scope := &Scope{
-		sb: bucket.stateBlock(),
+		sb:      bucket.stateBlock(),
+		cluster: bucket.cluster,
 	}
 	scope.sb.ScopeName = scopeName
 	return scope
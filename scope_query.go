@@ -0,0 +1,53 @@
+package gocb
+
+import (
+	"context"
+
+	"github.com/couchbase/gocb/v2/n1ql"
+)
+
+// queryContext returns the context used by the query and analytics services to resolve unqualified keyspaces
+// as collections within the scope.
+func (s *Scope) queryContext() string {
+	return "default:" + n1ql.EscapeKeyspace(s.sb.BucketName, s.sb.ScopeName)
+}
+
+// Query executes the N1QL query statement within the scope. Keyspaces which are not fully qualified are
+// resolved as collections within the scope, and prepared statements are cached separately for each scope.
+// Volatile: This API is subject to change at any time.
+func (s *Scope) Query(statement string, opts *QueryOptions) (*QueryResults, error) {
+	if opts == nil {
+		opts = &QueryOptions{}
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	provider, err := s.sb.getCachedClient().getHTTPProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cluster.query(ctx, statement, s.queryContext(), opts, provider)
+}
+
+// AnalyticsQuery executes the analytics query statement within the scope. Datasets which are not fully
+// qualified are resolved within the scope.
+// Volatile: This API is subject to change at any time.
+func (s *Scope) AnalyticsQuery(statement string, opts *AnalyticsQueryOptions) (*AnalyticsResults, error) {
+	if opts == nil {
+		opts = &AnalyticsQueryOptions{}
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	provider, err := s.sb.getCachedClient().getHTTPProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cluster.analyticsQuery(ctx, statement, s.queryContext(), opts, provider)
+}
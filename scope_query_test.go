@@ -0,0 +1,126 @@
+package gocb
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	gocbcore "github.com/couchbase/gocbcore/v8"
+)
+
+func testGetScopeForHTTP(cluster *Cluster, scopeName string) *Scope {
+	b := &Bucket{
+		sb: stateBlock{
+			clientStateBlock: clientStateBlock{
+				BucketName: "mock",
+			},
+			cachedClient: cluster.connections["mock-false"],
+		},
+		cluster: cluster,
+	}
+
+	return b.Scope(scopeName)
+}
+
+func TestScopeQuery(t *testing.T) {
+	statement := "SELECT * FROM airline WHERE `type` = ?"
+	dataBytes, err := loadRawTestDataset("enhanced_beer_sample_query_dataset")
+	if err != nil {
+		t.Fatalf("Could not read test dataset: %v", err)
+	}
+
+	var contexts []interface{}
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var opts map[string]interface{}
+		err := json.Unmarshal(req.Body, &opts)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+		contexts = append(contexts, opts["query_context"])
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8093",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(dataBytes), nil},
+		}, nil
+	}
+
+	provider := &mockHTTPProvider{
+		doFn: doHTTP,
+		supportFn: func(capability gocbcore.ClusterCapability) bool {
+			return true
+		},
+	}
+
+	cluster := testGetClusterForHTTP(provider, 60*time.Second, 0, 0)
+	scope := testGetScopeForHTTP(cluster, "inventory")
+
+	_, err = scope.Query(statement, &QueryOptions{Prepared: true, PositionalParameters: []interface{}{"airline"}})
+	if err != nil {
+		t.Fatalf("Expected query execution to not error %v", err)
+	}
+
+	_, err = cluster.Query(statement, &QueryOptions{Prepared: true, PositionalParameters: []interface{}{"airline"}})
+	if err != nil {
+		t.Fatalf("Expected query execution to not error %v", err)
+	}
+
+	if len(contexts) != 2 || contexts[0] != "default:`mock`.`inventory`" || contexts[1] != nil {
+		t.Fatalf("Expected only the scope query to send a query context but was %v", contexts)
+	}
+
+	if len(cluster.queryCache.testEntries()) != 2 {
+		t.Fatalf("Expected the statement to be prepared once per context but was %v", cluster.queryCache.testEntries())
+	}
+
+	if _, ok := cluster.queryCache.testEntries()["default:`mock`.`inventory` "+statement]; !ok {
+		t.Fatalf("Expected query cache to contain the scoped statement")
+	}
+}
+
+func TestScopeAnalyticsQuery(t *testing.T) {
+	dataBytes, err := loadRawTestDataset("beer_sample_analytics_dataset")
+	if err != nil {
+		t.Fatalf("Could not read test dataset: %v", err)
+	}
+
+	doHTTP := func(req *gocbcore.HttpRequest) (*gocbcore.HttpResponse, error) {
+		var opts map[string]interface{}
+		err := json.Unmarshal(req.Body, &opts)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal request body %v", err)
+		}
+
+		if opts["query_context"] != "default:`mock`.`inventory`" {
+			t.Fatalf("Expected query context to be set but was %v", opts["query_context"])
+		}
+
+		return &gocbcore.HttpResponse{
+			Endpoint:   "http://localhost:8095",
+			StatusCode: 200,
+			Body:       &testReadCloser{bytes.NewBuffer(dataBytes), nil},
+		}, nil
+	}
+
+	cluster := testGetClusterForHTTP(&mockHTTPProvider{doFn: doHTTP}, 0, 60*time.Second, 0)
+	scope := testGetScopeForHTTP(cluster, "inventory")
+
+	res, err := scope.AnalyticsQuery("SELECT * FROM airline", nil)
+	if err != nil {
+		t.Fatalf("Expected query execution to not error %v", err)
+	}
+
+	err = res.Close()
+	if err != nil {
+		t.Fatalf("Expected Close to not error %v", err)
+	}
+}
+
+func TestScopeQueryContextEscaping(t *testing.T) {
+	s := &Scope{sb: stateBlock{clientStateBlock: clientStateBlock{BucketName: "my`bucket"}, ScopeName: "inventory"}}
+
+	if s.queryContext() != "default:`my``bucket`.`inventory`" {
+		t.Fatalf("Expected identifiers to be escaped but query context was %s", s.queryContext())
+	}
+}
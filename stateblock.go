@@ -31,6 +31,15 @@ type stateBlock struct {
 	PersistTo       uint
 	ReplicateTo     uint
 
+	DuraPollBehavior          RetryBehavior
+	AmbiguousDurabilityErrors bool
+	DurabilityFallback        bool
+
+	ReplicaFallbackHandler func(ReplicaFallbackEvent)
+
+	KvStats    *kvStatsRegistry
+	NearCaches *nearCacheRegistry
+
 	N1qlRetryBehavior      RetryBehavior
 	AnalyticsRetryBehavior RetryBehavior
 	SearchRetryBehavior    RetryBehavior
@@ -3,6 +3,7 @@ package gocb
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	gocbcore "github.com/couchbase/gocbcore/v8"
 )
@@ -70,6 +71,25 @@ func (mt *MutationState) addSingle(token MutationToken) {
 	stateToken.VbUuid = fmt.Sprintf("%d", token.token.VbUuid)
 }
 
+// vbucketToken returns the token held for the given vbucket within the bucket, if there is one.
+func (mt *MutationState) vbucketToken(bucketName string, vbID uint16) (gocbcore.VbUuid, gocbcore.SeqNo, bool) {
+	if mt.data == nil || (*mt.data)[bucketName] == nil {
+		return 0, 0, false
+	}
+
+	stateToken := (*(*mt.data)[bucketName])[fmt.Sprintf("%d", vbID)]
+	if stateToken == nil {
+		return 0, 0, false
+	}
+
+	vbUUID, err := strconv.ParseUint(stateToken.VbUuid, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return gocbcore.VbUuid(vbUUID), gocbcore.SeqNo(stateToken.SeqNo), true
+}
+
 // Add includes an operation's mutation information in this mutation state.
 func (mt *MutationState) Add(tokens ...MutationToken) {
 	for _, v := range tokens {